@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"boscoin.io/sebak/cmd/sebak/cmd/db"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	dbCmd *cobra.Command
+)
+
+func init() {
+	dbCmd = &cobra.Command{
+		Use:   "db",
+		Short: "Storage management",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) < 1 {
+				c.Usage()
+			}
+		},
+	}
+
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(db.ReindexCmd)
+}
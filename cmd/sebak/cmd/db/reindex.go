@@ -0,0 +1,89 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"boscoin.io/sebak/cmd/sebak/common"
+	"boscoin.io/sebak/lib"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+var (
+	ReindexCmd *cobra.Command
+
+	flagNetworkID     string = sebakcommon.GetENVValue("SEBAK_NETWORK_ID", "")
+	flagStorageConfig string = sebakcommon.GetENVValue("SEBAK_STORAGE", "")
+	flagTargetConfig  string = "memory://"
+)
+
+func init() {
+	ReindexCmd = &cobra.Command{
+		Use:   "reindex",
+		Short: "Rebuild the state by replaying the transaction history and compare it with the existing state",
+		Run: func(c *cobra.Command, args []string) {
+			if len(flagNetworkID) < 1 {
+				common.PrintFlagsError(c, "--network-id", errors.New("--network-id must be given"))
+			}
+			if len(flagStorageConfig) < 1 {
+				common.PrintFlagsError(c, "--storage", errors.New("--storage must be given"))
+			}
+			if flagStorageConfig == flagTargetConfig {
+				common.PrintFlagsError(c, "--target", errors.New("--target must be different from --storage"))
+			}
+
+			from, err := openStorage(flagStorageConfig)
+			if err != nil {
+				common.PrintFlagsError(c, "--storage", err)
+			}
+			defer from.Close()
+
+			to, err := openStorage(flagTargetConfig)
+			if err != nil {
+				common.PrintFlagsError(c, "--target", err)
+			}
+			defer to.Close()
+
+			result, err := sebak.Reindex(from, to, []byte(flagNetworkID))
+			if err == sebakerror.ErrorReindexTargetNotEmpty {
+				common.PrintFlagsError(c, "--target", err)
+			} else if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to reindex: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("replayed %d transactions, checked %d accounts\n", result.Transactions, result.Accounts)
+			if result.IsMatched() {
+				fmt.Println("rebuilt state matches the existing state")
+				return
+			}
+
+			for _, m := range result.Mismatches {
+				if len(m.Hash) > 0 {
+					fmt.Printf("mismatch: hash=%s field=%s existing=%q rebuilt=%q\n", m.Hash, m.Field, m.Existing, m.Rebuilt)
+					continue
+				}
+				fmt.Printf("mismatch: address=%s field=%s existing=%q rebuilt=%q\n", m.Address, m.Field, m.Existing, m.Rebuilt)
+			}
+			os.Exit(1)
+		},
+	}
+
+	ReindexCmd.Flags().StringVar(&flagNetworkID, "network-id", flagNetworkID, "network id")
+	ReindexCmd.Flags().StringVar(&flagStorageConfig, "storage", flagStorageConfig, "existing storage uri; file://<path> or bolt://<path>")
+	ReindexCmd.Flags().StringVar(&flagTargetConfig, "target", flagTargetConfig, "empty storage uri, where the state is rebuilt; file://<path>, bolt://<path> or memory://")
+}
+
+func openStorage(uri string) (sebakstorage.DBBackend, error) {
+	config, err := sebakstorage.NewConfigFromString(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return sebakstorage.NewStorage(config)
+}
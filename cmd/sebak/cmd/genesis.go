@@ -13,6 +13,7 @@ import (
 	"boscoin.io/sebak/lib/storage"
 
 	"boscoin.io/sebak/cmd/sebak/common"
+	"boscoin.io/sebak/lib"
 	"boscoin.io/sebak/lib/block"
 )
 
@@ -41,7 +42,7 @@ func init() {
 	}
 
 	genesisCmd.Flags().StringVar(&flagBalance, "balance", flagBalance, "initial balance of genesis block")
-	genesisCmd.Flags().StringVar(&flagStorageConfigString, "storage", flagStorageConfigString, "storage uri")
+	genesisCmd.Flags().StringVar(&flagStorageConfigString, "storage", flagStorageConfigString, "storage uri; file://<path>, bolt://<path> or memory://")
 	genesisCmd.Flags().StringVar(&flagNetworkID, "network-id", flagNetworkID, "network id")
 
 	rootCmd.AddCommand(genesisCmd)
@@ -128,7 +129,13 @@ func MakeGenesisBlock(addressStr, networkID, balanceStr, storage string) (string
 		balance,
 		sebakcommon.MakeGenesisCheckpoint([]byte(flagNetworkID)),
 	)
-	account.Save(st)
+	if err = account.Save(st); err == nil {
+		err = sebak.SaveBlockAccountHistory(st, account, nil)
+	}
 	st.Close()
+	if err != nil {
+		return "--storage", fmt.Errorf("failed to save genesis block: %v", err)
+	}
+
 	return "", nil
 }
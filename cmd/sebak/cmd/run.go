@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"golang.org/x/net/http2"
 
@@ -43,9 +44,15 @@ var (
 	flagStorageConfigString string
 	flagTLSCertFile         string = sebakcommon.GetENVValue("SEBAK_TLS_CERT", "sebak.crt")
 	flagTLSKeyFile          string = sebakcommon.GetENVValue("SEBAK_TLS_KEY", "sebak.key")
+	flagTLSCAFile           string = sebakcommon.GetENVValue("SEBAK_TLS_CA", "")
 	flagValidators          string = sebakcommon.GetENVValue("SEBAK_VALIDATORS", "")
+	flagPeers               string = sebakcommon.GetENVValue("SEBAK_PEERS", "")
+	flagMode                string = sebakcommon.GetENVValue("SEBAK_MODE", string(sebaknode.NodeModeValidator))
 	flagSignThreshold       string = sebakcommon.GetENVValue("SEBAK_SIGN_THRESHOLD", "60")
 	flagAcceptThreshold     string = sebakcommon.GetENVValue("SEBAK_ACCEPT_THRESHOLD", "60")
+	flagPruneWindow         string = sebakcommon.GetENVValue("SEBAK_PRUNE_WINDOW", "0")
+	flagPruneInterval       string = sebakcommon.GetENVValue("SEBAK_PRUNE_INTERVAL", "1h")
+	flagAllowedOrigins      string = sebakcommon.GetENVValue("SEBAK_ALLOWED_ORIGINS", "")
 )
 
 var (
@@ -54,7 +61,10 @@ var (
 	kp            *keypair.Full
 	nodeEndpoint  *sebakcommon.Endpoint
 	storageConfig *sebakstorage.Config
+	prunePolicy   sebak.PrunePolicy
 	validators    []*sebaknode.Validator
+	peers         []*sebaknode.Validator
+	nodeMode      sebaknode.NodeMode
 	logLevel      logging.Lvl
 	log           logging.Logger
 )
@@ -108,13 +118,19 @@ func init() {
 	nodeCmd.Flags().StringVar(&flagLogLevel, "log-level", flagLogLevel, "log level, {crit, error, warn, info, debug}")
 	nodeCmd.Flags().StringVar(&flagLogOutput, "log-output", flagLogOutput, "set log output file")
 	nodeCmd.Flags().BoolVar(&flagVerbose, "verbose", flagVerbose, "verbose")
-	nodeCmd.Flags().StringVar(&flagEndpointString, "endpoint", flagEndpointString, "endpoint uri to listen on")
-	nodeCmd.Flags().StringVar(&flagStorageConfigString, "storage", flagStorageConfigString, "storage uri")
+	nodeCmd.Flags().StringVar(&flagEndpointString, "endpoint", flagEndpointString, "endpoint uri to listen on; https://<host>:<port> or https+stream://<host>:<port>, which keeps the stream to each validator")
+	nodeCmd.Flags().StringVar(&flagStorageConfigString, "storage", flagStorageConfigString, "storage uri; file://<path>, bolt://<path> or memory://")
 	nodeCmd.Flags().StringVar(&flagTLSCertFile, "tls-cert", flagTLSCertFile, "tls certificate file")
 	nodeCmd.Flags().StringVar(&flagTLSKeyFile, "tls-key", flagTLSKeyFile, "tls key file")
+	nodeCmd.Flags().StringVar(&flagTLSCAFile, "tls-ca", flagTLSCAFile, "local CA certificate file; if given, validators authenticate each other by the certificates issued by 'sebak tls issue'")
 	nodeCmd.Flags().StringVar(&flagValidators, "validators", flagValidators, "set validator: <endpoint url>?address=<public address>[&alias=<alias>] [ <validator>...]")
+	nodeCmd.Flags().StringVar(&flagMode, "mode", flagMode, "node mode, {validator, watcher}; watcher follows the validators without voting")
+	nodeCmd.Flags().StringVar(&flagPeers, "peers", flagPeers, "set the initial peers, which are not validators: <endpoint url>?address=<public address>[&alias=<alias>] [ <peer>...]")
 	nodeCmd.Flags().StringVar(&flagSignThreshold, "sign-threshold", flagSignThreshold, "sign threshold")
 	nodeCmd.Flags().StringVar(&flagAcceptThreshold, "accept-threshold", flagAcceptThreshold, "accept threshold")
+	nodeCmd.Flags().StringVar(&flagPruneWindow, "prune-window", flagPruneWindow, "prune the history older than window, like '720h'; '0' keeps the full history")
+	nodeCmd.Flags().StringVar(&flagPruneInterval, "prune-interval", flagPruneInterval, "interval of pruning")
+	nodeCmd.Flags().StringVar(&flagAllowedOrigins, "allowed-origins", flagAllowedOrigins, "origins, which the browser can connect to the websocket api from besides the node itself: <scheme>://<host>[:<port>] [ <origin>...]; '*' allows any origin")
 
 	rootCmd.AddCommand(nodeCmd)
 }
@@ -171,9 +187,18 @@ func parseFlagsNode() {
 		common.PrintFlagsError(nodeCmd, "--tls-key", err)
 	}
 
+	if len(flagTLSCAFile) > 0 {
+		if _, err = os.Stat(flagTLSCAFile); os.IsNotExist(err) {
+			common.PrintFlagsError(nodeCmd, "--tls-ca", err)
+		}
+	}
+
 	queries := nodeEndpoint.Query()
 	queries.Add("TLSCertFile", flagTLSCertFile)
 	queries.Add("TLSKeyFile", flagTLSKeyFile)
+	if len(flagTLSCAFile) > 0 {
+		queries.Add("TLSCAFile", flagTLSCAFile)
+	}
 	queries.Add("IdleTimeout", "3s")
 	queries.Add("NodeName", sebaknode.MakeAlias(kp.Address()))
 	nodeEndpoint.RawQuery = queries.Encode()
@@ -191,10 +216,29 @@ func parseFlagsNode() {
 		}
 	}
 
+	if nodeMode, err = sebaknode.NodeModeFromString(flagMode); err != nil {
+		common.PrintFlagsError(nodeCmd, "--mode", err)
+	}
+
+	if len(flagPeers) > 0 {
+		if peers, err = parseFlagValidators(flagPeers); err != nil {
+			common.PrintFlagsError(nodeCmd, "--peers", err)
+		}
+	}
+
 	if storageConfig, err = sebakstorage.NewConfigFromString(flagStorageConfigString); err != nil {
 		common.PrintFlagsError(nodeCmd, "--storage", err)
 	}
 
+	if prunePolicy.Window, err = time.ParseDuration(flagPruneWindow); err != nil {
+		common.PrintFlagsError(nodeCmd, "--prune-window", err)
+	}
+	if prunePolicy.Interval, err = time.ParseDuration(flagPruneInterval); err != nil {
+		common.PrintFlagsError(nodeCmd, "--prune-interval", err)
+	} else if prunePolicy.Interval < time.Second {
+		common.PrintFlagsError(nodeCmd, "--prune-interval", errors.New("too short interval"))
+	}
+
 	if logLevel, err = logging.LvlFromString(flagLogLevel); err != nil {
 		common.PrintFlagsError(nodeCmd, "--log-level", err)
 	}
@@ -221,14 +265,19 @@ func parseFlagsNode() {
 	// print flags
 	parsedFlags := []interface{}{}
 	parsedFlags = append(parsedFlags, "\n\tnetwork-id", flagNetworkID)
+	parsedFlags = append(parsedFlags, "\n\tmode", flagMode)
 	parsedFlags = append(parsedFlags, "\n\tendpoint", flagEndpointString)
 	parsedFlags = append(parsedFlags, "\n\tstorage", flagStorageConfigString)
 	parsedFlags = append(parsedFlags, "\n\ttls-cert", flagTLSCertFile)
 	parsedFlags = append(parsedFlags, "\n\ttls-key", flagTLSKeyFile)
+	parsedFlags = append(parsedFlags, "\n\ttls-ca", flagTLSCAFile)
 	parsedFlags = append(parsedFlags, "\n\tlog-level", flagLogLevel)
 	parsedFlags = append(parsedFlags, "\n\tlog-output", flagLogOutput)
 	parsedFlags = append(parsedFlags, "\n\tsign-threshold", flagSignThreshold)
 	parsedFlags = append(parsedFlags, "\n\taccept-threshold", flagAcceptThreshold)
+	parsedFlags = append(parsedFlags, "\n\tprune-window", flagPruneWindow)
+	parsedFlags = append(parsedFlags, "\n\tprune-interval", flagPruneInterval)
+	parsedFlags = append(parsedFlags, "\n\tallowed-origins", flagAllowedOrigins)
 
 	var vl []interface{}
 	for i, v := range validators {
@@ -238,6 +287,13 @@ func parseFlagsNode() {
 			fmt.Sprintf("alias=%s address=%s endpoint=%s", v.Alias(), v.Address(), v.Endpoint()),
 		)
 	}
+	for i, v := range peers {
+		vl = append(vl, fmt.Sprintf("\n\tpeer#%d", i))
+		vl = append(
+			vl,
+			fmt.Sprintf("alias=%s address=%s endpoint=%s", v.Alias(), v.Address(), v.Endpoint()),
+		)
+	}
 	parsedFlags = append(parsedFlags, vl...)
 
 	log.Debug("parsed flags:", parsedFlags...)
@@ -255,6 +311,7 @@ func runNode() {
 		return
 	}
 	localNode.AddValidators(validators...)
+	localNode.SetMode(nodeMode)
 
 	// create network
 	nt, err := sebaknetwork.NewNetwork(nodeEndpoint)
@@ -267,7 +324,11 @@ func runNode() {
 	signTh, err := strconv.Atoi(flagSignThreshold)
 	acceptTh, err := strconv.Atoi(flagAcceptThreshold)
 	policy, _ := sebak.NewDefaultVotingThresholdPolicy(100, signTh, acceptTh)
-	policy.SetValidators(len(localNode.GetValidators()) + 1) // including 'self'
+	if localNode.IsWatcher() {
+		policy.SetValidators(len(localNode.GetValidators())) // watcher does not vote
+	} else {
+		policy.SetValidators(len(localNode.GetValidators()) + 1) // including 'self'
+	}
 
 	isaac, err := sebak.NewISAAC([]byte(flagNetworkID), localNode, policy)
 	if err != nil {
@@ -282,10 +343,22 @@ func runNode() {
 		os.Exit(1)
 	}
 
+	if applied, err := sebak.Migrate(st); err != nil {
+		log.Crit("failed to migrate storage", "error", err)
+
+		os.Exit(1)
+	} else if len(applied) > 0 {
+		log.Info("storage migrated", "migrations", applied)
+	}
+
 	// Execution group.
 	var g run.Group
 	{
 		nr := sebak.NewNodeRunner(flagNetworkID, localNode, policy, nt, isaac, st)
+		nr.SetAllowedOrigins(strings.Fields(flagAllowedOrigins))
+		for _, p := range peers {
+			nr.PeerManager().Table().Add(p.Address(), p.Endpoint(), false)
+		}
 		g.Add(func() error {
 			if err := nr.Start(); err != nil {
 				log.Crit("failed to start node", "error", err)
@@ -296,6 +369,18 @@ func runNode() {
 			nr.Stop()
 		})
 	}
+	{
+		pruner := sebak.NewPruner(st, prunePolicy)
+		cancel := make(chan struct{})
+		g.Add(func() error {
+			pruner.Start()
+			<-cancel
+			return nil
+		}, func(error) {
+			pruner.Stop()
+			close(cancel)
+		})
+	}
 	{
 		cancel := make(chan struct{})
 		g.Add(func() error {
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 
 	logging "github.com/inconshreveable/log15"
 	"github.com/spf13/cobra"
@@ -13,7 +15,13 @@ import (
 
 var (
 	tlsCmd            *cobra.Command
+	tlsCACmd          *cobra.Command
+	tlsIssueCmd       *cobra.Command
 	flagTLSOutputPath = "."
+
+	flagTLSCACertFile = "ca.crt"
+	flagTLSCAKeyFile  = "ca.key"
+	flagTLSHosts      = []string{"localhost", "127.0.0.1"}
 )
 
 func init() {
@@ -30,9 +38,79 @@ func init() {
 	tlsCmd.Flags().StringVar(&flagTLSKeyFile, "key", flagTLSKeyFile, "tls key file name")
 	tlsCmd.Flags().StringVar(&flagTLSOutputPath, "output", flagTLSOutputPath, "tls output path")
 
+	tlsCACmd = &cobra.Command{
+		Use:   "ca",
+		Short: "Generate local CA certificate and key file for mutual tls between validators",
+		Run: func(c *cobra.Command, args []string) {
+			generateCA()
+		},
+	}
+
+	tlsCACmd.Flags().StringVar(&flagTLSCACertFile, "cert", flagTLSCACertFile, "CA certificate file name")
+	tlsCACmd.Flags().StringVar(&flagTLSCAKeyFile, "key", flagTLSCAKeyFile, "CA key file name")
+	tlsCACmd.Flags().StringVar(&flagTLSOutputPath, "output", flagTLSOutputPath, "tls output path")
+
+	tlsIssueCmd = &cobra.Command{
+		Use:   "issue <public address>",
+		Short: "Issue the node certificate, which is bound to the public address of node",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			issue(args[0])
+		},
+	}
+
+	tlsIssueCmd.Flags().StringVar(&flagTLSCACertFile, "ca-cert", flagTLSCACertFile, "CA certificate file")
+	tlsIssueCmd.Flags().StringVar(&flagTLSCAKeyFile, "ca-key", flagTLSCAKeyFile, "CA key file")
+	tlsIssueCmd.Flags().StringSliceVar(&flagTLSHosts, "host", flagTLSHosts, "host name or ip address of node")
+	tlsIssueCmd.Flags().StringVar(&flagTLSCertFile, "cert", flagTLSCertFile, "tls certificate file name")
+	tlsIssueCmd.Flags().StringVar(&flagTLSKeyFile, "key", flagTLSKeyFile, "tls key file name")
+	tlsIssueCmd.Flags().StringVar(&flagTLSOutputPath, "output", flagTLSOutputPath, "tls output path")
+
+	tlsCmd.AddCommand(tlsCACmd)
+	tlsCmd.AddCommand(tlsIssueCmd)
 	rootCmd.AddCommand(tlsCmd)
 }
 
+func generateCA() {
+	if _, err := os.Stat(flagTLSOutputPath); os.IsNotExist(err) {
+		common.PrintFlagsError(tlsCACmd, "output", err)
+	}
+
+	certPath := filepath.Join(flagTLSOutputPath, flagTLSCACertFile)
+	keyPath := filepath.Join(flagTLSOutputPath, flagTLSCAKeyFile)
+	if err := sebaknetwork.GenerateCA(certPath, keyPath); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate CA: %v\n", err)
+		os.Exit(1)
+	}
+
+	log = logging.New("module", "tls")
+	log.Info("Generate CA certificate and key files", "cert", certPath, "key", keyPath)
+}
+
+func issue(address string) {
+	if _, err := os.Stat(flagTLSOutputPath); os.IsNotExist(err) {
+		common.PrintFlagsError(tlsIssueCmd, "output", err)
+	}
+
+	certPath := filepath.Join(flagTLSOutputPath, flagTLSCertFile)
+	keyPath := filepath.Join(flagTLSOutputPath, flagTLSKeyFile)
+	err := sebaknetwork.IssueNodeCertificate(
+		flagTLSCACertFile,
+		flagTLSCAKeyFile,
+		address,
+		flagTLSHosts,
+		certPath,
+		keyPath,
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to issue certificate: %v\n", err)
+		os.Exit(1)
+	}
+
+	log = logging.New("module", "tls")
+	log.Info("Issue node certificate and key files", "address", address, "cert", certPath, "key", keyPath)
+}
+
 func generate() {
 	var err error
 
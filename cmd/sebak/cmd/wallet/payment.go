@@ -1,16 +1,14 @@
 package wallet
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
-	"time"
 
 	"boscoin.io/sebak/cmd/sebak/common"
 	"boscoin.io/sebak/lib"
+	"boscoin.io/sebak/lib/client"
 	"boscoin.io/sebak/lib/common"
-	"boscoin.io/sebak/lib/network"
 
 	"boscoin.io/sebak/lib/block"
 	"github.com/spf13/cobra"
@@ -72,17 +70,15 @@ func init() {
 			// At the moment this is a rather crude implementation: There is no support for pooling of transaction,
 			// 1 operation == 1 transaction
 			var tx sebak.Transaction
-			var connection *sebakcommon.HTTP2Client
+			var client *sebakclient.Client
 			var senderAccount block.BlockAccount
 
-			// Keep-alive ignores timeout/idle timeout
-			if connection, err = sebakcommon.NewHTTP2Client(0, 0, true); err != nil {
+			if client, err = sebakclient.NewClient(endpoint, nil); err != nil {
 				log.Fatal("Error while creating network client: ", err)
 				os.Exit(1)
 			}
-			client := sebaknetwork.NewHTTP2NetworkClient(endpoint, connection)
 
-			if senderAccount, err = getSenderDetails(client, sender); err != nil {
+			if senderAccount, err = client.GetAccount(sender.Address()); err != nil {
 				log.Fatal("Could not fetch sender account: ", err)
 				os.Exit(1)
 			}
@@ -106,31 +102,37 @@ func init() {
 			}
 
 			// TODO: Validate that the account doesn't already exists
+			builder := client.NewTransactionBuilder([]byte(flagNetworkID), sender.(*keypair.Full))
 			if flagCreateAccount {
-				tx = makeTransactionCreateAccount(sender, receiver, amount, senderAccount.Checkpoint)
+				builder.CreateAccount(receiver.Address(), amount)
 			} else {
-				tx = makeTransactionPayment(sender, receiver, amount, senderAccount.Checkpoint)
+				builder.Payment(receiver.Address(), amount)
+			}
+			if tx, err = builder.Build(); err != nil {
+				log.Fatal("Could not make transaction: ", err)
+				os.Exit(1)
 			}
-
-			tx.Sign(sender, []byte(flagNetworkID))
 
 			// Send request
-			var retbody []byte
 			if flagDry == true || flagVerbose == true {
 				fmt.Println(tx)
 			}
 			if flagDry == false {
-				if retbody, err = client.SendMessage(tx); err != nil {
-					log.Fatal("Network error: ", err, " body: ", retbody)
+				// with `--verbose`, wait until the transaction is confirmed
+				var result sebak.TransactionSubmitResult
+				if result, err = client.SubmitTransaction(tx, flagVerbose, 0); err != nil {
+					log.Fatal("Network error: ", err)
 					os.Exit(1)
 				}
+				if flagVerbose == true {
+					fmt.Println("Transaction ", result.Hash, " is ", result.Status)
+				}
 			}
-			if flagVerbose == true {
-				time.Sleep(5 * time.Second)
-				if recv, err := getSenderDetails(client, receiver); err != nil {
-					fmt.Println("Account ", receiver.Address(), " did not appear after 5 seconds")
+			if flagVerbose == true && flagDry == false {
+				if recv, err := client.GetAccount(receiver.Address()); err != nil {
+					fmt.Println("Account ", receiver.Address(), " did not appear: ", err)
 				} else {
-					fmt.Println("Receiver account after 5 seconds: ", recv)
+					fmt.Println("Receiver account after transaction: ", recv)
 				}
 			}
 		},
@@ -141,119 +143,3 @@ func init() {
 	PaymentCmd.Flags().BoolVar(&flagDry, "dry-run", flagDry, "Print the transaction instead of sending it")
 	PaymentCmd.Flags().BoolVar(&flagVerbose, "verbose", flagVerbose, "Print extra data (transaction sent, before/after balance...)")
 }
-
-///
-/// Make a full transaction, with a single operation to create an account
-///
-/// TODO:
-///   Move to lib (it was 'borrowed' from test code)
-///
-/// Params:
-///   kpSource = Sender's keypair.Full seed/address
-///   kpDest   = Newly created account's address
-///   amount   = Amount to send as initial value
-///   chkp     = Checkpoint of the last transaction
-///
-/// Returns:
-///   `sebak.Transaction` = The generated `Transaction` creating the account
-///
-func makeTransactionCreateAccount(kpSource keypair.KP, kpDest keypair.KP, amount sebakcommon.Amount, chkp string) sebak.Transaction {
-	opb := sebak.NewOperationBodyCreateAccount(kpDest.Address(), amount)
-
-	op := sebak.Operation{
-		H: sebak.OperationHeader{
-			Type: sebak.OperationCreateAccount,
-		},
-		B: opb,
-	}
-
-	txBody := sebak.TransactionBody{
-		Source:     kpSource.Address(),
-		Fee:        sebak.BaseFee,
-		Checkpoint: chkp,
-		Operations: []sebak.Operation{op},
-	}
-
-	tx := sebak.Transaction{
-		T: "transaction",
-		H: sebak.TransactionHeader{
-			Created: sebakcommon.NowISO8601(),
-			Hash:    txBody.MakeHashString(),
-		},
-		B: txBody,
-	}
-
-	return tx
-}
-
-///
-/// Make a full transaction, with a single payment operation in it
-///
-/// TODO:
-///   Move to lib (it was 'borrowed' from test code)
-///
-/// Params:
-///   kpSource = Sender's keypair.Full seed/address
-///   kpDest   = Receiver's keypair.FromAddress address
-///   amount   = Amount to send as initial value
-///   chkp     = Checkpoint of the last transaction
-///
-/// Returns:
-///  `sebak.Transaction` = The generated `Transaction` to do a payment
-///
-func makeTransactionPayment(kpSource keypair.KP, kpDest keypair.KP, amount sebakcommon.Amount, chkp string) sebak.Transaction {
-	opb := sebak.NewOperationBodyPayment(kpDest.Address(), amount)
-
-	op := sebak.Operation{
-		H: sebak.OperationHeader{
-			Type: sebak.OperationPayment,
-		},
-		B: opb,
-	}
-
-	txBody := sebak.TransactionBody{
-		Source:     kpSource.Address(),
-		Fee:        sebakcommon.Amount(sebak.BaseFee),
-		Checkpoint: chkp,
-		Operations: []sebak.Operation{op},
-	}
-
-	tx := sebak.Transaction{
-		T: "transaction",
-		H: sebak.TransactionHeader{
-			Created: sebakcommon.NowISO8601(),
-			Hash:    txBody.MakeHashString(),
-		},
-		B: txBody,
-	}
-
-	return tx
-}
-
-///
-/// Get the BlockAccount of the sender
-///
-/// TODO:
-///   Move to lib
-///
-/// Params:
-///   conn = Network connection to the node to request
-///   sender = sender from which to request the account (only `Address` is used)
-///
-/// Returns:
-///   sebak.BlockAccount = The deserialized block account, or a default-initialized one if an error occured
-///   error = `nil` or the error that occured (either network or deserialization)
-///
-func getSenderDetails(conn *sebaknetwork.HTTP2NetworkClient, sender keypair.KP) (block.BlockAccount, error) {
-	var ba block.BlockAccount
-	var err error
-	var retBody []byte
-
-	//response, err = c.client.Post(u.String(), body, headers)
-	if retBody, err = conn.Get("/api/account/" + sender.Address()); err != nil {
-		return ba, err
-	}
-
-	err = json.Unmarshal(retBody, &ba)
-	return ba, err
-}
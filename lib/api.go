@@ -1,10 +1,21 @@
 package sebak
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"boscoin.io/sebak/lib/error"
 	"boscoin.io/sebak/lib/network"
 	"boscoin.io/sebak/lib/storage"
 	"github.com/GianlucaGuarini/go-observable"
@@ -12,67 +23,370 @@ import (
 
 const maxNumberOfExistingData = 10
 
-func AddAPIHandlers(s *sebakstorage.LevelDBBackend) func(ctx context.Context, t *sebaknetwork.HTTP2Network) {
+func AddAPIHandlers(s sebakstorage.DBBackend) func(ctx context.Context, t *sebaknetwork.HTTP2Network) {
 	fn := func(ctx context.Context, t *sebaknetwork.HTTP2Network) {
+		t.AddAPIHandler(GetAccountsHandlerPattern, GetAccountsHandler(s)).Methods("GET")
 		t.AddAPIHandler(GetAccountHandlerPattern, GetAccountHandler(s)).Methods("GET")
+		t.AddAPIHandler(GetAccountHistoryHandlerPattern, GetAccountHistoryHandler(s)).Methods("GET")
 		t.AddAPIHandler(GetAccountTransactionsHandlerPattern, GetAccountTransactionsHandler(s)).Methods("GET")
 		t.AddAPIHandler(GetAccountOperationsHandlerPattern, GetAccountOperationsHandler(s)).Methods("GET")
+		t.AddAPIHandler(GetAccountOperationsWithHandlerPattern, GetAccountOperationsWithHandler(s)).Methods("GET")
+		t.AddAPIHandler(GetTransactionsHandlerPattern, GetTransactionsHandler(s)).Methods("GET")
 		t.AddAPIHandler(GetTransactionByHashHandlerPattern, GetTransactionByHashHandler(s)).Methods("GET")
+		t.AddAPIHandler(GetOperationsHandlerPattern, GetOperationsHandler(s)).Methods("GET")
+		t.AddAPIHandler(GetLedgerStatsHandlerPattern, GetLedgerStatsHandler(s)).Methods("GET")
 	}
 	return fn
 }
 
-// Implement `Server Sent Event`
-// Listen event `event` thru `o`
-// When the `event` triggered, `callBackFunc` fired
-// readyChan is used to notify caller of this function that streaming is ready
-// This function is not end until the connection is closed
-func streaming(o *observable.Observable, w http.ResponseWriter, event string, callBackFunc func(args ...interface{}) ([]byte, error), readyChan chan struct{}) {
+// AddNodeRunnerAPIHandlers adds the api handlers, which need `NodeRunner`
+// like submitting transaction, the status of node and the websocket of it's
+// events.
+func AddNodeRunnerAPIHandlers(nr *NodeRunner) func(ctx context.Context, t *sebaknetwork.HTTP2Network) {
+	fn := func(ctx context.Context, t *sebaknetwork.HTTP2Network) {
+		t.AddAPIHandler(PostTransactionHandlerPattern, PostTransactionHandler(nr)).Methods("POST")
+		t.AddAPIHandler(GetTransactionStatusHandlerPattern, GetTransactionStatusHandler(nr)).Methods("GET")
+		t.AddAPIHandler(GetNodeStatusHandlerPattern, GetNodeStatusHandler(nr)).Methods("GET")
+		t.AddAPIHandler(WebSocketHandlerPattern, WebSocketHandler(nr.webSocketHub, nr.allowedOrigins)).Methods("GET")
+	}
+	return fn
+}
+
+var (
+	// DefaultPageLimit is the number of records in one page, when `limit` is
+	// not requested.
+	DefaultPageLimit = 100
+
+	// MaxPageLimit limits the `limit` of request.
+	MaxPageLimit = 1000
+)
+
+// Page is the response of the paginated list. `Next` is the cursor of the
+// next page and it is empty in the last page.
+type Page struct {
+	Records interface{} `json:"records"`
+	Next    string      `json:"next,omitempty"`
+}
+
+func (p Page) Serialize() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// pageQuery is the pagination of request,
+//  * `cursor`: the records after it are returned
+//  * `limit`: the number of records
+//  * `order`: `asc`(default) or `desc`
+type pageQuery struct {
+	Cursor  string
+	Limit   int
+	Reverse bool
+}
+
+func newPageQuery(query url.Values) (q pageQuery, err error) {
+	q.Cursor = query.Get("cursor")
+	q.Limit = DefaultPageLimit
+
+	if v := query.Get("limit"); len(v) > 0 {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 {
+			err = errors.New("invalid limit")
+			return
+		}
+		if q.Limit > MaxPageLimit {
+			q.Limit = MaxPageLimit
+		}
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Reverse = true
+	default:
+		err = errors.New("invalid order")
+		return
+	}
+
+	return
+}
+
+// after checks the `id` of record comes after `Cursor` in the order of
+// query.
+func (q pageQuery) after(id string) bool {
+	if len(q.Cursor) < 1 {
+		return true
+	}
+	if q.Reverse {
+		return id < q.Cursor
+	}
+
+	return id > q.Cursor
+}
+
+// loadPage reads the records of page from `iterFunc`, which iterates after
+// the cursor of query. `cursor` returns the cursor of item and `record` makes
+// the record of item; the nil record is skipped.
+func loadPage(
+	q pageQuery,
+	iterFunc func() (sebakstorage.IterItem, bool),
+	cursor func(sebakstorage.IterItem) string,
+	record func(sebakstorage.IterItem) (interface{}, error),
+) (p Page, err error) {
+	records := []interface{}{}
+
+	var last string
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var v interface{}
+		if v, err = record(item); err != nil {
+			return
+		} else if v == nil {
+			continue
+		}
+
+		if len(records) == q.Limit {
+			p.Next = last
+			break
+		}
+		records = append(records, v)
+		last = cursor(item)
+	}
+	p.Records = records
+
+	return
+}
+
+// writePageQueryProblem responds the invalid pagination of request.
+func writePageQueryProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := sebaknetwork.NewProblem(http.StatusBadRequest, err)
+	for _, name := range []string{"cursor", "limit", "order"} {
+		if v, found := r.URL.Query()[name]; found {
+			problem = problem.With(name, v)
+		}
+	}
+	problem.Write(w)
+}
+
+var (
+	// DefaultStreamingHeartbeat is the interval of the keep-alive comment in
+	// stream; the proxies do not close the idle stream.
+	DefaultStreamingHeartbeat = 15 * time.Second
+
+	// StreamingBufferSize is the number of events, which wait for the slow
+	// client. When it is full, the stream is closed and the client should
+	// reconnect with `Last-Event-ID`.
+	StreamingBufferSize = 256
+
+	// MaxStreamingReplay is the number of stored events, which can be
+	// replayed after `Last-Event-ID`. With more events, the stream is refused
+	// and the client should read them from the list api.
+	MaxStreamingReplay = 1000
+)
 
+// errStreamingReplayTooLong is returned by `replayStreamEvents`, when more
+// than `MaxStreamingReplay` events are after `Last-Event-ID`.
+var errStreamingReplayTooLong = errors.New("too many events to replay")
+
+// streamEvent is one event of `Server-Sent Events`. `ID` follows the order
+// of storage, so the client can resume the stream from it.
+type streamEvent struct {
+	ID   string
+	Data []byte
+}
+
+func (e streamEvent) write(w io.Writer, name string) (err error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "id: %s\n", e.ID)
+	fmt.Fprintf(&b, "event: %s\n", name)
+	for _, line := range bytes.Split(e.Data, []byte("\n")) {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	_, err = w.Write(b.Bytes())
+	return
+}
+
+// makeStreamID makes the `streamEvent.ID` from the confirmed time of record;
+// `hash` keeps the id unique.
+func makeStreamID(confirmed, hash string) string {
+	return fmt.Sprintf("%s-%s", confirmed, hash)
+}
+
+// streamIDConfirmed returns the confirmed time of the id, which is made by
+// `makeStreamID`; the stored events are read from it at resuming.
+func streamIDConfirmed(id string) string {
+	if i := strings.LastIndex(id, "-"); i >= 0 {
+		return id[:i]
+	}
+
+	return id
+}
+
+// replayStreamEvents sends the stored events from `next`. Without `lastID`,
+// the first `maxNumberOfExistingData` events of `next` are sent; with
+// `lastID`, the events after it are sent as they are read, so `next` should
+// start from the confirmed time of `lastID` by `streamIDConfirmed`. If more
+// than `MaxStreamingReplay` events are after `lastID`, the first
+// `MaxStreamingReplay` events are sent and `errStreamingReplayTooLong` is
+// returned.
+func replayStreamEvents(next func() (streamEvent, bool), lastID string, send func(streamEvent) error) (err error) {
+	limit := maxNumberOfExistingData
+	if len(lastID) > 0 {
+		limit = MaxStreamingReplay
+	}
+
+	var n int
+	for {
+		e, hasNext := next()
+		if !hasNext {
+			break
+		}
+		// the events of same confirmed time, which were already sent
+		if len(lastID) > 0 && e.ID <= lastID {
+			continue
+		}
+		if n >= limit {
+			if len(lastID) > 0 {
+				err = errStreamingReplayTooLong
+			}
+			break
+		}
+		if err = send(e); err != nil {
+			return
+		}
+		n++
+	}
+
+	return
+}
+
+// streamSource is the events of `streaming`.
+type streamSource struct {
+	Name       string // `event` of `Server-Sent Events`
+	Observable *observable.Observable
+	Events     string // the events of `Observable`
+
+	// Event makes `streamEvent` from the record of `Observable`.
+	Event func(interface{}) (streamEvent, error)
+
+	// Match selects the records of `Observable`; nil selects all.
+	Match func(interface{}) bool
+
+	// Replay sends the stored events after `lastID`.
+	Replay func(lastID string, send func(streamEvent) error) error
+}
+
+// Implement `Server Sent Event`
+// The stored events are replayed from `Last-Event-ID` of request, and then
+// the new events of `source.Observable` are sent. The keep-alive comment is
+// sent every `DefaultStreamingHeartbeat`. This function is not end until the
+// connection is closed or the client is too slow.
+func streaming(w http.ResponseWriter, r *http.Request, source streamSource) {
 	cn, ok := w.(http.CloseNotifier)
 	if !ok {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		sebaknetwork.WriteProblem(w, http.StatusInternalServerError, nil)
 		return
 	}
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		sebaknetwork.WriteProblem(w, http.StatusInternalServerError, nil)
 		return
 	}
 
-	// consumerChan notify observerFunc that messageChan receiver is dismissed
-	consumerChan := make(chan struct{})
-	messageChan := make(chan []byte)
+	// the observer must not be blocked by the slow client
+	events := make(chan streamEvent, StreamingBufferSize)
+	overflow := make(chan struct{})
+	var overflowOnce sync.Once
 
 	observerFunc := func(args ...interface{}) {
-		s, err := callBackFunc(args...)
+		if len(args) < 1 {
+			return
+		}
+		if source.Match != nil && !source.Match(args[len(args)-1]) {
+			return
+		}
+		e, err := source.Event(args[len(args)-1])
 		if err != nil {
-			//TODO: handle the error
+			log.Error("failed to make stream event", "event", source.Name, "error", err)
 			return
 		}
 
 		select {
-		case messageChan <- s:
-		case <-consumerChan:
+		case events <- e:
+		default:
+			overflowOnce.Do(func() { close(overflow) })
+		}
+	}
+
+	// the stream, which has too many events to replay, is refused before it
+	// is started
+	lastID := r.Header.Get("Last-Event-ID")
+	if source.Replay != nil && len(lastID) > 0 {
+		err := source.Replay(lastID, func(streamEvent) error { return nil })
+		if err == errStreamingReplayTooLong {
+			sebaknetwork.NewProblem(http.StatusBadRequest, sebakerror.ErrorTooManyStreamingEvents).
+				With("Last-Event-ID", lastID).
+				With("limit", MaxStreamingReplay).
+				Write(w)
+			return
+		} else if err != nil {
+			log.Error("failed to replay stream", "event", source.Name, "error", err)
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
 		}
 	}
 
-	o.On(event, observerFunc)
-	defer o.Off(event, observerFunc)
+	source.Observable.On(source.Events, observerFunc)
+	defer source.Observable.Off(source.Events, observerFunc)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// the events, which are stored while replaying, can be received again
+	// from `Observable`. The events over `MaxStreamingReplay`, which are stored
+	// after checking, are also received from `Observable`. The account keeps
+	// it's id with the new balance, so the digest of data is compared too.
+	replayed := map[string][sha256.Size]byte{}
+	if source.Replay != nil {
+		send := func(e streamEvent) error {
+			replayed[e.ID] = sha256.Sum256(e.Data)
+			return e.write(w, source.Name)
+		}
+		if err := source.Replay(lastID, send); err != nil && err != errStreamingReplayTooLong {
+			log.Error("failed to replay stream", "event", source.Name, "error", err)
+			return
+		}
+	}
+	flusher.Flush()
 
-	w.Header().Set("Content-Type", "application/json")
+	heartbeat := time.NewTicker(DefaultStreamingHeartbeat)
+	defer heartbeat.Stop()
 
-	readyChan <- struct{}{}
 	for {
 		select {
 		case <-cn.CloseNotify():
-			close(consumerChan)
 			return
-		case message := <-messageChan:
-			fmt.Fprintf(w, "%s\n", message)
+		case <-overflow:
+			log.Debug("client is too slow; stream is closed", "event", source.Name)
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e := <-events:
+			if digest, found := replayed[e.ID]; found && digest == sha256.Sum256(e.Data) {
+				continue
+			}
+			if err := e.write(w, source.Name); err != nil {
+				return
+			}
 			flusher.Flush()
 		}
 	}
-
 }
@@ -1,30 +1,79 @@
 package sebak
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"boscoin.io/sebak/lib/block"
 	"boscoin.io/sebak/lib/common"
 	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
 	"boscoin.io/sebak/lib/observer"
 	"boscoin.io/sebak/lib/storage"
 	"github.com/gorilla/mux"
 )
 
+// GetAccountsHandlerPattern lists all the accounts by the created order; it
+// is paginated by `cursor`, `limit` and `order`.
+const GetAccountsHandlerPattern = "/accounts"
+
+func GetAccountsHandler(storage sebakstorage.DBBackend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := newPageQuery(r.URL.Query())
+		if err != nil {
+			writePageQueryProblem(w, r, err)
+			return
+		}
+
+		iterFunc, closeFunc := sebakstorage.GetIteratorAfter(storage, block.BlockAccountPrefixCreated, q.Cursor, q.Reverse)
+		defer closeFunc()
+
+		cursor := func(item sebakstorage.IterItem) string {
+			return sebakstorage.Cursor(block.BlockAccountPrefixCreated, item)
+		}
+		record := func(item sebakstorage.IterItem) (interface{}, error) {
+			var address string
+			if err := json.Unmarshal(item.Value, &address); err != nil {
+				return nil, err
+			}
+			return block.GetBlockAccount(storage, address)
+		}
+
+		var page Page
+		if page, err = loadPage(q, iterFunc, cursor, record); err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		var s []byte
+		if s, err = page.Serialize(); err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s)
+	}
+}
+
 const GetAccountHandlerPattern = "/account/{address}"
 
-func GetAccountHandler(storage *sebakstorage.LevelDBBackend) http.HandlerFunc {
+func GetAccountHandler(storage sebakstorage.DBBackend) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		vars := mux.Vars(r)
 		address := vars["address"]
 		if found, err := block.ExistBlockAccount(storage, address); err != nil {
-			http.Error(w, "Error reading request body", http.StatusInternalServerError)
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
 			return
 		} else if !found {
-			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			sebaknetwork.NewProblem(http.StatusNotFound, sebakerror.ErrorBlockAccountDoesNotExists).With("address", address).Write(w)
 			return
 		}
 
@@ -33,53 +82,149 @@ func GetAccountHandler(storage *sebakstorage.LevelDBBackend) http.HandlerFunc {
 
 		switch r.Header.Get("Accept") {
 		case "text/event-stream":
+			streaming(w, r, streamSource{
+				Name:       "account",
+				Observable: observer.BlockAccountObserver,
+				Events:     fmt.Sprintf("address-%s", address),
+				Event:      blockAccountStreamEvent,
+				Replay: func(lastID string, send func(streamEvent) error) (err error) {
+					var ba *block.BlockAccount
+					if ba, err = block.GetBlockAccount(storage, address); err != nil {
+						return
+					}
 
-			var readyChan = make(chan struct{})
-
-			// Trigger event for data already stored in the storage
-			iterateId := sebakcommon.GetUniqueIDFromUUID()
-			go func() {
-				<-readyChan
-				if ba, err = block.GetBlockAccount(storage, address); err != nil {
-					http.Error(w, "Error reading request body", http.StatusInternalServerError)
-					return
-				}
-				observer.BlockAccountObserver.Trigger(fmt.Sprintf("iterate-%s", iterateId), ba)
-			}()
-
-			callBackFunc := func(args ...interface{}) (account []byte, err error) {
-				ba := args[1].(*block.BlockAccount)
-				if account, err = ba.Serialize(); err != nil {
-					return []byte{}, sebakerror.ErrorBlockAccountDoesNotExists
-				}
-				return account, nil
-			}
-
-			event := fmt.Sprintf("iterate-%s", iterateId)
-			event += " " + fmt.Sprintf("address-%s", address)
-			streaming(observer.BlockAccountObserver, w, event, callBackFunc, readyChan)
+					var e streamEvent
+					if e, err = blockAccountStreamEvent(ba); err != nil || e.ID == lastID {
+						return
+					}
+					return send(e)
+				},
+			})
 		default:
 			if ba, err = block.GetBlockAccount(storage, address); err != nil {
-				http.Error(w, "Error reading request body", http.StatusInternalServerError)
+				sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
 				return
 			}
 
 			var s []byte
 			if s, err = ba.Serialize(); err != nil {
-				http.Error(w, "Error reading request body", http.StatusInternalServerError)
+				sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
 				return
 			}
 			if _, err = w.Write(s); err != nil {
-				http.Error(w, "Error reading request body", http.StatusInternalServerError)
+				sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
 				return
 			}
 		}
 	}
 }
 
+const GetAccountHistoryHandlerPattern = "/account/{address}/history"
+
+// GetAccountHistoryHandler responds the balance histories of account in
+// `Page`; with `bucket=day`, the closing balance of each day is responded.
+func GetAccountHistoryHandler(storage sebakstorage.DBBackend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := mux.Vars(r)["address"]
+
+		q, err := newPageQuery(r.URL.Query())
+		if err != nil {
+			writePageQueryProblem(w, r, err)
+			return
+		}
+
+		bucket := r.URL.Query().Get("bucket")
+		if len(bucket) > 0 && bucket != "day" {
+			sebaknetwork.NewProblem(http.StatusBadRequest, errors.New("invalid bucket")).With("bucket", bucket).Write(w)
+			return
+		}
+
+		if found, err := block.ExistBlockAccount(storage, address); err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		} else if !found {
+			sebaknetwork.NewProblem(http.StatusNotFound, sebakerror.ErrorBlockAccountDoesNotExists).With("address", address).Write(w)
+			return
+		}
+
+		var page Page
+		if bucket == "day" {
+			page = loadBlockAccountHistoryByDay(storage, address, q)
+		} else {
+			iterFunc, closeFunc := GetBlockAccountHistory(storage, address, q.Cursor, q.Reverse)
+			page = loadBlockAccountHistoryPage(q, iterFunc)
+			closeFunc()
+		}
+
+		s, err := page.Serialize()
+		if err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s)
+	}
+}
+
+// loadBlockAccountHistoryPage reads the histories of page from `iterFunc`,
+// which iterates after the cursor of query.
+func loadBlockAccountHistoryPage(q pageQuery, iterFunc func() (BlockAccountHistory, bool)) (page Page) {
+	records := []BlockAccountHistory{}
+	for {
+		h, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		if len(records) == q.Limit {
+			page.Next = records[len(records)-1].ID
+			break
+		}
+		records = append(records, h)
+	}
+	page.Records = records
+
+	return
+}
+
+// loadBlockAccountHistoryByDay reads the closing balances of days. The date is
+// not the key of index, so all the histories of account are read.
+func loadBlockAccountHistoryByDay(st sebakstorage.DBBackend, address string, q pageQuery) Page {
+	var histories []BlockAccountHistory
+
+	iterFunc, closeFunc := GetBlockAccountHistory(st, address, "", false)
+	for {
+		h, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		histories = append(histories, h)
+	}
+	closeFunc()
+
+	buckets := BucketBlockAccountHistoryByDay(histories)
+	if q.Reverse {
+		for i, j := 0, len(buckets)-1; i < j; i, j = i+1, j-1 {
+			buckets[i], buckets[j] = buckets[j], buckets[i]
+		}
+	}
+
+	var i int
+	return loadBlockAccountHistoryPage(q, func() (BlockAccountHistory, bool) {
+		for i < len(buckets) {
+			h := buckets[i]
+			i++
+			if q.after(h.ID) {
+				return h, true
+			}
+		}
+		return BlockAccountHistory{}, false
+	})
+}
+
 const GetAccountTransactionsHandlerPattern = "/account/{address}/transactions"
 
-func GetAccountTransactionsHandler(storage *sebakstorage.LevelDBBackend) http.HandlerFunc {
+func GetAccountTransactionsHandler(storage sebakstorage.DBBackend) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
@@ -91,34 +236,26 @@ func GetAccountTransactionsHandler(storage *sebakstorage.LevelDBBackend) http.Ha
 
 		switch r.Header.Get("Accept") {
 		case "text/event-stream":
-			var readyChan = make(chan struct{})
-			iterateId := sebakcommon.GetUniqueIDFromUUID()
-			go func() {
-				<-readyChan
-				count := maxNumberOfExistingData
-				iterFunc, closeFunc := GetBlockTransactionsByAccount(storage, address, false)
-				for {
-					bt, hasNext := iterFunc()
-					count--
-					if !hasNext || count < 0 {
-						break
+			streaming(w, r, streamSource{
+				Name:       "transaction",
+				Observable: observer.BlockTransactionObserver,
+				Events:     fmt.Sprintf("source-%s", address),
+				Event:      blockTransactionStreamEvent,
+				Replay: func(lastID string, send func(streamEvent) error) error {
+					var iterFunc func() (BlockTransaction, bool)
+					var closeFunc func()
+					if len(lastID) < 1 {
+						iterFunc, closeFunc = GetBlockTransactionsByAccount(storage, address, false)
+					} else {
+						// same with the streamed ones, only the transactions from
+						// the account are resumed
+						iterFunc, closeFunc = GetBlockTransactionsBySourceFromConfirmed(storage, address, streamIDConfirmed(lastID))
 					}
-					observer.BlockTransactionObserver.Trigger(fmt.Sprintf("iterate-%s", iterateId), &bt)
-				}
-				closeFunc()
-			}()
+					defer closeFunc()
 
-			callBackFunc := func(args ...interface{}) (btSerialized []byte, err error) {
-				bt := args[1].(*BlockTransaction)
-				if btSerialized, err = bt.Serialize(); err != nil {
-					return []byte{}, sebakerror.ErrorBlockTransactionDoesNotExists
-				}
-				return btSerialized, nil
-			}
-
-			event := fmt.Sprintf("iterate-%s", iterateId)
-			event += " " + fmt.Sprintf("source-%s", address)
-			streaming(observer.BlockTransactionObserver, w, event, callBackFunc, readyChan)
+					return replayStreamEvents(blockTransactionStreamEvents(iterFunc), lastID, send)
+				},
+			})
 		default:
 
 			var btl []BlockTransaction
@@ -135,7 +272,7 @@ func GetAccountTransactionsHandler(storage *sebakstorage.LevelDBBackend) http.Ha
 			s, err = sebakcommon.EncodeJSONValue(btl)
 
 			if _, err = w.Write(s); err != nil {
-				http.Error(w, "Error reading request body", http.StatusInternalServerError)
+				sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
 				return
 			}
 		}
@@ -144,63 +281,196 @@ func GetAccountTransactionsHandler(storage *sebakstorage.LevelDBBackend) http.Ha
 
 const GetAccountOperationsHandlerPattern = "/account/{address}/operations"
 
-func GetAccountOperationsHandler(storage *sebakstorage.LevelDBBackend) http.HandlerFunc {
-
+func GetAccountOperationsHandler(storage sebakstorage.DBBackend) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		address := mux.Vars(r)["address"]
 
-		vars := mux.Vars(r)
-		address := vars["address"]
+		blockOperationsHandler(w, r, fmt.Sprintf("source-%s", address), func() (func() (BlockOperation, bool), func()) {
+			return GetBlockOperationsBySource(storage, address, false)
+		}, func(confirmed string) (func() (BlockOperation, bool), func()) {
+			return GetBlockOperationsBySourceFromConfirmed(storage, address, confirmed)
+		})
+	}
+}
 
-		var err error
-		var s []byte
+// GetAccountOperationsWithHandlerPattern lists the operations between two
+// accounts; the operations from `with` to `address` are also included.
+const GetAccountOperationsWithHandlerPattern = "/account/{address}/operations/with/{with}"
 
-		switch r.Header.Get("Accept") {
-		case "text/event-stream":
-			var readyChan = make(chan struct{})
-			iterateId := sebakcommon.GetUniqueIDFromUUID()
-			go func() {
-				<-readyChan
-				count := maxNumberOfExistingData
-				iterFunc, closeFunc := GetBlockOperationsBySource(storage, address, false)
-				for {
-					bo, hasNext := iterFunc()
-					count--
-					if !hasNext || count < 0 {
-						break
-					}
-					observer.BlockOperationObserver.Trigger(fmt.Sprintf("iterate-%s", iterateId), &bo)
-				}
-				closeFunc()
-			}()
+func GetAccountOperationsWithHandler(storage sebakstorage.DBBackend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		address, with := vars["address"], vars["with"]
+
+		peers := blockOperationPeers(address, with)
+		blockOperationsHandler(w, r, fmt.Sprintf("peers-%s", peers), func() (func() (BlockOperation, bool), func()) {
+			return GetBlockOperationsByPeers(storage, address, with, false)
+		}, func(confirmed string) (func() (BlockOperation, bool), func()) {
+			return GetBlockOperationsByPeersFromConfirmed(storage, address, with, confirmed)
+		})
+	}
+}
 
-			callBackFunc := func(args ...interface{}) (boSerialized []byte, err error) {
-				bo := args[1].(*BlockOperation)
-				if boSerialized, err = bo.Serialize(); err != nil {
-					return []byte{}, sebakerror.ErrorBlockTransactionDoesNotExists
-				}
-				return boSerialized, nil
+// NewBlockOperationFilterFromQuery parses the filter of operation list
+// endpoints,
+//  * `type`: operation type, it can be repeated or separated by comma
+//  * `since`, `until`: the range of confirmed time in ISO8601
+func NewBlockOperationFilterFromQuery(query url.Values) (f BlockOperationFilter, err error) {
+	for _, v := range query["type"] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); len(t) < 1 {
+				continue
 			}
-			event := fmt.Sprintf("iterate-%s", iterateId)
-			event += " " + fmt.Sprintf("source-%s", address)
-			streaming(observer.BlockOperationObserver, w, event, callBackFunc, readyChan)
-		default:
+			switch OperationType(t) {
+			case OperationCreateAccount, OperationPayment:
+				f.Types = append(f.Types, OperationType(t))
+			default:
+				err = sebakerror.ErrorUnknownOperationType
+				return
+			}
+		}
+	}
 
-			var bol []BlockOperation
-			iterFunc, closeFunc := GetBlockOperationsBySource(storage, address, false)
-			for {
-				bo, hasNext := iterFunc()
-				if !hasNext {
-					break
+	if v := query.Get("since"); len(v) > 0 {
+		if f.Since, err = parseTimeQuery(v); err != nil {
+			return
+		}
+	}
+	if v := query.Get("until"); len(v) > 0 {
+		if f.Until, err = parseTimeQuery(v); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// parseTimeQuery accepts the layout of `Confirmed` and RFC3339.
+func parseTimeQuery(v string) (t time.Time, err error) {
+	if t, err = sebakcommon.ParseISO8601(v); err == nil {
+		return
+	}
+
+	if t, err = time.Parse(time.RFC3339Nano, v); err != nil {
+		err = fmt.Errorf("invalid time: '%s'", v)
+	}
+
+	return
+}
+
+// writeBlockOperationFilterProblem responds the invalid filter of request.
+func writeBlockOperationFilterProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := sebaknetwork.NewProblem(sebaknetwork.ProblemStatus(err, http.StatusBadRequest), err)
+	for _, name := range []string{"type", "since", "until"} {
+		if v, found := r.URL.Query()[name]; found {
+			problem = problem.With(name, v)
+		}
+	}
+	problem.Write(w)
+}
+
+// blockOperationsHandler responds the operations of `load` by the filter of
+// request; with `text/event-stream`, the new operations of `events` are
+// streamed. At resuming the stream, `resume` reads the operations of `load`
+// from the confirmed time of `Last-Event-ID`.
+func blockOperationsHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+	events string,
+	load func() (func() (BlockOperation, bool), func()),
+	resume func(confirmed string) (func() (BlockOperation, bool), func()),
+) {
+	filter, err := NewBlockOperationFilterFromQuery(r.URL.Query())
+	if err != nil {
+		writeBlockOperationFilterProblem(w, r, err)
+		return
+	}
+
+	switch r.Header.Get("Accept") {
+	case "text/event-stream":
+		streaming(w, r, streamSource{
+			Name:       "operation",
+			Observable: observer.BlockOperationObserver,
+			Events:     events,
+			Event:      blockOperationStreamEvent,
+			Match: func(v interface{}) bool {
+				bo, ok := v.(*BlockOperation)
+				return ok && filter.Match(*bo)
+			},
+			Replay: func(lastID string, send func(streamEvent) error) error {
+				var iterFunc func() (BlockOperation, bool)
+				var closeFunc func()
+				if len(lastID) < 1 {
+					iterFunc, closeFunc = load()
+				} else {
+					iterFunc, closeFunc = resume(streamIDConfirmed(lastID))
 				}
-				bol = append(bol, bo)
+				defer closeFunc()
+
+				return replayStreamEvents(blockOperationStreamEvents(FilterBlockOperations(iterFunc, filter)), lastID, send)
+			},
+		})
+	default:
+		var bol []BlockOperation
+		iterFunc, closeFunc := load()
+		iterFunc = FilterBlockOperations(iterFunc, filter)
+		for {
+			bo, hasNext := iterFunc()
+			if !hasNext {
+				break
 			}
-			closeFunc()
+			bol = append(bol, bo)
+		}
+		closeFunc()
 
-			s, err = sebakcommon.EncodeJSONValue(bol)
+		var s []byte
+		if s, err = sebakcommon.EncodeJSONValue(bol); err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+		if _, err = w.Write(s); err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+}
 
-			if _, err = w.Write(s); err != nil {
-				http.Error(w, "Error reading request body", http.StatusInternalServerError)
-				return
+func blockAccountStreamEvent(v interface{}) (e streamEvent, err error) {
+	ba, ok := v.(*block.BlockAccount)
+	if !ok {
+		err = sebakerror.ErrorBlockAccountDoesNotExists
+		return
+	}
+
+	// the account is streamed by it's latest state, so `Checkpoint` is enough
+	e.ID = ba.Checkpoint
+	e.Data, err = ba.Serialize()
+
+	return
+}
+
+func blockOperationStreamEvent(v interface{}) (e streamEvent, err error) {
+	bo, ok := v.(*BlockOperation)
+	if !ok {
+		err = sebakerror.ErrorBlockOperationDoesNotExists
+		return
+	}
+
+	e.ID = makeStreamID(bo.Confirmed, bo.Hash)
+	e.Data, err = bo.Serialize()
+
+	return
+}
+
+func blockOperationStreamEvents(iterFunc func() (BlockOperation, bool)) func() (streamEvent, bool) {
+	return func() (streamEvent, bool) {
+		for {
+			bo, hasNext := iterFunc()
+			if !hasNext {
+				return streamEvent{}, false
+			}
+			if e, err := blockOperationStreamEvent(&bo); err == nil {
+				return e, true
 			}
 		}
 	}
@@ -0,0 +1,85 @@
+package sebak
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"boscoin.io/sebak/lib/network"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// GetOperationsHandlerPattern lists all the operations by the confirmed
+// order; it is paginated by `cursor`, `limit` and `order`, and filtered like
+// the operations of account, see `NewBlockOperationFilterFromQuery`.
+const GetOperationsHandlerPattern = "/operations"
+
+func GetOperationsHandler(storage sebakstorage.DBBackend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := newPageQuery(r.URL.Query())
+		if err != nil {
+			writePageQueryProblem(w, r, err)
+			return
+		}
+
+		filter, err := NewBlockOperationFilterFromQuery(r.URL.Query())
+		if err != nil {
+			writeBlockOperationFilterProblem(w, r, err)
+			return
+		}
+
+		iterFunc, closeFunc := BlockOperationModel.GetIteratorAfter(storage, BlockOperationIndexConfirmed, q.Cursor, q.Reverse)
+		defer closeFunc()
+
+		cursor := func(item sebakstorage.IterItem) string {
+			return BlockOperationModel.Cursor(BlockOperationIndexConfirmed, item)
+		}
+		record := func(item sebakstorage.IterItem) (interface{}, error) {
+			var bo BlockOperation
+			if err := json.Unmarshal(item.Value, &bo); err != nil {
+				return nil, err
+			}
+			if !filter.Match(bo) {
+				return nil, nil
+			}
+			return bo, nil
+		}
+
+		var page Page
+		if page, err = loadPage(q, iterFunc, cursor, record); err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		var s []byte
+		if s, err = page.Serialize(); err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s)
+	}
+}
+
+// GetLedgerStatsHandlerPattern responds `LedgerStats`; the stats are stored
+// when the accounts and transactions are saved, so the ledger is not scanned.
+const GetLedgerStatsHandlerPattern = "/stats"
+
+func GetLedgerStatsHandler(storage sebakstorage.DBBackend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := GetLedgerStats(storage)
+		if err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		s, err := stats.Serialize()
+		if err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s)
+	}
+}
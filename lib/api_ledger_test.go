@@ -0,0 +1,174 @@
+package sebak
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestGetLedgerListHandlers(t *testing.T) {
+	storage, err := sebakstorage.NewTestMemoryLevelDBBackend()
+	require.Nil(t, err)
+	defer storage.Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc(GetAccountsHandlerPattern, GetAccountsHandler(storage)).Methods("GET")
+	router.HandleFunc(GetTransactionsHandlerPattern, GetTransactionsHandler(storage)).Methods("GET")
+	router.HandleFunc(GetOperationsHandlerPattern, GetOperationsHandler(storage)).Methods("GET")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	var addresses []string
+	for i := 0; i < 5; i++ {
+		ba := block.TestMakeBlockAccount()
+		require.Nil(t, ba.Save(storage))
+		addresses = append(addresses, ba.Address)
+	}
+
+	var bts []BlockTransaction
+	for i := 0; i < 5; i++ {
+		_, tx := TestMakeTransaction(networkID, 2)
+		a, _ := tx.Serialize()
+		bt := NewBlockTransactionFromTransaction(tx, a)
+		require.Nil(t, bt.Save(storage))
+		bts = append(bts, bt)
+	}
+
+	type page struct {
+		Records []json.RawMessage
+		Next    string
+	}
+
+	get := func(path string, query url.Values) (int, page) {
+		resp, err := http.Get(ts.URL + path + "?" + query.Encode())
+		require.Nil(t, err)
+		defer resp.Body.Close()
+
+		var p page
+		if resp.StatusCode == http.StatusOK {
+			require.Nil(t, json.NewDecoder(resp.Body).Decode(&p))
+		}
+		return resp.StatusCode, p
+	}
+
+	// collect follows `Next` until the last page
+	collect := func(path string, query url.Values) (records []json.RawMessage) {
+		for {
+			status, p := get(path, query)
+			require.Equal(t, http.StatusOK, status)
+			records = append(records, p.Records...)
+			if len(p.Next) < 1 {
+				return
+			}
+			query.Set("cursor", p.Next)
+		}
+	}
+
+	{ // accounts
+		records := collect(GetAccountsHandlerPattern, url.Values{"limit": []string{"2"}})
+		require.Equal(t, len(addresses), len(records))
+
+		var received []string
+		for _, r := range records {
+			var ba block.BlockAccount
+			require.Nil(t, json.Unmarshal(r, &ba))
+			received = append(received, ba.Address)
+		}
+		require.ElementsMatch(t, addresses, received)
+
+		// descending order is reversed
+		reversed := collect(GetAccountsHandlerPattern, url.Values{"limit": []string{"3"}, "order": []string{"desc"}})
+		for i, r := range reversed {
+			require.Equal(t, records[len(records)-1-i], r)
+		}
+	}
+
+	{ // transactions
+		records := collect(GetTransactionsHandlerPattern, url.Values{"limit": []string{"2"}})
+		require.Equal(t, len(bts), len(records))
+
+		for i, r := range records {
+			var bt BlockTransaction
+			require.Nil(t, json.Unmarshal(r, &bt))
+			require.Equal(t, bts[i].Hash, bt.Hash)
+		}
+	}
+
+	{ // operations
+		records := collect(GetOperationsHandlerPattern, url.Values{"limit": []string{"3"}})
+		require.Equal(t, len(bts)*2, len(records))
+
+		for i, r := range records {
+			var bo BlockOperation
+			require.Nil(t, json.Unmarshal(r, &bo))
+			require.Equal(t, bts[i/2].Hash, bo.TxHash)
+		}
+
+		_, p := get(GetOperationsHandlerPattern, url.Values{"type": []string{string(OperationCreateAccount)}})
+		require.Empty(t, p.Records)
+	}
+
+	{ // invalid
+		status, _ := get(GetAccountsHandlerPattern, url.Values{"limit": []string{"showme"}})
+		require.Equal(t, http.StatusBadRequest, status)
+
+		status, _ = get(GetOperationsHandlerPattern, url.Values{"type": []string{"showme"}})
+		require.Equal(t, http.StatusBadRequest, status)
+	}
+}
+
+func TestGetLedgerStatsHandler(t *testing.T) {
+	storage, err := sebakstorage.NewTestMemoryLevelDBBackend()
+	require.Nil(t, err)
+	defer storage.Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc(GetLedgerStatsHandlerPattern, GetLedgerStatsHandler(storage)).Methods("GET")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	get := func() (stats LedgerStats) {
+		resp, err := http.Get(ts.URL + GetLedgerStatsHandlerPattern)
+		require.Nil(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Nil(t, json.NewDecoder(resp.Body).Decode(&stats))
+		return
+	}
+
+	require.Equal(t, LedgerStats{CirculatingSupply: "0", FeesCollected: "0"}, get())
+
+	kp, _ := keypair.Random()
+	ba := block.NewBlockAccount(kp.Address(), sebakcommon.Amount(1000000), sebakcommon.MakeGenesisCheckpoint(networkID))
+	require.Nil(t, ba.Save(storage))
+
+	for i := 0; i < 3; i++ {
+		tx := TestMakeTransactionWithKeypair(networkID, 2, kp)
+		a, _ := tx.Serialize()
+		bt := NewBlockTransactionFromTransaction(tx, a)
+		require.Nil(t, bt.Save(storage))
+
+		require.Nil(t, ba.Withdraw(tx.TotalAmount(true), tx.NextSourceCheckpoint()))
+		require.Nil(t, ba.Save(storage))
+	}
+
+	stats := get()
+	require.Equal(t, uint64(1), stats.Accounts)
+	require.Equal(t, uint64(3), stats.Transactions)
+	require.Equal(t, uint64(6), stats.Operations)
+	require.Equal(t, ba.Balance, stats.CirculatingSupply)
+	require.Equal(t, BaseFee.MustMult(6).String(), stats.FeesCollected)
+}
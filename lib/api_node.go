@@ -0,0 +1,135 @@
+package sebak
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/network"
+)
+
+const GetNodeStatusHandlerPattern = "/node/status"
+
+type NodeStatusNode struct {
+	Address  string `json:"address"`
+	Alias    string `json:"alias"`
+	Endpoint string `json:"endpoint"`
+	Mode     string `json:"mode"`
+	State    string `json:"state"`
+}
+
+// NodeStatusPolicy is the current `VotingThresholdPolicy`; `Thresholds` is
+// the number of votes to pass each `BallotState`.
+type NodeStatusPolicy struct {
+	Validators int            `json:"validators"`
+	Connected  int            `json:"connected"`
+	Thresholds map[string]int `json:"thresholds"`
+}
+
+// NodeStatusVoting is the progress of one `VotingResult` in `BallotBoxes`.
+type NodeStatusVoting struct {
+	Hash      string `json:"hash"`
+	Source    string `json:"source"`
+	Box       string `json:"box"`
+	State     string `json:"state"`
+	Yes       int    `json:"yes"`
+	No        int    `json:"no"`
+	Threshold int    `json:"threshold"`
+}
+
+// NodeStatusConfirmed is the latest confirmed transaction and the new
+// checkpoint of its source account.
+type NodeStatusConfirmed struct {
+	Hash       string `json:"hash"`
+	Checkpoint string `json:"checkpoint"`
+	Confirmed  string `json:"confirmed"`
+}
+
+// NodeStatus is the response of `GetNodeStatusHandler`.
+type NodeStatus struct {
+	Node       NodeStatusNode                 `json:"node"`
+	Validators []sebaknetwork.ValidatorHealth `json:"validators"`
+	Policy     NodeStatusPolicy               `json:"policy"`
+	Boxes      map[string]int                 `json:"boxes"` // the number of messages in each box
+	Votings    []NodeStatusVoting             `json:"votings"`
+	Latest     *NodeStatusConfirmed           `json:"latest,omitempty"`
+}
+
+func (s NodeStatus) Serialize() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// GetNodeStatus collects the consensus state of `NodeRunner`; it is the
+// snapshot, so the votings can be already finished when it is returned.
+func GetNodeStatus(nr *NodeRunner) (status NodeStatus) {
+	localNode := nr.Node()
+	status.Node = NodeStatusNode{
+		Address:  localNode.Address(),
+		Alias:    localNode.Alias(),
+		Endpoint: localNode.Endpoint().String(),
+		Mode:     localNode.Mode().String(),
+		State:    localNode.State().String(),
+	}
+
+	status.Validators = nr.ConnectionManager().Health()
+
+	policy := nr.Policy()
+	status.Policy = NodeStatusPolicy{
+		Validators: policy.Validators(),
+		Connected:  policy.Connected(),
+		Thresholds: map[string]int{},
+	}
+	for _, state := range []sebakcommon.BallotState{
+		sebakcommon.BallotStateINIT,
+		sebakcommon.BallotStateSIGN,
+		sebakcommon.BallotStateACCEPT,
+	} {
+		status.Policy.Thresholds[state.String()] = policy.Threshold(state)
+	}
+
+	status.Boxes = map[string]int{
+		BallotBoxWaiting:  0,
+		BallotBoxVoting:   0,
+		BallotBoxReserved: 0,
+	}
+	status.Votings = []NodeStatusVoting{}
+	if isaac, ok := nr.Consensus().(*ISAAC); ok {
+		for _, snapshot := range isaac.Boxes.VotingSnapshots() {
+			status.Boxes[snapshot.Box]++
+			status.Votings = append(status.Votings, NodeStatusVoting{
+				Hash:      snapshot.MessageHash,
+				Source:    snapshot.Source,
+				Box:       snapshot.Box,
+				State:     snapshot.State.String(),
+				Yes:       snapshot.Yes,
+				No:        snapshot.No,
+				Threshold: policy.Threshold(snapshot.State),
+			})
+		}
+	}
+
+	iterFunc, closeFunc := GetBlockTransactionsByConfirmed(nr.Storage(), true)
+	if bt, hasNext := iterFunc(); hasNext {
+		status.Latest = &NodeStatusConfirmed{
+			Hash:       bt.Hash,
+			Checkpoint: bt.SourceCheckpoint,
+			Confirmed:  bt.Confirmed,
+		}
+	}
+	closeFunc()
+
+	return
+}
+
+func GetNodeStatusHandler(nr *NodeRunner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s, err := GetNodeStatus(nr).Serialize()
+		if err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s)
+	}
+}
@@ -3,23 +3,57 @@ package sebak
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"boscoin.io/sebak/lib/block"
 	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+	"boscoin.io/sebak/lib/node"
+	"boscoin.io/sebak/lib/observer"
 	"boscoin.io/sebak/lib/storage"
 
+	"github.com/GianlucaGuarini/go-observable"
 	"github.com/gorilla/mux"
 	"github.com/stellar/go/keypair"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// readStreamEvent reads one event of `Server-Sent Events`; the comments are
+// skipped.
+func readStreamEvent(reader *bufio.Reader) (e streamEvent, err error) {
+	for {
+		var line []byte
+		if line, err = reader.ReadBytes('\n'); err != nil {
+			return
+		}
+		line = bytes.TrimRight(line, "\n")
+
+		switch {
+		case len(line) < 1:
+			if len(e.ID) > 0 || len(e.Data) > 0 {
+				return
+			}
+		case bytes.HasPrefix(line, []byte("id: ")):
+			e.ID = string(line[4:])
+		case bytes.HasPrefix(line, []byte("data: ")):
+			if len(e.Data) > 0 {
+				e.Data = append(e.Data, '\n')
+			}
+			e.Data = append(e.Data, line[6:]...)
+		}
+	}
+}
+
 func TestGetAccountHandler(t *testing.T) {
 	// Setting Server
 	storage, err := sebakstorage.NewTestMemoryLevelDBBackend()
@@ -65,8 +99,9 @@ func TestGetAccountHandler(t *testing.T) {
 	var n sebakcommon.Amount
 	for n = 0; n < 10; n++ {
 		<-recv
-		line, err := reader.ReadBytes('\n')
+		e, err := readStreamEvent(reader)
 		require.Nil(t, err)
+		line := e.Data
 		var cba = &block.BlockAccount{}
 		json.Unmarshal(line, cba)
 		require.Equal(t, ba.Address, cba.Address)
@@ -156,9 +191,9 @@ func TestGetAccountTransactionsHandler(t *testing.T) {
 	var n sebakcommon.Amount
 	for n = 0; n < 10; n++ {
 		<-recv
-		line, err := reader.ReadBytes('\n')
+		e, err := readStreamEvent(reader)
 		require.Nil(t, err)
-		line = bytes.Trim(line, "\n\t ")
+		line := e.Data
 		txS, err := bts[n].Serialize()
 		require.Nil(t, err)
 		require.Equal(t, txS, line)
@@ -257,9 +292,9 @@ func TestGetAccountOperationsHandler(t *testing.T) {
 	var n sebakcommon.Amount
 	for n = 0; n < 10; n++ {
 		<-recv
-		line, err := reader.ReadBytes('\n')
+		e, err := readStreamEvent(reader)
 		require.Nil(t, err)
-		line = bytes.Trim(line, "\n\t ")
+		line := e.Data
 		txS, err := bos[n].Serialize()
 		require.Nil(t, err)
 		require.Equal(t, txS, line)
@@ -321,9 +356,9 @@ func TestGetTransactionByHashHandler(t *testing.T) {
 	require.Nil(t, err)
 	defer resp.Body.Close()
 	reader := bufio.NewReader(resp.Body)
-	line, err := reader.ReadBytes('\n')
+	e, err := readStreamEvent(reader)
 	require.Nil(t, err)
-	line = bytes.Trim(line, "\n\t ")
+	line := e.Data
 
 	serializedBt, err := bt.Serialize()
 	require.Nil(t, err)
@@ -404,9 +439,9 @@ func TestGetTransactionsHandler(t *testing.T) {
 	// Do stream Request to the Server
 	for n := 0; n < 10; n++ {
 		<-recv
-		line, err := reader.ReadBytes('\n')
+		e, err := readStreamEvent(reader)
 		require.Nil(t, err)
-		line = bytes.Trim(line, "\n\t ")
+		line := e.Data
 		txS, err := bts[n].Serialize()
 		require.Nil(t, err)
 		require.Equal(t, txS, line)
@@ -422,8 +457,13 @@ func TestGetTransactionsHandler(t *testing.T) {
 	reader = bufio.NewReader(resp2.Body)
 	readByte, err := ioutil.ReadAll(reader)
 	require.Nil(t, err)
-	var receivedBts []BlockTransaction
-	json.Unmarshal(readByte, &receivedBts)
+	var page struct {
+		Records []BlockTransaction `json:"records"`
+		Next    string             `json:"next"`
+	}
+	require.Nil(t, json.Unmarshal(readByte, &page))
+	require.Empty(t, page.Next)
+	receivedBts := page.Records
 
 	require.Equal(t, len(bts), len(receivedBts), "length is not same")
 
@@ -433,3 +473,776 @@ func TestGetTransactionsHandler(t *testing.T) {
 		i++
 	}
 }
+
+// TestGetTransactionsHandlerResume checks the stream, which is resumed by
+// `Last-Event-ID` sends the transactions after it in the confirmed order.
+func TestGetTransactionsHandlerResume(t *testing.T) {
+	storage, err := sebakstorage.NewTestMemoryLevelDBBackend()
+	require.Nil(t, err)
+	defer storage.Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc(GetTransactionsHandlerPattern, GetTransactionsHandler(storage)).Methods("GET")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	started := time.Now()
+	var bts []BlockTransaction
+	for i := 0; i < 15; i++ {
+		_, tx := TestMakeTransaction(networkID, 1)
+
+		a, err := tx.Serialize()
+		require.Nil(t, err)
+		bt := NewBlockTransactionFromTransaction(tx, a)
+		bt.Confirmed = sebakcommon.FormatISO8601(started.Add(time.Duration(i) * time.Second))
+		require.Nil(t, bt.Save(storage))
+		bts = append(bts, bt)
+	}
+
+	req, err := http.NewRequest("GET", ts.URL+"/transactions", nil)
+	require.Nil(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Last-Event-ID", makeStreamID(bts[11].Confirmed, bts[11].Hash))
+	resp, err := ts.Client().Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	for _, bt := range bts[12:] {
+		e, err := readStreamEvent(reader)
+		require.Nil(t, err)
+		require.Equal(t, makeStreamID(bt.Confirmed, bt.Hash), e.ID)
+	}
+}
+
+// `Last-Event-ID` of the account stream sends the transactions of the
+// account after it.
+func TestGetAccountTransactionsHandlerResume(t *testing.T) {
+	storage, err := sebakstorage.NewTestMemoryLevelDBBackend()
+	require.Nil(t, err)
+	defer storage.Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc(GetAccountTransactionsHandlerPattern, GetAccountTransactionsHandler(storage)).Methods("GET")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	kp, _ := keypair.Random()
+	kpOther, _ := keypair.Random()
+
+	started := time.Now()
+	var bts []BlockTransaction
+	for i := 0; i < 10; i++ {
+		source := kp
+		if i%2 == 1 {
+			source = kpOther
+		}
+		tx := TestMakeTransactionWithKeypair(networkID, 1, source)
+
+		a, err := tx.Serialize()
+		require.Nil(t, err)
+		bt := NewBlockTransactionFromTransaction(tx, a)
+		bt.Confirmed = sebakcommon.FormatISO8601(started.Add(time.Duration(i) * time.Second))
+		require.Nil(t, bt.Save(storage))
+		if source == kp {
+			bts = append(bts, bt)
+		}
+	}
+
+	req, err := http.NewRequest("GET", ts.URL+fmt.Sprintf("/account/%s/transactions", kp.Address()), nil)
+	require.Nil(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Last-Event-ID", makeStreamID(bts[1].Confirmed, bts[1].Hash))
+	resp, err := ts.Client().Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	for _, bt := range bts[2:] {
+		e, err := readStreamEvent(reader)
+		require.Nil(t, err)
+		require.Equal(t, makeStreamID(bt.Confirmed, bt.Hash), e.ID)
+	}
+}
+
+func TestGetAccountHistoryHandler(t *testing.T) {
+	storage, err := sebakstorage.NewTestMemoryLevelDBBackend()
+	require.Nil(t, err)
+	defer storage.Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc(GetAccountHistoryHandlerPattern, GetAccountHistoryHandler(storage)).Methods("GET")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	kp, _ := keypair.Random()
+	ba := block.NewBlockAccount(kp.Address(), sebakcommon.Amount(10000), sebakcommon.MakeGenesisCheckpoint(networkID))
+	require.Nil(t, ba.Save(storage))
+	require.Nil(t, SaveBlockAccountHistory(storage, ba, nil))
+
+	var bts []BlockTransaction
+	for i := 0; i < 3; i++ {
+		tx := TestMakeTransactionWithKeypair(networkID, 1, kp)
+		a, _ := tx.Serialize()
+		bt := NewBlockTransactionFromTransaction(tx, a)
+		require.Nil(t, bt.Save(storage))
+		bts = append(bts, bt)
+
+		require.Nil(t, ba.Withdraw(sebakcommon.Amount(100), tx.NextSourceCheckpoint()))
+		require.Nil(t, ba.Save(storage))
+		require.Nil(t, SaveBlockAccountHistory(storage, ba, &bt))
+	}
+
+	type historyPage struct {
+		Records []BlockAccountHistory
+		Next    string
+	}
+
+	get := func(address, query string) (int, historyPage) {
+		resp, err := http.Get(ts.URL + fmt.Sprintf("/account/%s/history", address) + query)
+		require.Nil(t, err)
+		defer resp.Body.Close()
+
+		var page historyPage
+		if resp.StatusCode == http.StatusOK {
+			require.Nil(t, json.NewDecoder(resp.Body).Decode(&page))
+		}
+		return resp.StatusCode, page
+	}
+
+	{ // all
+		status, page := get(kp.Address(), "")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 4, len(page.Records))
+		require.Empty(t, page.Next)
+
+		// genesis
+		require.Equal(t, "10000", page.Records[0].Balance)
+		require.Empty(t, page.Records[0].Hash)
+
+		for i, bt := range bts {
+			h := page.Records[i+1]
+			require.Equal(t, bt.Hash, h.Hash)
+			require.Equal(t, bt.Confirmed, h.Confirmed)
+			require.Equal(t, bt.SourceCheckpoint, h.Checkpoint)
+			require.Equal(t, sebakcommon.Amount(10000-100*(i+1)).String(), h.Balance)
+		}
+	}
+
+	{ // paginated
+		_, page := get(kp.Address(), "?limit=3")
+		require.Equal(t, 3, len(page.Records))
+		require.Equal(t, page.Records[2].ID, page.Next)
+
+		_, page = get(kp.Address(), "?limit=3&cursor="+url.QueryEscape(page.Next))
+		require.Equal(t, 1, len(page.Records))
+		require.Equal(t, bts[2].Hash, page.Records[0].Hash)
+		require.Empty(t, page.Next)
+
+		_, page = get(kp.Address(), "?limit=1&order=desc")
+		require.Equal(t, bts[2].Hash, page.Records[0].Hash)
+
+		_, page = get(kp.Address(), "?limit=1&order=desc&cursor="+url.QueryEscape(page.Next))
+		require.Equal(t, bts[1].Hash, page.Records[0].Hash)
+	}
+
+	{ // daily closing balance
+		_, page := get(kp.Address(), "?bucket=day")
+		require.True(t, len(page.Records) > 0)
+
+		last := page.Records[len(page.Records)-1]
+		require.Equal(t, bts[2].Hash, last.Hash)
+		require.Equal(t, last.Date, last.ID)
+	}
+
+	{ // invalid
+		status, _ := get(kp.Address(), "?limit=showme")
+		require.Equal(t, http.StatusBadRequest, status)
+
+		status, _ = get(kp.Address(), "?bucket=showme")
+		require.Equal(t, http.StatusBadRequest, status)
+
+		unknown, _ := keypair.Random()
+		status, _ = get(unknown.Address(), "")
+		require.Equal(t, http.StatusNotFound, status)
+	}
+}
+
+func TestGetAccountOperationsWithHandler(t *testing.T) {
+	storage, err := sebakstorage.NewTestMemoryLevelDBBackend()
+	require.Nil(t, err)
+	defer storage.Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc(GetAccountOperationsWithHandlerPattern, GetAccountOperationsWithHandler(storage)).Methods("GET")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	kpA, _ := keypair.Random()
+	kpB, _ := keypair.Random()
+	kpC, _ := keypair.Random()
+
+	save := func(source, target *keypair.Full) BlockTransaction {
+		tx := TestMakeTransactionWithKeypair(networkID, 2, source, target)
+		a, _ := tx.Serialize()
+		bt := NewBlockTransactionFromTransaction(tx, a)
+		require.Nil(t, bt.Save(storage))
+		return bt
+	}
+
+	btAB := save(kpA, kpB)
+	btBA := save(kpB, kpA)
+	save(kpA, kpC)
+
+	get := func(query string) (int, []BlockOperation) {
+		resp, err := http.Get(ts.URL + fmt.Sprintf("/account/%s/operations/with/%s", kpA.Address(), kpB.Address()) + query)
+		require.Nil(t, err)
+		defer resp.Body.Close()
+
+		var bos []BlockOperation
+		if resp.StatusCode == http.StatusOK {
+			require.Nil(t, json.NewDecoder(resp.Body).Decode(&bos))
+		}
+		return resp.StatusCode, bos
+	}
+
+	{ // both directions
+		status, bos := get("")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 4, len(bos))
+		for _, bo := range bos {
+			require.NotEqual(t, kpC.Address(), bo.Target)
+		}
+	}
+
+	{ // filters
+		_, bos := get("?type=payment")
+		require.Equal(t, 4, len(bos))
+
+		_, bos = get("?type=create-account")
+		require.Equal(t, 0, len(bos))
+
+		_, bos = get("?since=" + url.QueryEscape(btBA.Confirmed))
+		require.Equal(t, 2, len(bos))
+		for _, bo := range bos {
+			require.Equal(t, btBA.Hash, bo.TxHash)
+		}
+
+		_, bos = get("?until=" + url.QueryEscape(btBA.Confirmed))
+		require.Equal(t, 2, len(bos))
+		for _, bo := range bos {
+			require.Equal(t, btAB.Hash, bo.TxHash)
+		}
+
+		status, _ := get("?type=showme")
+		require.Equal(t, http.StatusBadRequest, status)
+
+		status, _ = get("?since=showme")
+		require.Equal(t, http.StatusBadRequest, status)
+	}
+
+	stream := func(lastID string) (*http.Response, *bufio.Reader) {
+		req, err := http.NewRequest("GET", ts.URL+fmt.Sprintf("/account/%s/operations/with/%s", kpB.Address(), kpA.Address()), nil)
+		require.Nil(t, err)
+		req.Header.Set("Accept", "text/event-stream")
+		if len(lastID) > 0 {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := ts.Client().Do(req)
+		require.Nil(t, err)
+		return resp, bufio.NewReader(resp.Body)
+	}
+
+	var lastID string
+	var bt BlockTransaction
+	{ // stream
+		resp, reader := stream("")
+		defer resp.Body.Close()
+
+		for i := 0; i < 4; i++ {
+			e, err := readStreamEvent(reader)
+			require.Nil(t, err)
+			if e.ID > lastID {
+				lastID = e.ID
+			}
+		}
+
+		// the operations of the other account are not streamed
+		save(kpA, kpC)
+		bt = save(kpA, kpB)
+
+		for i := 0; i < 2; i++ {
+			e, err := readStreamEvent(reader)
+			require.Nil(t, err)
+
+			var bo BlockOperation
+			require.Nil(t, json.Unmarshal(e.Data, &bo))
+			require.Equal(t, bt.Hash, bo.TxHash)
+		}
+	}
+
+	{ // resume from `Last-Event-ID`; the operations of the other account are
+		// not replayed
+		resp, reader := stream(lastID)
+		defer resp.Body.Close()
+
+		for i := 0; i < 2; i++ {
+			e, err := readStreamEvent(reader)
+			require.Nil(t, err)
+
+			var bo BlockOperation
+			require.Nil(t, json.Unmarshal(e.Data, &bo))
+			require.Equal(t, bt.Hash, bo.TxHash)
+		}
+	}
+}
+
+func TestPostTransactionHandler(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunnersWithReady(3)
+	for _, nr := range nodeRunners {
+		defer nr.Stop()
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc(PostTransactionHandlerPattern, PostTransactionHandler(nodeRunners[0])).Methods("POST")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	kpSource, _ := keypair.Random()
+	kpTarget, _ := keypair.Random()
+
+	checkpoint := sebakcommon.MakeGenesisCheckpoint(networkID)
+	accountSource := block.NewBlockAccount(kpSource.Address(), BaseFee.MustAdd(1), checkpoint)
+	accountTarget := block.NewBlockAccount(kpTarget.Address(), sebakcommon.Amount(2000), checkpoint)
+	for _, nr := range nodeRunners {
+		accountSource.Save(nr.Storage())
+		accountTarget.Save(nr.Storage())
+	}
+
+	tx := makeTransactionPayment(kpSource, kpTarget.Address(), sebakcommon.Amount(1))
+	tx.B.Checkpoint = accountSource.Checkpoint
+	tx.Sign(kpSource, networkID)
+	body, err := tx.Serialize()
+	require.Nil(t, err)
+
+	post := func(url string, body []byte) (int, TransactionSubmitResult) {
+		resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+		require.Nil(t, err)
+		defer resp.Body.Close()
+
+		var result TransactionSubmitResult
+		require.Nil(t, json.NewDecoder(resp.Body).Decode(&result))
+		return resp.StatusCode, result
+	}
+
+	{ // confirmed
+		status, result := post(ts.URL+PostTransactionHandlerPattern+"?wait=true&timeout=10s", body)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, tx.GetHash(), result.Hash)
+		require.Equal(t, TransactionStatusConfirmed, result.Status)
+		require.Nil(t, result.Error)
+	}
+
+	postProblem := func(url string, body []byte) (int, sebaknetwork.Problem) {
+		resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+		require.Nil(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, sebaknetwork.ProblemContentType, resp.Header.Get("Content-Type"))
+
+		var problem sebaknetwork.Problem
+		require.Nil(t, json.NewDecoder(resp.Body).Decode(&problem))
+		return resp.StatusCode, problem
+	}
+
+	{ // same transaction is rejected
+		status, problem := postProblem(ts.URL+PostTransactionHandlerPattern, body)
+		require.Equal(t, http.StatusConflict, status)
+		require.Equal(t, http.StatusConflict, problem.Status)
+		require.Equal(t, sebakerror.ErrorBlockAlreadyExists.Code, problem.Code)
+		require.Equal(t, tx.GetHash(), problem.Data["hash"])
+		require.Equal(t, TransactionStatusRejected, problem.Data["status"])
+	}
+
+	{ // invalid transaction
+		status, problem := postProblem(ts.URL+PostTransactionHandlerPattern, []byte("showme"))
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Equal(t, sebakerror.ErrorInvalidMessage.Code, problem.Code)
+	}
+
+	{ // invalid timeout
+		status, problem := postProblem(ts.URL+PostTransactionHandlerPattern+"?wait=true&timeout=showme", body)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Equal(t, "showme", problem.Data["timeout"])
+	}
+
+	{ // too large body
+		defer func(size int64) {
+			MaxTransactionBodySize = size
+		}(MaxTransactionBodySize)
+		MaxTransactionBodySize = int64(len(body) - 1)
+
+		status, problem := postProblem(ts.URL+PostTransactionHandlerPattern, body)
+		require.Equal(t, http.StatusRequestEntityTooLarge, status)
+		require.Equal(t, http.StatusRequestEntityTooLarge, problem.Status)
+	}
+}
+
+func TestPostTransactionHandlerOtherFailed(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(1)[0]
+	defer nr.Storage().Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc(PostTransactionHandlerPattern, PostTransactionHandler(nr)).Methods("POST")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	kp, _ := keypair.Random()
+	tx := makeTransaction(kp)
+	body, _ := tx.Serialize()
+	other := makeTransaction(kp)
+
+	// the node accepts the transaction, and then the other transaction fails
+	go func() {
+		r := <-nr.requests
+		r.result <- nil
+
+		observer.TransactionObserver.Trigger(
+			fmt.Sprintf("failed hash-%s", other.GetHash()),
+			VotingStateStaging{MessageHash: other.GetHash(), Reason: sebakerror.ErrorTransactionFailed},
+		)
+	}()
+
+	resp, err := http.Post(ts.URL+PostTransactionHandlerPattern+"?wait=true&timeout=1s", "application/json", bytes.NewReader(body))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	var result TransactionSubmitResult
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Equal(t, tx.GetHash(), result.Hash)
+	require.Equal(t, TransactionStatusAccepted, result.Status)
+	require.Nil(t, result.Error)
+}
+
+func TestPostTransactionHandlerStopped(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	// the stopped node does not handle the message; the network is started
+	// for `Stop`, but `handleMessage` is not
+	nr := createNodeRunners(1)[0]
+	go nr.Network().Start()
+	nr.Stop()
+
+	router := mux.NewRouter()
+	router.HandleFunc(PostTransactionHandlerPattern, PostTransactionHandler(nr)).Methods("POST")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	kp, _ := keypair.Random()
+	body, _ := makeTransaction(kp).Serialize()
+
+	resp, err := http.Post(ts.URL+PostTransactionHandlerPattern, "application/json", bytes.NewReader(body))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	var problem sebaknetwork.Problem
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&problem))
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.Equal(t, sebakerror.ErrorNodeStopped.Code, problem.Code)
+
+	// the request, which is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	nr = createNodeRunners(1)[0]
+	err = nr.HandleMessageFromClient(ctx, sebaknetwork.NewMessage(sebaknetwork.MessageFromClient, body))
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestGetTransactionStatusHandler(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(3)[0]
+	defer nr.Storage().Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc(GetTransactionStatusHandlerPattern, GetTransactionStatusHandler(nr)).Methods("GET")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	get := func(hash string) (int, TransactionStatus) {
+		resp, err := http.Get(ts.URL + "/transactions/" + hash + "/status")
+		require.Nil(t, err)
+		defer resp.Body.Close()
+
+		var status TransactionStatus
+		if resp.StatusCode == http.StatusOK {
+			require.Nil(t, json.NewDecoder(resp.Body).Decode(&status))
+		}
+		return resp.StatusCode, status
+	}
+
+	kp, _ := keypair.Random()
+
+	{ // unknown
+		status, _ := get(makeTransaction(kp).GetHash())
+		require.Equal(t, http.StatusNotFound, status)
+	}
+
+	{ // only in history
+		tx := makeTransaction(kp)
+		bth := NewTransactionHistoryFromTransaction(tx, []byte{})
+		require.Nil(t, bth.Save(nr.Storage()))
+
+		status, s := get(tx.GetHash())
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, TransactionStageHistory, s.Stage)
+		require.Equal(t, tx.H.Created, s.Created)
+	}
+
+	{ // waiting in `BallotBoxes`
+		tx := makeTransaction(kp)
+		_, err := nr.Consensus().ReceiveMessage(tx)
+		require.Nil(t, err)
+
+		status, s := get(tx.GetHash())
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, TransactionStageWaiting, s.Stage)
+		require.Equal(t, sebakcommon.BallotStateINIT.String(), s.State)
+		require.Equal(t, 1, s.Votes.Yes)
+		require.Equal(t, nr.Policy().Threshold(sebakcommon.BallotStateINIT), s.Votes.Threshold)
+	}
+
+	{ // failed
+		tx := makeTransaction(kp)
+		bte := BlockTransactionError{Hash: tx.GetHash(), State: sebakcommon.BallotStateSIGN, Reason: "showme"}
+		require.Nil(t, bte.Save(nr.Storage()))
+
+		status, s := get(tx.GetHash())
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, TransactionStageFailed, s.Stage)
+		require.Equal(t, sebakerror.ErrorTransactionFailed.Code, s.Error.Code)
+		require.Equal(t, "showme", s.Error.Message)
+
+		// saved again
+		bte.Reason = "findme"
+		require.Nil(t, bte.Save(nr.Storage()))
+		_, s = get(tx.GetHash())
+		require.Equal(t, "findme", s.Error.Message)
+	}
+
+	{ // confirmed
+		tx := makeTransaction(kp)
+		a, _ := tx.Serialize()
+		bt := NewBlockTransactionFromTransaction(tx, a)
+		require.Nil(t, bt.Save(nr.Storage()))
+
+		status, s := get(tx.GetHash())
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, TransactionStageConfirmed, s.Stage)
+		require.Equal(t, sebakcommon.BallotStateALLCONFIRM.String(), s.State)
+		require.Nil(t, s.Error)
+	}
+}
+
+func TestGetNodeStatusHandler(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nr := createNodeRunners(3)[0]
+	defer nr.Storage().Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc(GetNodeStatusHandlerPattern, GetNodeStatusHandler(nr)).Methods("GET")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// `ValidatorHealth` is only marshaled
+	type nodeStatus struct {
+		NodeStatus
+		Validators []map[string]interface{} `json:"validators"`
+	}
+
+	get := func() (status nodeStatus) {
+		resp, err := http.Get(ts.URL + GetNodeStatusHandlerPattern)
+		require.Nil(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Nil(t, json.NewDecoder(resp.Body).Decode(&status))
+		return
+	}
+
+	{ // empty
+		s := get()
+		require.Equal(t, nr.Node().Address(), s.Node.Address)
+		require.Equal(t, sebaknode.NodeModeValidator.String(), s.Node.Mode)
+		require.Equal(t, sebaknode.NodeStateNONE.String(), s.Node.State)
+		require.Equal(t, 2, len(s.Validators))
+		require.Equal(t, nr.Policy().Threshold(sebakcommon.BallotStateSIGN), s.Policy.Thresholds["SIGN"])
+		require.Equal(t, 0, s.Boxes[BallotBoxWaiting])
+		require.Equal(t, 0, len(s.Votings))
+		require.Nil(t, s.Latest)
+	}
+
+	kp, _ := keypair.Random()
+
+	{ // voting and the latest confirmed
+		tx := makeTransaction(kp)
+		_, err := nr.Consensus().ReceiveMessage(tx)
+		require.Nil(t, err)
+
+		txConfirmed := makeTransaction(kp)
+		a, _ := txConfirmed.Serialize()
+		bt := NewBlockTransactionFromTransaction(txConfirmed, a)
+		require.Nil(t, bt.Save(nr.Storage()))
+
+		s := get()
+		require.Equal(t, 1, s.Boxes[BallotBoxWaiting])
+		require.Equal(t, 1, len(s.Votings))
+		require.Equal(t, tx.GetHash(), s.Votings[0].Hash)
+		require.Equal(t, kp.Address(), s.Votings[0].Source)
+		require.Equal(t, sebakcommon.BallotStateINIT.String(), s.Votings[0].State)
+		require.Equal(t, 1, s.Votings[0].Yes)
+		require.Equal(t, nr.Policy().Threshold(sebakcommon.BallotStateINIT), s.Votings[0].Threshold)
+
+		require.Equal(t, bt.Hash, s.Latest.Hash)
+		require.Equal(t, bt.SourceCheckpoint, s.Latest.Checkpoint)
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	defaultHeartbeat := DefaultStreamingHeartbeat
+	DefaultStreamingHeartbeat = 100 * time.Millisecond
+	defer func() {
+		DefaultStreamingHeartbeat = defaultHeartbeat
+	}()
+
+	var stored []streamEvent
+	for i := 1; i <= 15; i++ {
+		stored = append(stored, streamEvent{ID: fmt.Sprintf("%02d", i), Data: []byte(fmt.Sprintf(`{"n":%d}`, i))})
+	}
+
+	o := observable.New()
+	source := streamSource{
+		Name:       "showme",
+		Observable: o,
+		Events:     "saved",
+		Event: func(v interface{}) (streamEvent, error) {
+			return v.(streamEvent), nil
+		},
+		Replay: func(lastID string, send func(streamEvent) error) error {
+			var i int
+			next := func() (streamEvent, bool) {
+				if i >= len(stored) {
+					return streamEvent{}, false
+				}
+				i++
+				return stored[i-1], true
+			}
+			return replayStreamEvents(next, lastID, send)
+		},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streaming(w, r, source)
+	}))
+	defer ts.Close()
+
+	request := func(lastID string) *http.Response {
+		req, err := http.NewRequest("GET", ts.URL, nil)
+		require.Nil(t, err)
+		req.Header.Set("Accept", "text/event-stream")
+		if len(lastID) > 0 {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := ts.Client().Do(req)
+		require.Nil(t, err)
+		return resp
+	}
+
+	{ // without `Last-Event-ID`
+		resp := request("")
+		defer resp.Body.Close()
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		reader := bufio.NewReader(resp.Body)
+
+		line, err := reader.ReadBytes('\n')
+		require.Nil(t, err)
+		require.Equal(t, "id: 01\n", string(line))
+		line, err = reader.ReadBytes('\n')
+		require.Nil(t, err)
+		require.Equal(t, "event: showme\n", string(line))
+		line, err = reader.ReadBytes('\n')
+		require.Nil(t, err)
+		require.Equal(t, "data: {\"n\":1}\n", string(line))
+
+		for i := 1; i < maxNumberOfExistingData; i++ {
+			e, err := readStreamEvent(reader)
+			require.Nil(t, err)
+			require.Equal(t, stored[i], e)
+		}
+
+		// new event
+		o.Trigger("saved", streamEvent{ID: "16", Data: []byte("{}")})
+		e, err := readStreamEvent(reader)
+		require.Nil(t, err)
+		require.Equal(t, "16", e.ID)
+
+		// heartbeat
+		line, err = reader.ReadBytes('\n')
+		require.Nil(t, err)
+		require.Equal(t, ": keep-alive\n", string(line))
+	}
+
+	{ // resume from `Last-Event-ID`
+		resp := request("12")
+		defer resp.Body.Close()
+
+		reader := bufio.NewReader(resp.Body)
+		for _, expected := range stored[12:] {
+			e, err := readStreamEvent(reader)
+			require.Nil(t, err)
+			require.Equal(t, expected, e)
+		}
+	}
+
+	{ // too many events after `Last-Event-ID`
+		defer func(n int) {
+			MaxStreamingReplay = n
+		}(MaxStreamingReplay)
+		MaxStreamingReplay = 2
+
+		resp := request("12")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var problem sebaknetwork.Problem
+		require.Nil(t, json.NewDecoder(resp.Body).Decode(&problem))
+		require.Equal(t, sebakerror.ErrorTooManyStreamingEvents.Code, problem.Code)
+
+		resp = request("13")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		reader := bufio.NewReader(resp.Body)
+		for _, expected := range stored[13:] {
+			e, err := readStreamEvent(reader)
+			require.Nil(t, err)
+			require.Equal(t, expected, e)
+		}
+	}
+}
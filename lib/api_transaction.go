@@ -1,78 +1,121 @@
 package sebak
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"time"
 
 	"boscoin.io/sebak/lib/common"
 	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
 	"boscoin.io/sebak/lib/observer"
 	"boscoin.io/sebak/lib/storage"
 	"fmt"
 	"github.com/gorilla/mux"
 )
 
+// GetTransactionsHandlerPattern lists all the transactions by the confirmed
+// order; it is paginated by `cursor`, `limit` and `order`. With
+// `text/event-stream`, the new transactions are streamed.
 const GetTransactionsHandlerPattern = "/transactions"
 
-func GetTransactionsHandler(storage *sebakstorage.LevelDBBackend) http.HandlerFunc {
+func GetTransactionsHandler(storage sebakstorage.DBBackend) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		var err error
 
 		switch r.Header.Get("Accept") {
 		case "text/event-stream":
-			var readyChan = make(chan struct{})
-			iterateId := sebakcommon.GetUniqueIDFromUUID()
-			go func() {
-				<-readyChan
-				count := maxNumberOfExistingData
-				iterFunc, closeFunc := GetBlockTransactions(storage, false)
-				for {
-					bt, hasNext := iterFunc()
-					count--
-					if !hasNext || count < 0 {
-						break
+			streaming(w, r, streamSource{
+				Name:       "transaction",
+				Observable: observer.BlockTransactionObserver,
+				Events:     "saved",
+				Event:      blockTransactionStreamEvent,
+				Replay: func(lastID string, send func(streamEvent) error) error {
+					var iterFunc func() (BlockTransaction, bool)
+					var closeFunc func()
+					if len(lastID) < 1 {
+						iterFunc, closeFunc = GetBlockTransactions(storage, false)
+					} else {
+						iterFunc, closeFunc = GetBlockTransactionsFromConfirmed(storage, streamIDConfirmed(lastID))
 					}
-					observer.BlockTransactionObserver.Trigger(fmt.Sprintf("iterate-%s", iterateId), &bt)
-				}
-				closeFunc()
-			}()
+					defer closeFunc()
 
-			callBackFunc := func(args ...interface{}) (account []byte, err error) {
-				ba := args[1].(*BlockTransaction)
-				if account, err = ba.Serialize(); err != nil {
-					return []byte{}, sebakerror.ErrorBlockAccountDoesNotExists
-				}
-				return account, nil
-			}
-			event := "saved"
-			event += " " + fmt.Sprintf("iterate-%s", iterateId)
-			streaming(observer.BlockTransactionObserver, w, event, callBackFunc, readyChan)
+					return replayStreamEvents(blockTransactionStreamEvents(iterFunc), lastID, send)
+				},
+			})
 		default:
+			var q pageQuery
+			if q, err = newPageQuery(r.URL.Query()); err != nil {
+				writePageQueryProblem(w, r, err)
+				return
+			}
 
-			var s []byte
-			var btl []BlockTransaction
-			iterFunc, closeFunc := GetBlockTransactions(storage, false)
-			for {
-				bt, hasNext := iterFunc()
-				if !hasNext {
-					break
+			iterFunc, closeFunc := BlockTransactionModel.GetIteratorAfter(storage, BlockTransactionIndexConfirmed, q.Cursor, q.Reverse)
+			defer closeFunc()
+
+			cursor := func(item sebakstorage.IterItem) string {
+				return BlockTransactionModel.Cursor(BlockTransactionIndexConfirmed, item)
+			}
+			record := func(item sebakstorage.IterItem) (interface{}, error) {
+				var bt BlockTransaction
+				if err := json.Unmarshal(item.Value, &bt); err != nil {
+					return nil, err
 				}
-				btl = append(btl, bt)
+				return bt, nil
 			}
-			closeFunc()
 
-			s, err = sebakcommon.EncodeJSONValue(btl)
-			if _, err = w.Write(s); err != nil {
-				http.Error(w, "Error reading request body", http.StatusInternalServerError)
+			var page Page
+			if page, err = loadPage(q, iterFunc, cursor, record); err != nil {
+				sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+				return
+			}
+
+			var s []byte
+			if s, err = page.Serialize(); err != nil {
+				sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
 				return
 			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(s)
+		}
+	}
+}
+
+func blockTransactionStreamEvent(v interface{}) (e streamEvent, err error) {
+	bt, ok := v.(*BlockTransaction)
+	if !ok {
+		err = sebakerror.ErrorBlockTransactionDoesNotExists
+		return
+	}
+
+	e.ID = makeStreamID(bt.Confirmed, bt.Hash)
+	e.Data, err = bt.Serialize()
+
+	return
+}
+
+func blockTransactionStreamEvents(iterFunc func() (BlockTransaction, bool)) func() (streamEvent, bool) {
+	return func() (streamEvent, bool) {
+		for {
+			bt, hasNext := iterFunc()
+			if !hasNext {
+				return streamEvent{}, false
+			}
+			if e, err := blockTransactionStreamEvent(&bt); err == nil {
+				return e, true
+			}
 		}
 	}
 }
 
 const GetTransactionByHashHandlerPattern = "/transactions/{txid}"
 
-func GetTransactionByHashHandler(storage *sebakstorage.LevelDBBackend) http.HandlerFunc {
+func GetTransactionByHashHandler(storage sebakstorage.DBBackend) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
@@ -82,61 +125,345 @@ func GetTransactionByHashHandler(storage *sebakstorage.LevelDBBackend) http.Hand
 
 		switch r.Header.Get("Accept") {
 		case "text/event-stream":
+			streaming(w, r, streamSource{
+				Name:       "transaction",
+				Observable: observer.BlockTransactionObserver,
+				Events:     fmt.Sprintf("hash-%s", key),
+				Event:      blockTransactionStreamEvent,
+				Replay: func(lastID string, send func(streamEvent) error) (err error) {
+					var found bool
+					if found, err = ExistBlockTransaction(storage, key); err != nil || !found {
+						return
+					}
 
-			var readyChan = make(chan struct{})
-			iterateId := sebakcommon.GetUniqueIDFromUUID()
-			go func() {
-				<-readyChan
-				var bt BlockTransaction
-				if bt, err = GetBlockTransaction(storage, key); err != nil {
-					http.Error(w, "Error reading request body", http.StatusInternalServerError)
-					return
-				}
-				observer.BlockTransactionObserver.Trigger(fmt.Sprintf("iterate-%s", iterateId), &bt)
-			}()
-
-			callBackFunc := func(args ...interface{}) (account []byte, err error) {
-				ba := args[1].(*BlockTransaction)
-				if account, err = ba.Serialize(); err != nil {
-					return []byte{}, sebakerror.ErrorBlockAccountDoesNotExists
-				}
-				return account, nil
-			}
+					var bt BlockTransaction
+					if bt, err = GetBlockTransaction(storage, key); err != nil {
+						return
+					}
 
-			event := fmt.Sprintf("iterate-%s", iterateId)
-			event += " " + fmt.Sprintf("hash-%s", key)
-			streaming(observer.BlockTransactionObserver, w, event, callBackFunc, readyChan)
+					var e streamEvent
+					if e, err = blockTransactionStreamEvent(&bt); err != nil || e.ID <= lastID {
+						return
+					}
+					return send(e)
+				},
+			})
 		default:
 
 			var s []byte
 			if found, err := ExistBlockTransaction(storage, key); err != nil {
-				http.Error(w, "Error reading request body", http.StatusInternalServerError)
+				sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
 				return
 			} else if found {
 				var bt BlockTransaction
 				if bt, err = GetBlockTransaction(storage, key); err != nil {
-					http.Error(w, "Error reading request body", http.StatusInternalServerError)
+					sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
 					return
 				}
 				if s, err = bt.Serialize(); err != nil {
-					http.Error(w, "Error reading request body", http.StatusInternalServerError)
+					sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
 					return
 				}
 			} else {
 				var bth BlockTransactionHistory
-				if bth, err = GetBlockTransactionHistory(storage, key); err != nil {
-					http.Error(w, "Error reading request body", http.StatusInternalServerError)
+				if bth, err = GetBlockTransactionHistory(storage, key); err == sebakerror.ErrorStorageRecordDoesNotExist {
+					sebaknetwork.NewProblem(http.StatusNotFound, sebakerror.ErrorBlockTransactionDoesNotExists).With("hash", key).Write(w)
+					return
+				} else if err != nil {
+					sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
 					return
 				}
 				if s, err = bth.Serialize(); err != nil {
-					http.Error(w, "Error reading request body", http.StatusInternalServerError)
+					sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
 					return
 				}
 			}
 			if _, err = w.Write(s); err != nil {
-				http.Error(w, "Error reading request body", http.StatusInternalServerError)
+				sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
 				return
 			}
 		}
 	}
 }
+
+const PostTransactionHandlerPattern = "/transactions"
+
+// The status of submitted transaction.
+const (
+	TransactionStatusRejected  string = "rejected"  // checkers did not accept it
+	TransactionStatusAccepted  string = "accepted"  // it goes into consensus
+	TransactionStatusConfirmed string = "confirmed" // it is stored in block
+	TransactionStatusFailed    string = "failed"    // consensus is closed without storing
+)
+
+var (
+	// DefaultTransactionWaitTimeout is used, when `wait=true` is requested
+	// without `timeout`.
+	DefaultTransactionWaitTimeout = 30 * time.Second
+
+	// MaxTransactionWaitTimeout limits the `timeout` of request.
+	MaxTransactionWaitTimeout = 5 * time.Minute
+
+	// MaxTransactionBodySize limits the body of `PostTransactionHandler`; the
+	// larger one gets `413 Request Entity Too Large`.
+	MaxTransactionBodySize int64 = 1024 * 1024
+)
+
+// TransactionSubmitResult is the response of `PostTransactionHandler`.
+type TransactionSubmitResult struct {
+	Hash   string            `json:"hash"`
+	Status string            `json:"status"`
+	Error  *sebakerror.Error `json:"error,omitempty"`
+}
+
+func (r TransactionSubmitResult) Serialize() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// newTransactionSubmitError returns the `sebakerror.Error` of the error from
+// checkers; the error, which is not `sebakerror.Error` is wrapped by
+// `ErrorTransactionRejected`.
+func newTransactionSubmitError(err error) *sebakerror.Error {
+	switch e := err.(type) {
+	case *sebakerror.Error:
+		return e
+	case sebakcommon.CheckerErrorStop:
+		return sebakerror.NewError(sebakerror.ErrorTransactionRejected.Code, e.Message)
+	default:
+		return sebakerror.NewError(sebakerror.ErrorTransactionRejected.Code, err.Error())
+	}
+}
+
+func writeTransactionSubmitResult(w http.ResponseWriter, status int, result TransactionSubmitResult) {
+	s, err := result.Serialize()
+	if err != nil {
+		sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(s)
+}
+
+// PostTransactionHandler receives the transaction from client and runs the
+// checkers of `NodeRunner` for it. With `wait=true`, the response is
+// returned after the transaction is confirmed or failed, or `timeout`, like
+// `10s` is expired; after timeout the status is still `accepted`. The
+// rejected transaction is responded by `sebaknetwork.Problem`, which has the
+// `hash` and `status` in it's `Data`.
+func PostTransactionHandler(nr *NodeRunner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxTransactionBodySize+1))
+		if err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusBadRequest, err)
+			return
+		}
+		if int64(len(body)) > MaxTransactionBodySize {
+			sebaknetwork.WriteProblem(w, http.StatusRequestEntityTooLarge, errors.New("transaction body too large"))
+			return
+		}
+
+		var tx Transaction
+		if tx, err = NewTransactionFromJSON(body); err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusBadRequest, sebakerror.ErrorInvalidMessage)
+			return
+		}
+		result := TransactionSubmitResult{Hash: tx.GetHash()}
+
+		var wait bool
+		timeout := DefaultTransactionWaitTimeout
+		if wait = r.URL.Query().Get("wait") == "true"; wait {
+			if t := r.URL.Query().Get("timeout"); len(t) > 0 {
+				if timeout, err = time.ParseDuration(t); err != nil || timeout <= 0 {
+					sebaknetwork.NewProblem(http.StatusBadRequest, errors.New("invalid timeout")).With("timeout", t).Write(w)
+					return
+				}
+				if timeout > MaxTransactionWaitTimeout {
+					timeout = MaxTransactionWaitTimeout
+				}
+			}
+		}
+
+		// the observers are set before submitting, so the result is not missed
+		done := make(chan TransactionSubmitResult, 1)
+		finish := func(result TransactionSubmitResult) {
+			select {
+			case done <- result:
+			default:
+			}
+		}
+		if wait {
+			confirmedEvent := fmt.Sprintf("hash-%s", result.Hash)
+			onConfirmed := func(args ...interface{}) {
+				finish(TransactionSubmitResult{Hash: result.Hash, Status: TransactionStatusConfirmed})
+			}
+			// the 'failed' of the other transactions is also triggered, so
+			// only the hash is subscribed
+			failedEvent := fmt.Sprintf("hash-%s", result.Hash)
+			onFailed := func(args ...interface{}) {
+				staging, ok := args[len(args)-1].(VotingStateStaging)
+				if !ok || staging.MessageHash != result.Hash {
+					return
+				}
+				e := sebakerror.NewError(
+					sebakerror.ErrorTransactionFailed.Code,
+					NewBlockTransactionErrorFromStaging(staging).Reason,
+				)
+				finish(TransactionSubmitResult{Hash: result.Hash, Status: TransactionStatusFailed, Error: e})
+			}
+
+			observer.BlockTransactionObserver.On(confirmedEvent, onConfirmed)
+			defer observer.BlockTransactionObserver.Off(confirmedEvent, onConfirmed)
+			observer.TransactionObserver.On(failedEvent, onFailed)
+			defer observer.TransactionObserver.Off(failedEvent, onFailed)
+		}
+
+		if err = nr.HandleMessageFromClient(r.Context(), sebaknetwork.NewMessage(sebaknetwork.MessageFromClient, body)); err != nil {
+			e := newTransactionSubmitError(err)
+			sebaknetwork.NewProblem(sebaknetwork.ProblemStatus(e, http.StatusBadRequest), e).
+				With("hash", result.Hash).
+				With("status", TransactionStatusRejected).
+				Write(w)
+			return
+		}
+
+		result.Status = TransactionStatusAccepted
+		if !wait {
+			writeTransactionSubmitResult(w, http.StatusAccepted, result)
+			return
+		}
+
+		select {
+		case result = <-done:
+			writeTransactionSubmitResult(w, http.StatusOK, result)
+		case <-time.After(timeout):
+			writeTransactionSubmitResult(w, http.StatusAccepted, result)
+		}
+	}
+}
+
+const GetTransactionStatusHandlerPattern = "/transactions/{txid}/status"
+
+// The stages of transaction in `TransactionStatus`.
+const (
+	TransactionStageHistory   string = "history"   // received, but not in consensus
+	TransactionStageWaiting   string = "waiting"   // waiting in `BallotBoxes`
+	TransactionStageVoting    string = "voting"    // under voting
+	TransactionStageConfirmed string = "confirmed" // stored as `BlockTransaction`
+	TransactionStageFailed    string = "failed"    // consensus was closed without storing
+)
+
+type TransactionStatusVotes struct {
+	Yes       int `json:"yes"`
+	No        int `json:"no"`
+	Threshold int `json:"threshold"`
+}
+
+// TransactionStatus is the response of `GetTransactionStatusHandler`.
+type TransactionStatus struct {
+	Hash      string                  `json:"hash"`
+	Stage     string                  `json:"stage"`
+	State     string                  `json:"state,omitempty"`
+	Votes     *TransactionStatusVotes `json:"votes,omitempty"`
+	Created   string                  `json:"created,omitempty"`
+	Confirmed string                  `json:"confirmed,omitempty"`
+	Error     *sebakerror.Error       `json:"error,omitempty"`
+}
+
+func (s TransactionStatus) Serialize() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// GetTransactionStatus finds the current stage of transaction from
+// `BlockTransaction`, `BallotBoxes`, `BlockTransactionError` and
+// `BlockTransactionHistory` in order; `found` is false when the node does not
+// know the transaction.
+func GetTransactionStatus(nr *NodeRunner, hash string) (status TransactionStatus, found bool, err error) {
+	st := nr.Storage()
+	status.Hash = hash
+
+	if found, err = ExistBlockTransaction(st, hash); err != nil || found {
+		if found {
+			var bt BlockTransaction
+			if bt, err = GetBlockTransaction(st, hash); err != nil {
+				return
+			}
+			status.Stage = TransactionStageConfirmed
+			status.State = sebakcommon.BallotStateALLCONFIRM.String()
+			status.Created = bt.Created
+			status.Confirmed = bt.Confirmed
+		}
+		return
+	}
+
+	if isaac, ok := nr.Consensus().(*ISAAC); ok {
+		var snapshot VotingSnapshot
+		if snapshot, found = isaac.Boxes.VotingSnapshot(hash); found {
+			status.Stage = TransactionStageVoting
+			if snapshot.Box != BallotBoxVoting {
+				status.Stage = TransactionStageWaiting
+			}
+			status.State = snapshot.State.String()
+			status.Votes = &TransactionStatusVotes{
+				Yes:       snapshot.Yes,
+				No:        snapshot.No,
+				Threshold: nr.Policy().Threshold(snapshot.State),
+			}
+		}
+	}
+
+	if found, err = ExistBlockTransactionError(st, hash); err != nil {
+		return
+	} else if found && len(status.Stage) < 1 {
+		var bte BlockTransactionError
+		if bte, err = GetBlockTransactionError(st, hash); err != nil {
+			return
+		}
+		status.Stage = TransactionStageFailed
+		status.State = bte.State.String()
+		status.Error = sebakerror.NewError(sebakerror.ErrorTransactionFailed.Code, bte.Reason)
+	}
+
+	var bth BlockTransactionHistory
+	if found, err = BlockTransactionHistoryModel.Has(st, hash); err != nil {
+		return
+	} else if found {
+		if bth, err = GetBlockTransactionHistory(st, hash); err != nil {
+			return
+		}
+		status.Created = bth.Created
+		if len(status.Stage) < 1 {
+			status.Stage = TransactionStageHistory
+		}
+	}
+
+	found = len(status.Stage) > 0
+
+	return
+}
+
+// GetTransactionStatusHandler returns the current stage of transaction.
+func GetTransactionStatusHandler(nr *NodeRunner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hash := mux.Vars(r)["txid"]
+
+		status, found, err := GetTransactionStatus(nr, hash)
+		if err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		} else if !found {
+			sebaknetwork.NewProblem(http.StatusNotFound, sebakerror.ErrorBlockTransactionDoesNotExists).With("hash", hash).Write(w)
+			return
+		}
+
+		s, err := status.Serialize()
+		if err != nil {
+			sebaknetwork.WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s)
+	}
+}
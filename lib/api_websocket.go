@@ -0,0 +1,438 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/observer"
+)
+
+const WebSocketHandlerPattern = "/subscribe"
+
+// The topics of subscription and their filters. The values of one filter are
+// matched by `OR` and the filters are matched by `AND`; the subscription
+// without filter gets all the events of topic.
+//  * `account`: `address`
+//  * `transaction`: `source`, `account`(source or target of operations)
+//  * `operation`: `type`, `source`, `target`
+//  * `confirmation`: `source`
+const (
+	WebSocketTopicAccount      string = "account"
+	WebSocketTopicTransaction  string = "transaction"
+	WebSocketTopicOperation    string = "operation"
+	WebSocketTopicConfirmation string = "confirmation"
+)
+
+var webSocketTopicFilters = map[string][]string{
+	WebSocketTopicAccount:      []string{"address"},
+	WebSocketTopicTransaction:  []string{"source", "account"},
+	WebSocketTopicOperation:    []string{"type", "source", "target"},
+	WebSocketTopicConfirmation: []string{"source"},
+}
+
+// The actions of `WebSocketRequest`.
+const (
+	WebSocketActionSubscribe   string = "subscribe"
+	WebSocketActionUnsubscribe string = "unsubscribe"
+)
+
+// The types of `WebSocketResponse`.
+const (
+	WebSocketResponseSubscribed   string = "subscribed"
+	WebSocketResponseUnsubscribed string = "unsubscribed"
+	WebSocketResponseEvent        string = "event"
+	WebSocketResponseError        string = "error"
+)
+
+var (
+	// WebSocketBufferSize is the number of messages, which wait for the slow
+	// client. When it is full, the connection is closed; the client should
+	// connect and subscribe again.
+	WebSocketBufferSize = 1024
+
+	// WebSocketWriteTimeout is the maximum time to write one message.
+	WebSocketWriteTimeout = 10 * time.Second
+
+	// MaxWebSocketMessageSize limits the size of the request from client.
+	MaxWebSocketMessageSize = 4 * 1024 * 1024
+
+	MaxWebSocketSubscriptions = 100    // per connection
+	MaxWebSocketFilterValues  = 100000 // per subscription
+)
+
+// WebSocketRequest is sent by client. `ID` is set in the response of it.
+type WebSocketRequest struct {
+	ID           string              `json:"id"`
+	Action       string              `json:"action"`
+	Topic        string              `json:"topic,omitempty"`
+	Filter       map[string][]string `json:"filter,omitempty"`
+	Subscription string              `json:"subscription,omitempty"` // for `unsubscribe`
+}
+
+type WebSocketResponse struct {
+	Type         string            `json:"type"`
+	ID           string            `json:"id,omitempty"`
+	Subscription string            `json:"subscription,omitempty"`
+	Topic        string            `json:"topic,omitempty"`
+	Data         json.RawMessage   `json:"data,omitempty"`
+	Error        *sebakerror.Error `json:"error,omitempty"`
+}
+
+func (r WebSocketResponse) Serialize() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+type webSocketSubscription struct {
+	ID     string
+	Topic  string
+	Filter map[string]map[string]bool
+}
+
+func newWebSocketSubscription(topic string, filter map[string][]string) (s *webSocketSubscription, err error) {
+	names, found := webSocketTopicFilters[topic]
+	if !found {
+		err = sebakerror.ErrorInvalidSubscription
+		return
+	}
+
+	s = &webSocketSubscription{
+		ID:     sebakcommon.GetUniqueIDFromUUID(),
+		Topic:  topic,
+		Filter: map[string]map[string]bool{},
+	}
+
+	for name, values := range filter {
+		if _, found := sebakcommon.InStringArray(names, name); !found {
+			err = sebakerror.ErrorInvalidSubscription
+			return
+		}
+		if len(values) < 1 {
+			continue
+		}
+		if len(values) > MaxWebSocketFilterValues {
+			err = sebakerror.ErrorTooManySubscriptions
+			return
+		}
+
+		s.Filter[name] = map[string]bool{}
+		for _, v := range values {
+			s.Filter[name][v] = true
+		}
+	}
+
+	return
+}
+
+func (s *webSocketSubscription) match(topic string, values map[string][]string) bool {
+	if s.Topic != topic {
+		return false
+	}
+
+	for name, filter := range s.Filter {
+		var matched bool
+		for _, v := range values[name] {
+			if filter[v] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+type webSocketClient struct {
+	sync.RWMutex
+
+	conn          *websocket.Conn
+	subscriptions map[string]*webSocketSubscription
+	send          chan []byte
+	closed        chan struct{}
+	closeOnce     sync.Once
+}
+
+func newWebSocketClient(conn *websocket.Conn) *webSocketClient {
+	return &webSocketClient{
+		conn:          conn,
+		subscriptions: map[string]*webSocketSubscription{},
+		send:          make(chan []byte, WebSocketBufferSize),
+		closed:        make(chan struct{}),
+	}
+}
+
+func (c *webSocketClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+// enqueue does not wait for the slow client, which is closed instead.
+func (c *webSocketClient) enqueue(r WebSocketResponse) {
+	b, err := r.Serialize()
+	if err != nil {
+		log.Error("failed to serialize websocket response", "error", err)
+		return
+	}
+
+	select {
+	case <-c.closed:
+	case c.send <- b:
+	default:
+		log.Debug("websocket client is too slow; connection is closed", "remote", c.conn.Request().RemoteAddr)
+		c.close()
+	}
+}
+
+func (c *webSocketClient) writeLoop() {
+	for {
+		select {
+		case <-c.closed:
+			return
+		case b := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(WebSocketWriteTimeout))
+			if err := websocket.Message.Send(c.conn, string(b)); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+func (c *webSocketClient) handle(req WebSocketRequest) WebSocketResponse {
+	c.Lock()
+	defer c.Unlock()
+
+	switch req.Action {
+	case WebSocketActionSubscribe:
+		if len(c.subscriptions) >= MaxWebSocketSubscriptions {
+			return WebSocketResponse{Type: WebSocketResponseError, ID: req.ID, Error: sebakerror.ErrorTooManySubscriptions}
+		}
+
+		s, err := newWebSocketSubscription(req.Topic, req.Filter)
+		if err != nil {
+			return WebSocketResponse{Type: WebSocketResponseError, ID: req.ID, Error: err.(*sebakerror.Error)}
+		}
+		c.subscriptions[s.ID] = s
+
+		return WebSocketResponse{Type: WebSocketResponseSubscribed, ID: req.ID, Subscription: s.ID, Topic: s.Topic}
+	case WebSocketActionUnsubscribe:
+		s, found := c.subscriptions[req.Subscription]
+		if !found {
+			return WebSocketResponse{Type: WebSocketResponseError, ID: req.ID, Error: sebakerror.ErrorSubscriptionNotFound}
+		}
+		delete(c.subscriptions, s.ID)
+
+		return WebSocketResponse{Type: WebSocketResponseUnsubscribed, ID: req.ID, Subscription: s.ID, Topic: s.Topic}
+	default:
+		return WebSocketResponse{Type: WebSocketResponseError, ID: req.ID, Error: sebakerror.ErrorInvalidMessage}
+	}
+}
+
+func (c *webSocketClient) dispatch(topic string, values map[string][]string, data func() []byte) {
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, s := range c.subscriptions {
+		if !s.match(topic, values) {
+			continue
+		}
+
+		b := data()
+		if b == nil {
+			return
+		}
+		c.enqueue(WebSocketResponse{Type: WebSocketResponseEvent, Subscription: s.ID, Topic: topic, Data: b})
+	}
+}
+
+// WebSocketHub receives the events of observers once and sends them to the
+// subscriptions of all the clients. `NodeRunner` makes the hub at adding the
+// api handlers and closes it at stopping.
+type WebSocketHub struct {
+	sync.RWMutex
+
+	clients map[*webSocketClient]bool
+	closed  bool
+}
+
+func NewWebSocketHub() *WebSocketHub {
+	h := &WebSocketHub{clients: map[*webSocketClient]bool{}}
+
+	observer.BlockAccountObserver.On("saved", func(v interface{}) {
+		ba, ok := v.(*block.BlockAccount)
+		if !ok {
+			return
+		}
+		h.dispatch(WebSocketTopicAccount, map[string][]string{"address": []string{ba.Address}}, ba.Serialize)
+	})
+	observer.BlockTransactionObserver.On("saved", func(v interface{}) {
+		bt, ok := v.(*BlockTransaction)
+		if !ok {
+			return
+		}
+
+		accounts := []string{bt.Source}
+		for _, op := range bt.Transaction().B.Operations {
+			accounts = append(accounts, op.B.TargetAddress())
+		}
+		h.dispatch(WebSocketTopicTransaction, map[string][]string{"source": []string{bt.Source}, "account": accounts}, bt.Serialize)
+
+		confirmation := func() ([]byte, error) {
+			return json.Marshal(map[string]string{"hash": bt.Hash, "source": bt.Source, "confirmed": bt.Confirmed})
+		}
+		h.dispatch(WebSocketTopicConfirmation, map[string][]string{"source": []string{bt.Source}}, confirmation)
+	})
+	observer.BlockOperationObserver.On("saved", func(v interface{}) {
+		bo, ok := v.(*BlockOperation)
+		if !ok {
+			return
+		}
+
+		values := map[string][]string{
+			"type":   []string{string(bo.Type)},
+			"source": []string{bo.Source},
+			"target": []string{bo.Target},
+		}
+		h.dispatch(WebSocketTopicOperation, values, bo.Serialize)
+	})
+
+	return h
+}
+
+// Close closes the connected clients. The observers can not remove the
+// callbacks of one hub, so the closed hub keeps them, but has no client to
+// send.
+func (h *WebSocketHub) Close() {
+	h.Lock()
+	defer h.Unlock()
+
+	h.closed = true
+	for c := range h.clients {
+		c.close()
+		delete(h.clients, c)
+	}
+}
+
+func (h *WebSocketHub) add(c *webSocketClient) bool {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.closed {
+		return false
+	}
+	h.clients[c] = true
+
+	return true
+}
+
+func (h *WebSocketHub) remove(c *webSocketClient) {
+	h.Lock()
+	defer h.Unlock()
+
+	delete(h.clients, c)
+}
+
+// dispatch sends the record to the matched subscriptions; the record is
+// serialized only when any subscription is matched.
+func (h *WebSocketHub) dispatch(topic string, values map[string][]string, serialize func() ([]byte, error)) {
+	h.RLock()
+	defer h.RUnlock()
+
+	var data []byte
+	var failed bool
+	lazy := func() []byte {
+		if data == nil && !failed {
+			var err error
+			if data, err = serialize(); err != nil {
+				log.Error("failed to serialize websocket event", "topic", topic, "error", err)
+				failed = true
+			}
+		}
+		return data
+	}
+
+	for c := range h.clients {
+		c.dispatch(topic, values, lazy)
+	}
+}
+
+func (h *WebSocketHub) serve(conn *websocket.Conn) {
+	// the deadlines of `http.Server` are still set in the hijacked connection
+	conn.SetDeadline(time.Time{})
+	conn.MaxPayloadBytes = MaxWebSocketMessageSize
+
+	c := newWebSocketClient(conn)
+	defer c.close()
+	if !h.add(c) {
+		return
+	}
+	defer h.remove(c)
+
+	go c.writeLoop()
+
+	for {
+		var req WebSocketRequest
+		if err := websocket.JSON.Receive(conn, &req); err != nil {
+			switch err.(type) {
+			case *json.SyntaxError, *json.UnmarshalTypeError:
+				c.enqueue(WebSocketResponse{Type: WebSocketResponseError, Error: sebakerror.ErrorInvalidMessage})
+				continue
+			}
+			if err != io.EOF {
+				log.Debug("failed to receive websocket request", "error", err)
+			}
+			return
+		}
+
+		c.enqueue(c.handle(req))
+	}
+}
+
+// WebSocketHandler accepts the websocket connection, which subscribes the
+// topics of `hub` by `WebSocketRequest`. The browser can connect only from the
+// same origin or `allowedOrigins`; `*` allows any origin.
+func WebSocketHandler(hub *WebSocketHub, allowedOrigins []string) http.HandlerFunc {
+	server := websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) (err error) {
+			if config.Origin, err = websocket.Origin(config, r); err != nil {
+				return
+			}
+			return checkWebSocketOrigin(config.Origin, r, allowedOrigins)
+		},
+		Handler: hub.serve,
+	}
+
+	return server.ServeHTTP
+}
+
+// checkWebSocketOrigin allows the request without `Origin`, which is not from
+// the browser.
+func checkWebSocketOrigin(origin *url.URL, r *http.Request, allowedOrigins []string) error {
+	if origin == nil || origin.Host == r.Host {
+		return nil
+	}
+
+	o := origin.Scheme + "://" + origin.Host
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.TrimRight(allowed, "/") == o {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("origin is not allowed: '%s'", o)
+}
@@ -0,0 +1,170 @@
+package sebak
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestWebSocketHandler(t *testing.T) {
+	storage, err := sebakstorage.NewTestMemoryLevelDBBackend()
+	require.Nil(t, err)
+	defer storage.Close()
+
+	hub := NewWebSocketHub()
+	defer hub.Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc(WebSocketHandlerPattern, WebSocketHandler(hub, nil)).Methods("GET")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	ws, err := websocket.Dial(strings.Replace(ts.URL, "http", "ws", 1)+WebSocketHandlerPattern, "", ts.URL)
+	require.Nil(t, err)
+	defer ws.Close()
+
+	send := func(req WebSocketRequest) {
+		require.Nil(t, websocket.JSON.Send(ws, req))
+	}
+	receive := func() (r WebSocketResponse) {
+		ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		require.Nil(t, websocket.JSON.Receive(ws, &r))
+		return
+	}
+
+	kp, _ := keypair.Random()
+	kpOther, _ := keypair.Random()
+	ba := block.TestMakeBlockAccount()
+
+	subscriptions := map[string]string{}
+	for _, req := range []WebSocketRequest{
+		{ID: "1", Action: WebSocketActionSubscribe, Topic: WebSocketTopicTransaction, Filter: map[string][]string{"source": []string{kp.Address()}}},
+		{ID: "2", Action: WebSocketActionSubscribe, Topic: WebSocketTopicAccount, Filter: map[string][]string{"address": []string{ba.Address}}},
+		{ID: "3", Action: WebSocketActionSubscribe, Topic: WebSocketTopicConfirmation},
+	} {
+		send(req)
+		r := receive()
+		require.Equal(t, WebSocketResponseSubscribed, r.Type)
+		require.Equal(t, req.ID, r.ID)
+		require.Equal(t, req.Topic, r.Topic)
+		subscriptions[req.Topic] = r.Subscription
+	}
+
+	{ // invalid requests
+		send(WebSocketRequest{ID: "4", Action: WebSocketActionSubscribe, Topic: "showme"})
+		r := receive()
+		require.Equal(t, WebSocketResponseError, r.Type)
+		require.Equal(t, sebakerror.ErrorInvalidSubscription.Code, r.Error.Code)
+
+		send(WebSocketRequest{ID: "5", Action: WebSocketActionSubscribe, Topic: WebSocketTopicAccount, Filter: map[string][]string{"source": []string{kp.Address()}}})
+		r = receive()
+		require.Equal(t, sebakerror.ErrorInvalidSubscription.Code, r.Error.Code)
+
+		send(WebSocketRequest{ID: "6", Action: WebSocketActionUnsubscribe, Subscription: "findme"})
+		r = receive()
+		require.Equal(t, sebakerror.ErrorSubscriptionNotFound.Code, r.Error.Code)
+
+		require.Nil(t, websocket.Message.Send(ws, "showme"))
+		r = receive()
+		require.Equal(t, sebakerror.ErrorInvalidMessage.Code, r.Error.Code)
+	}
+
+	{ // the transaction of other source is filtered out
+		tx := TestMakeTransactionWithKeypair(networkID, 1, kpOther)
+		a, _ := tx.Serialize()
+		bt := NewBlockTransactionFromTransaction(tx, a)
+		require.Nil(t, bt.Save(storage))
+
+		r := receive()
+		require.Equal(t, WebSocketResponseEvent, r.Type)
+		require.Equal(t, WebSocketTopicConfirmation, r.Topic)
+		require.Equal(t, subscriptions[WebSocketTopicConfirmation], r.Subscription)
+	}
+
+	{ // transaction of `kp`
+		tx := TestMakeTransactionWithKeypair(networkID, 1, kp)
+		a, _ := tx.Serialize()
+		bt := NewBlockTransactionFromTransaction(tx, a)
+		require.Nil(t, bt.Save(storage))
+
+		r := receive()
+		require.Equal(t, WebSocketResponseEvent, r.Type)
+		require.Equal(t, WebSocketTopicTransaction, r.Topic)
+		require.Equal(t, subscriptions[WebSocketTopicTransaction], r.Subscription)
+
+		var received BlockTransaction
+		require.Nil(t, json.Unmarshal(r.Data, &received))
+		require.Equal(t, bt.Hash, received.Hash)
+
+		r = receive()
+		require.Equal(t, WebSocketTopicConfirmation, r.Topic)
+
+		var confirmation map[string]string
+		require.Nil(t, json.Unmarshal(r.Data, &confirmation))
+		require.Equal(t, bt.Hash, confirmation["hash"])
+		require.Equal(t, bt.Confirmed, confirmation["confirmed"])
+	}
+
+	{ // unsubscribe and account
+		send(WebSocketRequest{ID: "7", Action: WebSocketActionUnsubscribe, Subscription: subscriptions[WebSocketTopicConfirmation]})
+		r := receive()
+		require.Equal(t, WebSocketResponseUnsubscribed, r.Type)
+		require.Equal(t, "7", r.ID)
+
+		require.Nil(t, ba.Save(storage))
+
+		r = receive()
+		require.Equal(t, WebSocketResponseEvent, r.Type)
+		require.Equal(t, WebSocketTopicAccount, r.Topic)
+
+		var received block.BlockAccount
+		require.Nil(t, json.Unmarshal(r.Data, &received))
+		require.Equal(t, ba.Address, received.Address)
+	}
+}
+
+func TestWebSocketHandlerOrigin(t *testing.T) {
+	hub := NewWebSocketHub()
+	defer hub.Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc(WebSocketHandlerPattern, WebSocketHandler(hub, []string{"https://allowed.example.com"})).Methods("GET")
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	location := strings.Replace(ts.URL, "http", "ws", 1) + WebSocketHandlerPattern
+
+	// same origin and the allowed origin
+	for _, origin := range []string{ts.URL, "https://allowed.example.com"} {
+		ws, err := websocket.Dial(location, "", origin)
+		require.Nil(t, err, origin)
+		ws.Close()
+	}
+
+	// the other origin
+	_, err := websocket.Dial(location, "", "https://evil.example.com")
+	require.NotNil(t, err)
+
+	// the closed hub does not accept the connection
+	hub.Close()
+	ws, err := websocket.Dial(location, "", ts.URL)
+	require.Nil(t, err)
+	defer ws.Close()
+
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var r WebSocketResponse
+	require.NotNil(t, websocket.JSON.Receive(ws, &r))
+}
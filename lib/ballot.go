@@ -2,6 +2,8 @@ package sebak
 
 import (
 	"encoding/json"
+	"sort"
+	"time"
 
 	"boscoin.io/sebak/lib/common"
 	"boscoin.io/sebak/lib/error"
@@ -198,7 +200,7 @@ func (b Ballot) VerifySignature(networkID []byte) (err error) {
 	return
 }
 
-func (b Ballot) Validate(st *sebakstorage.LevelDBBackend) (err error) {
+func (b Ballot) Validate(st sebakstorage.DBBackend) (err error) {
 	return
 }
 
@@ -430,6 +432,87 @@ func (b *BallotBoxes) IsSameSourceUnderVoting(m sebakcommon.Message) bool {
 	return found && existingHash != m.GetHash()
 }
 
+// The names of `BallotBox` in `BallotBoxes`.
+const (
+	BallotBoxWaiting  string = "waiting"
+	BallotBoxVoting   string = "voting"
+	BallotBoxReserved string = "reserved"
+)
+
+// VotingSnapshot is the copied state of `VotingResult` in `BallotBoxes`.
+type VotingSnapshot struct {
+	ID          string
+	MessageHash string
+	Source      string
+	Box         string
+	State       sebakcommon.BallotState
+	Yes         int // `VotingYES` in `State`
+	No          int // `VotingNO` in `State`
+}
+
+// VotingSnapshot returns the snapshot of the voting of message; `found` is
+// false when the message is not in the boxes.
+func (b *BallotBoxes) VotingSnapshot(hash string) (snapshot VotingSnapshot, found bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	var vr *VotingResult
+	if vr, found = b.Results[hash]; !found {
+		return
+	}
+
+	snapshot = b.votingSnapshot(vr)
+	return
+}
+
+// VotingSnapshots returns the snapshots of all the votings; the older voting
+// comes first by the time of `VotingResult.ID`.
+func (b *BallotBoxes) VotingSnapshots() (snapshots []VotingSnapshot) {
+	b.Lock()
+	defer b.Unlock()
+
+	started := map[string]time.Time{}
+	for _, vr := range b.Results {
+		snapshot := b.votingSnapshot(vr)
+		started[snapshot.ID], _ = sebakcommon.GetTimeFromUUID(snapshot.ID)
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return started[snapshots[i].ID].Before(started[snapshots[j].ID])
+	})
+
+	return
+}
+
+func (b *BallotBoxes) votingSnapshot(vr *VotingResult) (snapshot VotingSnapshot) {
+	switch {
+	case b.VotingBox.HasMessageByHash(vr.MessageHash):
+		snapshot.Box = BallotBoxVoting
+	case b.ReservedBox.HasMessageByHash(vr.MessageHash):
+		snapshot.Box = BallotBoxReserved
+	default:
+		snapshot.Box = BallotBoxWaiting
+	}
+
+	vr.Lock()
+	defer vr.Unlock()
+
+	snapshot.ID = vr.ID
+	snapshot.MessageHash = vr.MessageHash
+	snapshot.Source = vr.Source
+	snapshot.State = vr.State
+	for _, vrb := range vr.VotedBallotsByState(vr.State) {
+		switch vrb.VotingHole {
+		case VotingYES:
+			snapshot.Yes++
+		case VotingNO:
+			snapshot.No++
+		}
+	}
+
+	return
+}
+
 type BallotBox struct {
 	sebakcommon.SafeLock
 
@@ -25,6 +25,9 @@ const BlockAccountPrefixCreated string = "ba-created-"
 const BlockAccountCheckpointPrefix string = "bac-ac-"
 const BlockAccountCheckpointByAddressPrefix string = "bac-aa-"
 
+// BlockAccountCacheName is the name of storage cache for `BlockAccount`.
+const BlockAccountCacheName string = "account"
+
 type BlockAccount struct {
 	Address    string
 	Balance    string
@@ -45,7 +48,7 @@ func (b *BlockAccount) String() string {
 	return string(sebakcommon.MustJSONMarshal(b))
 }
 
-func (b *BlockAccount) Save(st *sebakstorage.LevelDBBackend) (err error) {
+func (b *BlockAccount) Save(st sebakstorage.DBBackend) (err error) {
 	key := GetBlockAccountKey(b.Address)
 
 	var exists bool
@@ -54,20 +57,33 @@ func (b *BlockAccount) Save(st *sebakstorage.LevelDBBackend) (err error) {
 		return
 	}
 
+	// the previous balance is needed for `BlockAccountStats`
+	var previous BlockAccount
 	if exists {
+		if err = st.Get(key, &previous); err != nil {
+			return
+		}
 		err = st.Set(key, b)
 	} else {
 		// TODO consider to use, [`Transaction`](https://godoc.org/github.com/syndtr/goleveldb/leveldb#DB.OpenTransaction)
-		err = st.New(key, b)
+		if err = st.New(key, b); err != nil {
+			return
+		}
 		createdKey := GetBlockAccountCreatedKey(sebakcommon.GetUniqueIDFromUUID())
 		err = st.New(createdKey, b.Address)
 	}
-	if err == nil {
-		event := "saved"
-		event += " " + fmt.Sprintf("address-%s", b.Address)
-		observer.BlockAccountObserver.Trigger(event, b)
+	if err != nil {
+		return
+	}
+
+	if err = updateBlockAccountStats(st, !exists, previous.Balance, b.Balance); err != nil {
+		return
 	}
 
+	event := "saved"
+	event += " " + fmt.Sprintf("address-%s", b.Address)
+	observer.BlockAccountObserver.Trigger(event, b)
+
 	bac := BlockAccountCheckpoint{
 		Checkpoint: b.Checkpoint,
 		Address:    b.Address,
@@ -94,19 +110,35 @@ func GetBlockAccountCreatedKey(created string) string {
 	return fmt.Sprintf("%s%s", BlockAccountPrefixCreated, created)
 }
 
-func ExistBlockAccount(st *sebakstorage.LevelDBBackend, address string) (exists bool, err error) {
+func ExistBlockAccount(st sebakstorage.DBBackend, address string) (exists bool, err error) {
 	return st.Has(GetBlockAccountKey(address))
 }
 
-func GetBlockAccount(st *sebakstorage.LevelDBBackend, address string) (b *BlockAccount, err error) {
-	if err = st.Get(GetBlockAccountKey(address), &b); err != nil {
+func GetBlockAccount(st sebakstorage.DBBackend, address string) (b *BlockAccount, err error) {
+	key := GetBlockAccountKey(address)
+
+	var cached interface{}
+	cached, err = st.Cache(BlockAccountCacheName).Load(key, func() (interface{}, error) {
+		var ba BlockAccount
+		if err := st.Get(key, &ba); err != nil {
+			return nil, err
+		}
+		return ba, nil
+	})
+	if err != nil {
 		return
 	}
 
+	// the cached one is shared, so copy it; `BlockAccount` is usually modified
+	// by the caller.
+	ba := cached.(BlockAccount)
+	ba.CodeHash = append([]byte(nil), ba.CodeHash...)
+	b = &ba
+
 	return
 }
 
-func GetBlockAccountAddressesByCreated(st *sebakstorage.LevelDBBackend, reverse bool) (func() (string, bool), func()) {
+func GetBlockAccountAddressesByCreated(st sebakstorage.DBBackend, reverse bool) (func() (string, bool), func()) {
 	iterFunc, closeFunc := st.GetIterator(BlockAccountPrefixCreated, reverse)
 
 	return (func() (string, bool) {
@@ -123,7 +155,7 @@ func GetBlockAccountAddressesByCreated(st *sebakstorage.LevelDBBackend, reverse
 		})
 }
 
-func GetBlockAccountsByCreated(st *sebakstorage.LevelDBBackend, reverse bool) (func() (*BlockAccount, bool), func()) {
+func GetBlockAccountsByCreated(st sebakstorage.DBBackend, reverse bool) (func() (*BlockAccount, bool), func()) {
 	iterFunc, closeFunc := GetBlockAccountAddressesByCreated(st, reverse)
 
 	return (func() (*BlockAccount, bool) {
@@ -207,7 +239,7 @@ func (b *BlockAccountCheckpoint) String() string {
 	return string(sebakcommon.MustJSONMarshal(b))
 }
 
-func (b *BlockAccountCheckpoint) Save(st *sebakstorage.LevelDBBackend) (err error) {
+func (b *BlockAccountCheckpoint) Save(st sebakstorage.DBBackend) (err error) {
 	key := GetBlockAccountCheckpointKey(b.Address, b.Checkpoint)
 
 	var exists bool
@@ -231,7 +263,7 @@ func (b *BlockAccountCheckpoint) Save(st *sebakstorage.LevelDBBackend) (err erro
 	return
 }
 
-func GetBlockAccountCheckpoint(st *sebakstorage.LevelDBBackend, address, checkpoint string) (b BlockAccountCheckpoint, err error) {
+func GetBlockAccountCheckpoint(st sebakstorage.DBBackend, address, checkpoint string) (b BlockAccountCheckpoint, err error) {
 	if err = st.Get(GetBlockAccountCheckpointKey(address, checkpoint), &b); err != nil {
 		return
 	}
@@ -239,7 +271,7 @@ func GetBlockAccountCheckpoint(st *sebakstorage.LevelDBBackend, address, checkpo
 	return
 }
 
-func GetBlockAccountCheckpointByAddress(st *sebakstorage.LevelDBBackend, address string, reverse bool) (func() (BlockAccountCheckpoint, bool), func()) {
+func GetBlockAccountCheckpointByAddress(st sebakstorage.DBBackend, address string, reverse bool) (func() (BlockAccountCheckpoint, bool), func()) {
 	prefix := GetBlockAccountCheckpointByAddressKeyPrefix(address)
 	iterFunc, closeFunc := st.GetIterator(prefix, reverse)
 
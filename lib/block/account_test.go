@@ -153,3 +153,51 @@ func TestBlockAccountObserver(t *testing.T) {
 	require.Equal(t, b.Balance, triggered.Balance)
 	require.Equal(t, b.Checkpoint, triggered.Checkpoint)
 }
+
+func TestGetBlockAccountCached(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	b := TestMakeBlockAccount()
+	require.Nil(t, b.Save(st))
+
+	fetched, err := GetBlockAccount(st, b.Address)
+	require.Nil(t, err)
+	require.Equal(t, b.Balance, fetched.Balance)
+
+	// modifying the fetched one does not change the cached one
+	fetched.Deposit(sebakcommon.Amount(100), "fake-checkpoint")
+
+	cached, _ := GetBlockAccount(st, b.Address)
+	require.Equal(t, b.Balance, cached.Balance)
+	require.Equal(t, uint64(1), st.Cache(BlockAccountCacheName).Hits())
+
+	// saving invalidates the cached one
+	require.Nil(t, fetched.Save(st))
+
+	cached, _ = GetBlockAccount(st, b.Address)
+	require.Equal(t, fetched.Balance, cached.Balance)
+}
+
+func TestBlockAccountStats(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	s, err := GetBlockAccountStats(st)
+	require.Nil(t, err)
+	require.Equal(t, uint64(0), s.Accounts)
+	require.Equal(t, "0", s.Supply)
+
+	b0 := TestMakeBlockAccount()
+	require.Nil(t, b0.Save(st))
+	b1 := TestMakeBlockAccount()
+	require.Nil(t, b1.Save(st))
+
+	// only the balance is changed
+	require.Nil(t, b0.Withdraw(sebakcommon.Amount(300), "fake-checkpoint"))
+	require.Nil(t, b0.Save(st))
+
+	s, err = GetBlockAccountStats(st)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), s.Accounts)
+	require.Equal(t, "3700", s.Supply)
+}
@@ -0,0 +1,104 @@
+package block
+
+import (
+	"fmt"
+	"math/big"
+
+	"boscoin.io/sebak/lib/storage"
+)
+
+const BlockAccountStatsKey string = "stats-account"
+
+// BlockAccountStats is the totals of all the accounts. It is updated by
+// `BlockAccount.Save`, so the accounts are not scanned to get them; the
+// accounts are saved in the exclusive storage transaction, so the updates do
+// not overlap. `Supply` is the sum of balances; it is the decimal string like
+// `BlockAccount.Balance` and is not limited by `MaximumBalance`.
+type BlockAccountStats struct {
+	Accounts uint64
+	Supply   string
+}
+
+// GetBlockAccountStats returns the stored stats; before any account is saved,
+// it is the empty stats.
+func GetBlockAccountStats(st sebakstorage.DBBackend) (s BlockAccountStats, err error) {
+	s.Supply = "0"
+
+	var exists bool
+	if exists, err = st.Has(BlockAccountStatsKey); err != nil || !exists {
+		return
+	}
+	err = st.Get(BlockAccountStatsKey, &s)
+
+	return
+}
+
+// updateBlockAccountStats applies the balance change of one account; for the
+// new account, `previous` is empty.
+func updateBlockAccountStats(st sebakstorage.DBBackend, created bool, previous, balance string) (err error) {
+	var s BlockAccountStats
+	if s, err = GetBlockAccountStats(st); err != nil {
+		return
+	}
+
+	if created {
+		s.Accounts++
+	}
+	if s.Supply, err = AddBigAmount(s.Supply, balance); err != nil {
+		return
+	}
+	if !created {
+		if s.Supply, err = SubBigAmount(s.Supply, previous); err != nil {
+			return
+		}
+	}
+
+	var exists bool
+	if exists, err = st.Has(BlockAccountStatsKey); err != nil {
+		return
+	} else if exists {
+		err = st.Set(BlockAccountStatsKey, s)
+	} else {
+		err = st.New(BlockAccountStatsKey, s)
+	}
+
+	return
+}
+
+func parseBigAmount(s string) (n *big.Int, err error) {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		err = fmt.Errorf("invalid amount: '%s'", s)
+	}
+
+	return
+}
+
+// AddBigAmount adds the amounts of decimal string; the stats use them,
+// because the sum of amounts can be greater than `sebakcommon.Amount`.
+func AddBigAmount(a, b string) (string, error) {
+	x, err := parseBigAmount(a)
+	if err != nil {
+		return "", err
+	}
+	y, err := parseBigAmount(b)
+	if err != nil {
+		return "", err
+	}
+
+	return x.Add(x, y).String(), nil
+}
+
+// SubBigAmount subtracts `b` from `a`, see `AddBigAmount`.
+func SubBigAmount(a, b string) (string, error) {
+	x, err := parseBigAmount(a)
+	if err != nil {
+		return "", err
+	}
+	y, err := parseBigAmount(b)
+	if err != nil {
+		return "", err
+	}
+
+	return x.Sub(x, y).String(), nil
+}
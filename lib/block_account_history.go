@@ -0,0 +1,116 @@
+package sebak
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// BlockAccountHistory is the balance of account after the transaction; it is
+// indexed by `SaveBlockAccountHistory` at finishing the transaction. The
+// genesis balance has no `Hash` and `Confirmed`.
+type BlockAccountHistory struct {
+	ID         string `json:"id"`
+	Address    string `json:"address"`
+	Balance    string `json:"balance"`
+	Checkpoint string `json:"checkpoint"`
+	Hash       string `json:"hash,omitempty"` // hash of `BlockTransaction`
+	Confirmed  string `json:"confirmed,omitempty"`
+	Date       string `json:"date,omitempty"` // only for the daily closing balance
+}
+
+// BlockAccountHistoryPrefix is the index of the balance histories of account
+// by the confirmed time; `BlockAccountHistory.ID` makes the order and it is
+// the cursor of `GetBlockAccountHistory`.
+//  * 'bah-<BlockAccountHistory.Address>-<BlockAccountHistory.ID>': `BlockAccountHistory`
+const BlockAccountHistoryPrefix string = "bah-"
+
+func GetBlockAccountHistoryKeyPrefix(address string) string {
+	return fmt.Sprintf("%s%s-", BlockAccountHistoryPrefix, address)
+}
+
+// NewBlockAccountHistory makes the history of the current balance of `ba`,
+// which was changed by `bt`; `bt` is nil for the genesis balance.
+func NewBlockAccountHistory(ba *block.BlockAccount, bt *BlockTransaction) BlockAccountHistory {
+	h := BlockAccountHistory{
+		Address:    ba.Address,
+		Balance:    ba.Balance,
+		Checkpoint: ba.Checkpoint,
+	}
+	if bt != nil {
+		h.Hash = bt.Hash
+		h.Confirmed = bt.Confirmed
+	}
+	h.ID = makeStreamID(h.Confirmed, h.Checkpoint)
+
+	return h
+}
+
+func (h BlockAccountHistory) Key() string {
+	return GetBlockAccountHistoryKeyPrefix(h.Address) + h.ID
+}
+
+// SaveBlockAccountHistory indexes the current balance of `ba`; see
+// `NewBlockAccountHistory` for `bt`. It should be called after `ba` is saved.
+func SaveBlockAccountHistory(st sebakstorage.DBBackend, ba *block.BlockAccount, bt *BlockTransaction) (err error) {
+	h := NewBlockAccountHistory(ba, bt)
+
+	var exists bool
+	if exists, err = st.Has(h.Key()); err != nil || exists {
+		return
+	}
+
+	return st.New(h.Key(), h)
+}
+
+// GetBlockAccountHistory iterates the balance histories of account after
+// `cursor` in the order of confirmed time. The histories, which are older
+// than the window of `Pruner` are pruned except the current one and the
+// genesis balance, so the iterated histories can have the gap.
+func GetBlockAccountHistory(st sebakstorage.DBBackend, address, cursor string, reverse bool) (
+	func() (BlockAccountHistory, bool),
+	func(),
+) {
+	iterFunc, closeFunc := sebakstorage.GetIteratorAfter(st, GetBlockAccountHistoryKeyPrefix(address), cursor, reverse)
+
+	return (func() (BlockAccountHistory, bool) {
+			item, hasNext := iterFunc()
+			if !hasNext {
+				return BlockAccountHistory{}, false
+			}
+
+			var h BlockAccountHistory
+			if err := json.Unmarshal(item.Value, &h); err != nil {
+				return BlockAccountHistory{}, false
+			}
+			return h, hasNext
+		}), (func() {
+			closeFunc()
+		})
+}
+
+// BucketBlockAccountHistoryByDay returns the closing balance of each day in
+// UTC; `ID` and `Date` is the date like '2018-07-01'. The history, which is
+// not confirmed like genesis is skipped.
+func BucketBlockAccountHistoryByDay(histories []BlockAccountHistory) (buckets []BlockAccountHistory) {
+	for _, h := range histories {
+		confirmed, err := sebakcommon.ParseISO8601(h.Confirmed)
+		if err != nil {
+			continue
+		}
+
+		h.Date = confirmed.UTC().Format("2006-01-02")
+		h.ID = h.Date
+
+		if n := len(buckets); n > 0 && buckets[n-1].Date == h.Date {
+			buckets[n-1] = h
+			continue
+		}
+		buckets = append(buckets, h)
+	}
+
+	return
+}
@@ -0,0 +1,32 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+func TestBucketBlockAccountHistoryByDay(t *testing.T) {
+	day := time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	histories := []BlockAccountHistory{
+		{Balance: "100"}, // genesis
+		{Balance: "90", Confirmed: sebakcommon.FormatISO8601(day.Add(time.Hour))},
+		{Balance: "80", Confirmed: sebakcommon.FormatISO8601(day.Add(23 * time.Hour))},
+		{Balance: "70", Confirmed: sebakcommon.FormatISO8601(day.Add(49 * time.Hour))},
+	}
+
+	buckets := BucketBlockAccountHistoryByDay(histories)
+	require.Equal(t, 2, len(buckets))
+
+	require.Equal(t, "2018-07-01", buckets[0].Date)
+	require.Equal(t, "2018-07-01", buckets[0].ID)
+	require.Equal(t, "80", buckets[0].Balance)
+
+	// the day without transaction has no bucket
+	require.Equal(t, "2018-07-03", buckets[1].Date)
+	require.Equal(t, "70", buckets[1].Balance)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"time"
 
 	"boscoin.io/sebak/lib/common"
 	"boscoin.io/sebak/lib/error"
@@ -17,14 +18,54 @@ import (
 //
 //  * get list by `Source` and created order
 //  * get list by `Target` and created order
+//  * get list by `Source` and `Target` pair and created order
+//  * get list by `Confirmed` order
 
 const (
-	BlockOperationPrefixHash       string = "bo-hash-"       // bo-hash-<BlockOperation.Hash>
-	BlockOperationPrefixTxHash     string = "bo-txhash-"     // bo-txhash-<BlockOperation.TxHash>-<created>
-	BlockOperationPrefixSource     string = "bo-source-"     // bo-source-<BlockOperation.Source>-<created>
-	BlockOperationPrefixTarget     string = "bo-target-"     // bo-target-<BlockOperation.Target>-<created>
-	BlockOperationPrefixPeers      string = "bo-peers-"      // bo-target-<Address0>-<Address1>-<created>
-	BlockOperationPrefixCheckpoint string = "bo-checkpoint-" // bo-checkpoint-<Transaction.B.Checkpoint>-<created>
+	BlockOperationIndexHash       string = "hash"       // bo-hash-<BlockOperation.Hash>
+	BlockOperationIndexTxHash     string = "txhash"     // bo-txhash-<BlockOperation.TxHash>-<created>
+	BlockOperationIndexSource     string = "source"     // bo-source-<BlockOperation.Source>-<created>
+	BlockOperationIndexTarget     string = "target"     // bo-target-<BlockOperation.Target>-<created>
+	BlockOperationIndexCheckpoint string = "checkpoint" // bo-checkpoint-<Transaction.B.Checkpoint>-<created>
+	BlockOperationIndexPeers      string = "peers"      // bo-peers-<Address0><Address1>-<created>
+	BlockOperationIndexConfirmed  string = "confirmed"  // bo-confirmed-<BlockOperation.Confirmed>-<created>
+
+	BlockOperationIndexSourceConfirmed string = "sourceconfirmed" // bo-sourceconfirmed-<BlockOperation.Source>-<BlockOperation.Confirmed>-<created>
+	BlockOperationIndexPeersConfirmed  string = "peersconfirmed"  // bo-peersconfirmed-<Address0><Address1>-<BlockOperation.Confirmed>-<created>
+)
+
+var BlockOperationModel = sebakstorage.NewModel(
+	"bo-",
+	sebakstorage.NewIndex(BlockOperationIndexHash, true, func(v interface{}) []string {
+		return []string{v.(*BlockOperation).Hash}
+	}),
+	sebakstorage.NewIndex(BlockOperationIndexTxHash, false, func(v interface{}) []string {
+		return []string{v.(*BlockOperation).TxHash}
+	}),
+	sebakstorage.NewIndex(BlockOperationIndexSource, false, func(v interface{}) []string {
+		return []string{v.(*BlockOperation).Source}
+	}),
+	sebakstorage.NewIndex(BlockOperationIndexTarget, false, func(v interface{}) []string {
+		return []string{v.(*BlockOperation).Target}
+	}),
+	sebakstorage.NewIndex(BlockOperationIndexCheckpoint, false, func(v interface{}) []string {
+		return []string{v.(*BlockOperation).transaction.B.Checkpoint}
+	}),
+	sebakstorage.NewIndex(BlockOperationIndexPeers, false, func(v interface{}) []string {
+		bo := v.(*BlockOperation)
+		return []string{blockOperationPeers(bo.Source, bo.Target)}
+	}),
+	sebakstorage.NewIndex(BlockOperationIndexConfirmed, false, func(v interface{}) []string {
+		return []string{v.(*BlockOperation).Confirmed}
+	}),
+	sebakstorage.NewIndex(BlockOperationIndexSourceConfirmed, false, func(v interface{}) []string {
+		bo := v.(*BlockOperation)
+		return []string{makeConfirmedIndexValue(bo.Source, bo.Confirmed)}
+	}),
+	sebakstorage.NewIndex(BlockOperationIndexPeersConfirmed, false, func(v interface{}) []string {
+		bo := v.(*BlockOperation)
+		return []string{makeConfirmedIndexValue(blockOperationPeers(bo.Source, bo.Target), bo.Confirmed)}
+	}),
 )
 
 type BlockOperation struct {
@@ -36,6 +77,8 @@ type BlockOperation struct {
 	Target string
 	Amount sebakcommon.Amount
 
+	Confirmed string
+
 	// transaction will be used only for `Save` time.
 	transaction Transaction
 	isSaved     bool
@@ -59,44 +102,49 @@ func NewBlockOperationFromOperation(op Operation, tx Transaction) BlockOperation
 	}
 }
 
-func (bo *BlockOperation) Save(st *sebakstorage.LevelDBBackend) (err error) {
+func (bo *BlockOperation) Save(st sebakstorage.DBBackend) (err error) {
+	batch := sebakstorage.NewBatch()
+	if err = bo.put(st, batch); err != nil {
+		return
+	}
+
+	if err = st.Write(batch); err != nil {
+		return
+	}
+	bo.saved()
+
+	return nil
+}
+
+// put adds `BlockOperation` to the `Batch`; after the `Batch` is written,
+// `saved` must be called.
+func (bo *BlockOperation) put(st sebakstorage.DBBackend, batch *sebakstorage.Batch) (err error) {
 	if bo.isSaved {
 		return sebakerror.ErrorAlreadySaved
 	}
 
-	key := GetBlockOperationKey(bo.Hash)
-
 	var exists bool
-	if exists, err = st.Has(key); err != nil {
+	if exists, err = ExistBlockOperation(st, bo.Hash); err != nil {
 		return
 	} else if exists {
 		return sebakerror.ErrorBlockAlreadyExists
 	}
 
-	if err = st.New(GetBlockOperationKey(bo.Hash), bo); err != nil {
-		return
-	}
-	if err = st.New(bo.NewBlockOperationTxHashKey(), bo.Hash); err != nil {
-		return
-	}
-	if err = st.New(bo.NewBlockOperationSourceKey(), bo.Hash); err != nil {
-		return
-	}
-	if err = st.New(bo.NewBlockOperationTargetKey(), bo.Hash); err != nil {
-		return
-	}
-	if err = st.New(bo.NewBlockOperationCheckpoint(), bo.Hash); err != nil {
-		return
+	if len(bo.Confirmed) < 1 {
+		bo.Confirmed = sebakcommon.NowISO8601()
 	}
 
+	return BlockOperationModel.Put(st, batch, bo)
+}
+
+func (bo *BlockOperation) saved() {
 	bo.isSaved = true
 
 	event := "saved"
 	event += " " + fmt.Sprintf("source-%s", bo.Source)
 	event += " " + fmt.Sprintf("hash-%s", bo.Hash)
+	event += " " + fmt.Sprintf("peers-%s", blockOperationPeers(bo.Source, bo.Target))
 	observer.BlockOperationObserver.Trigger(event, bo)
-
-	return nil
 }
 
 func (bo BlockOperation) Serialize() (encoded []byte, err error) {
@@ -109,77 +157,43 @@ func (bo BlockOperation) Transaction() Transaction {
 }
 
 func GetBlockOperationKey(hash string) string {
-	return fmt.Sprintf("%s%s", BlockOperationPrefixHash, hash)
+	return BlockOperationModel.Key(hash)
 }
 
 func GetBlockOperationKeyPrefixTxHash(txHash string) string {
-	return fmt.Sprintf("%s%s-", BlockOperationPrefixTxHash, txHash)
+	return BlockOperationModel.IndexPrefix(BlockOperationIndexTxHash, txHash)
 }
 
 func GetBlockOperationKeyPrefixSource(source string) string {
-	return fmt.Sprintf("%s%s-", BlockOperationPrefixSource, source)
+	return BlockOperationModel.IndexPrefix(BlockOperationIndexSource, source)
 }
 
 func GetBlockOperationKeyPrefixTarget(target string) string {
-	return fmt.Sprintf("%s%s-", BlockOperationPrefixTarget, target)
+	return BlockOperationModel.IndexPrefix(BlockOperationIndexTarget, target)
 }
 
 func GetBlockOperationKeyPrefixCheckpoint(checkpoint string) string {
-	return fmt.Sprintf("%s%s-", BlockOperationPrefixCheckpoint, checkpoint)
-}
-
-func GetBlockOperationKeyPrefixPeers(one, two string) string {
-	return fmt.Sprintf("%s%s%s-", BlockOperationPrefixPeers, one, two)
-}
-
-func (bo BlockOperation) NewBlockOperationTxHashKey() string {
-	return fmt.Sprintf(
-		"%s%s",
-		GetBlockOperationKeyPrefixTxHash(bo.TxHash),
-		sebakcommon.GetUniqueIDFromUUID(),
-	)
-}
-
-func (bo BlockOperation) NewBlockOperationSourceKey() string {
-	return fmt.Sprintf(
-		"%s%s",
-		GetBlockOperationKeyPrefixSource(bo.Source),
-		sebakcommon.GetUniqueIDFromUUID(),
-	)
-}
-
-func (bo BlockOperation) NewBlockOperationTargetKey() string {
-	return fmt.Sprintf(
-		"%s%s",
-		GetBlockOperationKeyPrefixTarget(bo.Target),
-		sebakcommon.GetUniqueIDFromUUID(),
-	)
+	return BlockOperationModel.IndexPrefix(BlockOperationIndexCheckpoint, checkpoint)
 }
 
-func (bo BlockOperation) NewBlockOperationCheckpoint() string {
-	return fmt.Sprintf(
-		"%s%s",
-		GetBlockOperationKeyPrefixCheckpoint(bo.transaction.B.Checkpoint),
-		sebakcommon.GetUniqueIDFromUUID(),
-	)
+// blockOperationPeers is the value of `BlockOperationIndexPeers`; the order of
+// addresses does not matter.
+func blockOperationPeers(one, two string) string {
+	addresses := []string{one, two}
+	sort.Strings(addresses)
+	return addresses[0] + addresses[1]
 }
 
-func (bo BlockOperation) NewBlockOperationPeersKey() string {
-	addresses := []string{bo.Target, bo.Source}
-	sort.Strings(addresses)
-	return fmt.Sprintf(
-		"%s%s",
-		GetBlockOperationKeyPrefixPeers(addresses[0], addresses[1]),
-		sebakcommon.GetUniqueIDFromUUID(),
-	)
+func GetBlockOperationKeyPrefixPeers(one, two string) string {
+	return BlockOperationModel.IndexPrefix(BlockOperationIndexPeers, blockOperationPeers(one, two))
 }
 
-func ExistBlockOperation(st *sebakstorage.LevelDBBackend, hash string) (bool, error) {
-	return st.Has(GetBlockOperationKey(hash))
+func ExistBlockOperation(st sebakstorage.DBBackend, hash string) (bool, error) {
+	return BlockOperationModel.Has(st, hash)
 }
 
-func GetBlockOperation(st *sebakstorage.LevelDBBackend, hash string) (bo BlockOperation, err error) {
-	if err = st.Get(GetBlockOperationKey(hash), &bo); err != nil {
+func GetBlockOperation(st sebakstorage.DBBackend, hash string) (bo BlockOperation, err error) {
+	if err = BlockOperationModel.Get(st, hash, &bo); err != nil {
 		return
 	}
 
@@ -188,7 +202,6 @@ func GetBlockOperation(st *sebakstorage.LevelDBBackend, hash string) (bo BlockOp
 }
 
 func LoadBlockOperationsInsideIterator(
-	st *sebakstorage.LevelDBBackend,
 	iterFunc func() (sebakstorage.IterItem, bool),
 	closeFunc func(),
 ) (
@@ -202,13 +215,11 @@ func LoadBlockOperationsInsideIterator(
 				return BlockOperation{}, false
 			}
 
-			var hash string
-			json.Unmarshal(item.Value, &hash)
-
-			bo, err := GetBlockOperation(st, hash)
-			if err != nil {
+			var bo BlockOperation
+			if err := json.Unmarshal(item.Value, &bo); err != nil {
 				return BlockOperation{}, false
 			}
+			bo.isSaved = true
 
 			return bo, hasNext
 		}), (func() {
@@ -216,38 +227,162 @@ func LoadBlockOperationsInsideIterator(
 		})
 }
 
-func GetBlockOperationsByTxHash(st *sebakstorage.LevelDBBackend, txHash string, reverse bool) (
+func GetBlockOperationsByTxHash(st sebakstorage.DBBackend, txHash string, reverse bool) (
+	func() (BlockOperation, bool),
+	func(),
+) {
+	iterFunc, closeFunc := BlockOperationModel.GetIterator(st, BlockOperationIndexTxHash, txHash, reverse)
+
+	return LoadBlockOperationsInsideIterator(iterFunc, closeFunc)
+}
+
+func GetBlockOperationsBySource(st sebakstorage.DBBackend, source string, reverse bool) (
+	func() (BlockOperation, bool),
+	func(),
+) {
+	iterFunc, closeFunc := BlockOperationModel.GetIterator(st, BlockOperationIndexSource, source, reverse)
+
+	return LoadBlockOperationsInsideIterator(iterFunc, closeFunc)
+}
+
+func GetBlockOperationsByTarget(st sebakstorage.DBBackend, target string, reverse bool) (
+	func() (BlockOperation, bool),
+	func(),
+) {
+	iterFunc, closeFunc := BlockOperationModel.GetIterator(st, BlockOperationIndexTarget, target, reverse)
+
+	return LoadBlockOperationsInsideIterator(iterFunc, closeFunc)
+}
+
+func GetBlockOperationsByCheckpoint(st sebakstorage.DBBackend, checkpoint string, reverse bool) (
+	func() (BlockOperation, bool),
+	func(),
+) {
+	iterFunc, closeFunc := BlockOperationModel.GetIterator(st, BlockOperationIndexCheckpoint, checkpoint, reverse)
+
+	return LoadBlockOperationsInsideIterator(iterFunc, closeFunc)
+}
+
+// GetBlockOperationsFromConfirmed iterates the operations in the order of
+// `Confirmed` from `confirmed`; the operations confirmed before it are not
+// read.
+func GetBlockOperationsFromConfirmed(st sebakstorage.DBBackend, confirmed string) (
 	func() (BlockOperation, bool),
 	func(),
 ) {
-	iterFunc, closeFunc := st.GetIterator(GetBlockOperationKeyPrefixTxHash(txHash), reverse)
+	iterFunc, closeFunc := BlockOperationModel.GetIteratorRange(st, BlockOperationIndexConfirmed, confirmed, "", false)
 
-	return LoadBlockOperationsInsideIterator(st, iterFunc, closeFunc)
+	return LoadBlockOperationsInsideIterator(iterFunc, closeFunc)
 }
 
-func GetBlockOperationsBySource(st *sebakstorage.LevelDBBackend, source string, reverse bool) (
+// GetBlockOperationsBySourceFromConfirmed iterates the operations of `source`
+// in the order of `Confirmed` from `confirmed`.
+func GetBlockOperationsBySourceFromConfirmed(st sebakstorage.DBBackend, source, confirmed string) (
 	func() (BlockOperation, bool),
 	func(),
 ) {
-	iterFunc, closeFunc := st.GetIterator(GetBlockOperationKeyPrefixSource(source), reverse)
+	iterFunc, closeFunc := BlockOperationModel.GetIteratorFrom(
+		st,
+		BlockOperationIndexSourceConfirmed,
+		makeConfirmedIndexValue(source, ""),
+		makeConfirmedIndexValue(source, confirmed),
+	)
 
-	return LoadBlockOperationsInsideIterator(st, iterFunc, closeFunc)
+	return LoadBlockOperationsInsideIterator(iterFunc, closeFunc)
 }
 
-func GetBlockOperationsByTarget(st *sebakstorage.LevelDBBackend, target string, reverse bool) (
+// GetBlockOperationsByPeersFromConfirmed iterates the operations between two
+// accounts in the order of `Confirmed` from `confirmed`.
+func GetBlockOperationsByPeersFromConfirmed(st sebakstorage.DBBackend, one, two, confirmed string) (
 	func() (BlockOperation, bool),
 	func(),
 ) {
-	iterFunc, closeFunc := st.GetIterator(GetBlockOperationKeyPrefixTarget(target), reverse)
+	peers := blockOperationPeers(one, two)
+	iterFunc, closeFunc := BlockOperationModel.GetIteratorFrom(
+		st,
+		BlockOperationIndexPeersConfirmed,
+		makeConfirmedIndexValue(peers, ""),
+		makeConfirmedIndexValue(peers, confirmed),
+	)
 
-	return LoadBlockOperationsInsideIterator(st, iterFunc, closeFunc)
+	return LoadBlockOperationsInsideIterator(iterFunc, closeFunc)
 }
 
-func GetBlockOperationsByCheckpoint(st *sebakstorage.LevelDBBackend, checkpoint string, reverse bool) (
+// GetBlockOperationsByPeers returns the operations between two accounts in
+// both directions.
+func GetBlockOperationsByPeers(st sebakstorage.DBBackend, one, two string, reverse bool) (
 	func() (BlockOperation, bool),
 	func(),
 ) {
-	iterFunc, closeFunc := st.GetIterator(GetBlockOperationKeyPrefixCheckpoint(checkpoint), reverse)
+	iterFunc, closeFunc := BlockOperationModel.GetIterator(st, BlockOperationIndexPeers, blockOperationPeers(one, two), reverse)
+
+	return LoadBlockOperationsInsideIterator(iterFunc, closeFunc)
+}
 
-	return LoadBlockOperationsInsideIterator(st, iterFunc, closeFunc)
+func CountBlockOperationsBySource(st sebakstorage.DBBackend, source string) (int, error) {
+	return BlockOperationModel.Count(st, BlockOperationIndexSource, source)
+}
+
+func CountBlockOperationsByTarget(st sebakstorage.DBBackend, target string) (int, error) {
+	return BlockOperationModel.Count(st, BlockOperationIndexTarget, target)
+}
+
+func CountBlockOperationsByPeers(st sebakstorage.DBBackend, one, two string) (int, error) {
+	return BlockOperationModel.Count(st, BlockOperationIndexPeers, blockOperationPeers(one, two))
+}
+
+// BlockOperationFilter selects the `BlockOperation`s by `Type` and the range
+// of `Confirmed`; the empty field selects all.
+type BlockOperationFilter struct {
+	Types []OperationType
+	Since time.Time // `Confirmed` is same or after it
+	Until time.Time // `Confirmed` is before it
+}
+
+func (f BlockOperationFilter) Match(bo BlockOperation) bool {
+	if len(f.Types) > 0 {
+		var found bool
+		for _, t := range f.Types {
+			if t == bo.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.Since.IsZero() && f.Until.IsZero() {
+		return true
+	}
+
+	confirmed, err := sebakcommon.ParseISO8601(bo.Confirmed)
+	if err != nil {
+		return false
+	}
+	if !f.Since.IsZero() && confirmed.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !confirmed.Before(f.Until) {
+		return false
+	}
+
+	return true
+}
+
+// FilterBlockOperations wraps the iterator of `BlockOperation`s; only the
+// operations, which match `f` are returned.
+func FilterBlockOperations(iterFunc func() (BlockOperation, bool), f BlockOperationFilter) func() (BlockOperation, bool) {
+	return func() (BlockOperation, bool) {
+		for {
+			bo, hasNext := iterFunc()
+			if !hasNext {
+				return BlockOperation{}, false
+			}
+			if f.Match(bo) {
+				return bo, true
+			}
+		}
+	}
 }
@@ -2,11 +2,13 @@ package sebak
 
 import (
 	"testing"
+	"time"
 
 	"boscoin.io/sebak/lib/common"
 	"boscoin.io/sebak/lib/error"
 	"boscoin.io/sebak/lib/storage"
 
+	"github.com/stellar/go/keypair"
 	"github.com/stretchr/testify/require"
 )
 
@@ -161,3 +163,62 @@ func TestBlockOperationGetSortedByCheckpoint(t *testing.T) {
 		require.Equal(t, bo.Amount, op.B.GetAmount())
 	}
 }
+
+func TestBlockOperationGetByPeers(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	kpA, _ := keypair.Random()
+	kpB, _ := keypair.Random()
+	kpC, _ := keypair.Random()
+
+	var expected []string
+	for _, pair := range [][]*keypair.Full{{kpA, kpB}, {kpB, kpA}, {kpA, kpC}, {kpB, kpA}} {
+		tx := TestMakeTransactionWithKeypair(networkID, 1, pair[0], pair[1])
+		bt := NewBlockTransactionFromTransaction(tx, sebakcommon.MustJSONMarshal(tx))
+		require.Nil(t, bt.Save(st))
+
+		if pair[1] != kpC {
+			expected = append(expected, bt.Operations...)
+		}
+	}
+
+	// the order of addresses does not matter
+	for _, peers := range [][]string{{kpA.Address(), kpB.Address()}, {kpB.Address(), kpA.Address()}} {
+		var saved []string
+		iterFunc, closeFunc := GetBlockOperationsByPeers(st, peers[0], peers[1], false)
+		for {
+			bo, hasNext := iterFunc()
+			if !hasNext {
+				break
+			}
+			saved = append(saved, bo.Hash)
+		}
+		closeFunc()
+
+		require.Equal(t, len(expected), len(saved))
+		for _, hash := range expected {
+			_, found := sebakcommon.InStringArray(saved, hash)
+			require.True(t, found)
+		}
+	}
+
+	n, err := CountBlockOperationsByPeers(st, kpC.Address(), kpA.Address())
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+}
+
+func TestBlockOperationFilter(t *testing.T) {
+	confirmed := time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)
+	bo := BlockOperation{Type: OperationPayment, Confirmed: sebakcommon.FormatISO8601(confirmed)}
+
+	require.True(t, BlockOperationFilter{}.Match(bo))
+	require.True(t, BlockOperationFilter{Types: []OperationType{OperationCreateAccount, OperationPayment}}.Match(bo))
+	require.False(t, BlockOperationFilter{Types: []OperationType{OperationCreateAccount}}.Match(bo))
+
+	// `Since` is included and `Until` is not
+	require.True(t, BlockOperationFilter{Since: confirmed}.Match(bo))
+	require.False(t, BlockOperationFilter{Until: confirmed}.Match(bo))
+	require.True(t, BlockOperationFilter{Since: confirmed.Add(-time.Second), Until: confirmed.Add(time.Second)}.Match(bo))
+	require.False(t, BlockOperationFilter{Since: confirmed.Add(time.Second)}.Match(bo))
+}
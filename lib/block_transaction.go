@@ -19,14 +19,54 @@ import (
 //  * get list by `Account` and created order
 
 const (
-	BlockTransactionPrefixHash       string = "bt-hash-"       // bt-hash-<BlockTransaction.Hash>
-	BlockTransactionPrefixCheckpoint string = "bt-checkpoint-" // bt-hash-<BlockTransaction.PreviousCheckpoint>,bt-hash-<BlockTransaction.SourceCheckpoint>,  bt-hash-<BlockTransaction.TargetCheckpoint>,
-	BlockTransactionPrefixSource     string = "bt-source-"     // bt-hash-<BlockTransaction.Source>
-	BlockTransactionPrefixConfirmed  string = "bt-confirmed-"  // bt-hash-<BlockTransaction.Confirmed>
-	BlockTransactionPrefixAccount    string = "bt-account-"    //bt-hash-<BlockTransaction.Source>,<BlockTransaction.Operations.Target>
+	BlockTransactionIndexHash       string = "hash"       // bt-hash-<BlockTransaction.Hash>
+	BlockTransactionIndexCheckpoint string = "checkpoint" // bt-checkpoint-<BlockTransaction.SourceCheckpoint>, bt-checkpoint-<BlockTransaction.TargetCheckpoint>
+	BlockTransactionIndexSource     string = "source"     // bt-source-<BlockTransaction.Source>-<created>
+	BlockTransactionIndexConfirmed  string = "confirmed"  // bt-confirmed-<BlockTransaction.Confirmed>-<created>
+	BlockTransactionIndexAccount    string = "account"    // bt-account-<BlockTransaction.Source>-<created>, bt-account-<BlockTransaction.Operations.Target>-<created>
+
+	BlockTransactionIndexSourceConfirmed string = "sourceconfirmed" // bt-sourceconfirmed-<BlockTransaction.Source>-<BlockTransaction.Confirmed>-<created>
 )
 
-// TODO(BlockTransaction): support counting
+// BlockTransactionCacheName is the name of storage cache for
+// `BlockTransaction`.
+const BlockTransactionCacheName string = "transaction"
+
+var BlockTransactionModel = sebakstorage.NewModel(
+	"bt-",
+	sebakstorage.NewIndex(BlockTransactionIndexHash, true, func(v interface{}) []string {
+		return []string{v.(*BlockTransaction).Hash}
+	}),
+	sebakstorage.NewIndex(BlockTransactionIndexCheckpoint, true, func(v interface{}) []string {
+		bt := v.(*BlockTransaction)
+		return []string{bt.SourceCheckpoint, bt.TargetCheckpoint}
+	}),
+	sebakstorage.NewIndex(BlockTransactionIndexSource, false, func(v interface{}) []string {
+		return []string{v.(*BlockTransaction).Source}
+	}),
+	sebakstorage.NewIndex(BlockTransactionIndexConfirmed, false, func(v interface{}) []string {
+		return []string{v.(*BlockTransaction).Confirmed}
+	}),
+	sebakstorage.NewIndex(BlockTransactionIndexAccount, false, func(v interface{}) []string {
+		bt := v.(*BlockTransaction)
+
+		accounts := []string{bt.Source}
+		for _, op := range bt.transaction.B.Operations {
+			accounts = append(accounts, op.B.TargetAddress())
+		}
+		return accounts
+	}),
+	sebakstorage.NewIndex(BlockTransactionIndexSourceConfirmed, false, func(v interface{}) []string {
+		bt := v.(*BlockTransaction)
+		return []string{makeConfirmedIndexValue(bt.Source, bt.Confirmed)}
+	}),
+)
+
+// makeConfirmedIndexValue makes the index value, which orders the records of
+// `value` by `confirmed`.
+func makeConfirmedIndexValue(value, confirmed string) string {
+	return fmt.Sprintf("%s-%s", value, confirmed)
+}
 
 type BlockTransaction struct {
 	Hash string
@@ -73,78 +113,52 @@ func NewBlockTransactionFromTransaction(tx Transaction, message []byte) BlockTra
 }
 
 func GetBlockTransactionKeyCheckpoint(checkpoint string) string {
-	return fmt.Sprintf("%s%s", BlockTransactionPrefixCheckpoint, checkpoint)
-}
-
-func (bt BlockTransaction) NewBlockTransactionKeySource() string {
-	return fmt.Sprintf(
-		"%s%s",
-		GetBlockTransactionKeyPrefixSource(bt.Source),
-		sebakcommon.GetUniqueIDFromUUID(),
-	)
-}
-
-func (bt BlockTransaction) NewBlockTransactionKeyConfirmed() string {
-	return fmt.Sprintf(
-		"%s%s",
-		GetBlockTransactionKeyPrefixConfirmed(bt.Confirmed),
-		sebakcommon.GetUniqueIDFromUUID(),
-	)
-}
-
-func (bt BlockTransaction) NewBlockTransactionKeyByAccount(accountAddress string) string {
-	return fmt.Sprintf(
-		"%s%s",
-		GetBlockTransactionKeyPrefixAccount(accountAddress),
-		sebakcommon.GetUniqueIDFromUUID(),
-	)
+	return BlockTransactionModel.IndexPrefix(BlockTransactionIndexCheckpoint, checkpoint)
 }
 
-func (bt *BlockTransaction) Save(st *sebakstorage.LevelDBBackend) (err error) {
+func (bt *BlockTransaction) Save(st sebakstorage.DBBackend) (err error) {
 	if bt.isSaved {
 		return sebakerror.ErrorAlreadySaved
 	}
 
-	key := GetBlockTransactionKey(bt.Hash)
-
 	var exists bool
-	exists, err = st.Has(key)
-	if err != nil {
+	if exists, err = ExistBlockTransaction(st, bt.Hash); err != nil {
 		return
 	} else if exists {
 		return sebakerror.ErrorBlockAlreadyExists
 	}
 
-	bt.Confirmed = sebakcommon.NowISO8601()
-	if err = st.New(GetBlockTransactionKey(bt.Hash), bt); err != nil {
-		return
-	}
-	if err = st.New(GetBlockTransactionKeyCheckpoint(bt.SourceCheckpoint), bt.Hash); err != nil {
-		return
-	}
-	if err = st.New(GetBlockTransactionKeyCheckpoint(bt.TargetCheckpoint), bt.Hash); err != nil {
-		return
+	// the replayed transaction keeps it's original `Confirmed`
+	if len(bt.Confirmed) < 1 {
+		bt.Confirmed = sebakcommon.NowISO8601()
 	}
-	if err = st.New(bt.NewBlockTransactionKeySource(), bt.Hash); err != nil {
-		return
-	}
-	if err = st.New(bt.NewBlockTransactionKeyConfirmed(), bt.Hash); err != nil {
+
+	batch := sebakstorage.NewBatch()
+	if err = BlockTransactionModel.Put(st, batch, bt); err != nil {
 		return
 	}
-	if err = st.New(bt.NewBlockTransactionKeyByAccount(bt.Source), bt.Hash); err != nil {
+	if err = putBlockTransactionStats(st, batch, *bt); err != nil {
 		return
 	}
+
+	var bos []BlockOperation
 	for _, op := range bt.transaction.B.Operations {
 		bo := NewBlockOperationFromOperation(op, bt.transaction)
-		if err = bo.Save(st); err != nil {
+		bo.Confirmed = bt.Confirmed
+		if err = bo.put(st, batch); err != nil {
 			return
 		}
+		bos = append(bos, bo)
+	}
 
-		target := op.B.TargetAddress()
-		if err = st.New(bt.NewBlockTransactionKeyByAccount(target), bt.Hash); err != nil {
-			return
-		}
+	if err = st.Write(batch); err != nil {
+		return
+	}
+
+	for i := range bos {
+		bos[i].saved()
 	}
+
 	event := "saved"
 	event += " " + fmt.Sprintf("source-%s", bt.Source)
 	event += " " + fmt.Sprintf("hash-%s", bt.Hash)
@@ -169,36 +183,44 @@ func (bt BlockTransaction) Transaction() Transaction {
 }
 
 func GetBlockTransactionKeyPrefixSource(source string) string {
-	return fmt.Sprintf("%s%s-", BlockTransactionPrefixSource, source)
+	return BlockTransactionModel.IndexPrefix(BlockTransactionIndexSource, source)
 }
 
 func GetBlockTransactionKeyPrefixConfirmed(confirmed string) string {
-	return fmt.Sprintf("%s%s-", BlockTransactionPrefixConfirmed, confirmed)
+	return BlockTransactionModel.IndexPrefix(BlockTransactionIndexConfirmed, confirmed)
 }
 
 func GetBlockTransactionKeyPrefixAccount(accountAddress string) string {
-	return fmt.Sprintf("%s%s-", BlockTransactionPrefixAccount, accountAddress)
+	return BlockTransactionModel.IndexPrefix(BlockTransactionIndexAccount, accountAddress)
 }
 
 func GetBlockTransactionKey(hash string) string {
-	return fmt.Sprintf("%s%s", BlockTransactionPrefixHash, hash)
+	return BlockTransactionModel.Key(hash)
 }
 
-func GetBlockTransaction(st *sebakstorage.LevelDBBackend, hash string) (bt BlockTransaction, err error) {
-	if err = st.Get(GetBlockTransactionKey(hash), &bt); err != nil {
+func GetBlockTransaction(st sebakstorage.DBBackend, hash string) (bt BlockTransaction, err error) {
+	var cached interface{}
+	cached, err = st.Cache(BlockTransactionCacheName).Load(GetBlockTransactionKey(hash), func() (interface{}, error) {
+		var loaded BlockTransaction
+		if err := BlockTransactionModel.Get(st, hash, &loaded); err != nil {
+			return nil, err
+		}
+		return loaded, nil
+	})
+	if err != nil {
 		return
 	}
 
+	bt = cached.(BlockTransaction)
 	bt.isSaved = true
 	return
 }
 
-func ExistBlockTransaction(st *sebakstorage.LevelDBBackend, hash string) (bool, error) {
-	return st.Has(GetBlockTransactionKey(hash))
+func ExistBlockTransaction(st sebakstorage.DBBackend, hash string) (bool, error) {
+	return BlockTransactionModel.Has(st, hash)
 }
 
 func LoadBlockTransactionsInsideIterator(
-	st *sebakstorage.LevelDBBackend,
 	iterFunc func() (sebakstorage.IterItem, bool),
 	closeFunc func(),
 ) (
@@ -212,13 +234,11 @@ func LoadBlockTransactionsInsideIterator(
 				return BlockTransaction{}, false
 			}
 
-			var hash string
-			json.Unmarshal(item.Value, &hash)
-
-			bt, err := GetBlockTransaction(st, hash)
-			if err != nil {
+			var bt BlockTransaction
+			if err := json.Unmarshal(item.Value, &bt); err != nil {
 				return BlockTransaction{}, false
 			}
+			bt.isSaved = true
 
 			return bt, hasNext
 		}), (func() {
@@ -226,40 +246,79 @@ func LoadBlockTransactionsInsideIterator(
 		})
 }
 
-func GetBlockTransactionByCheckpoint(st *sebakstorage.LevelDBBackend, checkpoint string) (bt BlockTransaction, err error) {
-	var hash string
-	if err = st.Get(GetBlockTransactionKeyCheckpoint(checkpoint), &hash); err != nil {
+func GetBlockTransactionByCheckpoint(st sebakstorage.DBBackend, checkpoint string) (bt BlockTransaction, err error) {
+	if err = BlockTransactionModel.GetByIndex(st, BlockTransactionIndexCheckpoint, checkpoint, &bt); err != nil {
 		return
 	}
-	bt, err = GetBlockTransaction(st, hash)
 
+	bt.isSaved = true
 	return
 }
 
-func GetBlockTransactionsBySource(st *sebakstorage.LevelDBBackend, source string, reverse bool) (
+func GetBlockTransactionsBySource(st sebakstorage.DBBackend, source string, reverse bool) (
 	func() (BlockTransaction, bool),
 	func(),
 ) {
-	iterFunc, closeFunc := st.GetIterator(GetBlockTransactionKeyPrefixSource(source), reverse)
+	iterFunc, closeFunc := BlockTransactionModel.GetIterator(st, BlockTransactionIndexSource, source, reverse)
 
-	return LoadBlockTransactionsInsideIterator(st, iterFunc, closeFunc)
+	return LoadBlockTransactionsInsideIterator(iterFunc, closeFunc)
 }
 
-func GetBlockTransactionsByConfirmed(st *sebakstorage.LevelDBBackend, reverse bool) (
+func GetBlockTransactionsByConfirmed(st sebakstorage.DBBackend, reverse bool) (
 	func() (BlockTransaction, bool),
 	func(),
 ) {
-	iterFunc, closeFunc := st.GetIterator(BlockTransactionPrefixConfirmed, reverse)
+	iterFunc, closeFunc := BlockTransactionModel.GetIterator(st, BlockTransactionIndexConfirmed, "", reverse)
 
-	return LoadBlockTransactionsInsideIterator(st, iterFunc, closeFunc)
+	return LoadBlockTransactionsInsideIterator(iterFunc, closeFunc)
 }
 
-func GetBlockTransactionsByAccount(st *sebakstorage.LevelDBBackend, accountAddress string, reverse bool) (
+func GetBlockTransactionsByAccount(st sebakstorage.DBBackend, accountAddress string, reverse bool) (
 	func() (BlockTransaction, bool),
 	func(),
 ) {
-	iterFunc, closeFunc := st.GetIterator(GetBlockTransactionKeyPrefixAccount(accountAddress), reverse)
-	return LoadBlockTransactionsInsideIterator(st, iterFunc, closeFunc)
+	iterFunc, closeFunc := BlockTransactionModel.GetIterator(st, BlockTransactionIndexAccount, accountAddress, reverse)
+	return LoadBlockTransactionsInsideIterator(iterFunc, closeFunc)
 }
 
 var GetBlockTransactions = GetBlockTransactionsByConfirmed
+
+// GetBlockTransactionsFromConfirmed iterates the transactions in the order of
+// `Confirmed` from `confirmed`; the transactions confirmed before it are not
+// read.
+func GetBlockTransactionsFromConfirmed(st sebakstorage.DBBackend, confirmed string) (
+	func() (BlockTransaction, bool),
+	func(),
+) {
+	iterFunc, closeFunc := BlockTransactionModel.GetIteratorRange(st, BlockTransactionIndexConfirmed, confirmed, "", false)
+
+	return LoadBlockTransactionsInsideIterator(iterFunc, closeFunc)
+}
+
+// GetBlockTransactionsBySourceFromConfirmed iterates the transactions of
+// `source` in the order of `Confirmed` from `confirmed`.
+func GetBlockTransactionsBySourceFromConfirmed(st sebakstorage.DBBackend, source, confirmed string) (
+	func() (BlockTransaction, bool),
+	func(),
+) {
+	iterFunc, closeFunc := BlockTransactionModel.GetIteratorFrom(
+		st,
+		BlockTransactionIndexSourceConfirmed,
+		makeConfirmedIndexValue(source, ""),
+		makeConfirmedIndexValue(source, confirmed),
+	)
+
+	return LoadBlockTransactionsInsideIterator(iterFunc, closeFunc)
+}
+
+func CountBlockTransactions(st sebakstorage.DBBackend) (int, error) {
+	return BlockTransactionModel.Count(st, BlockTransactionIndexConfirmed, "")
+}
+
+func CountBlockTransactionsBySource(st sebakstorage.DBBackend, source string) (int, error) {
+	return BlockTransactionModel.Count(st, BlockTransactionIndexSource, source)
+}
+
+func CountBlockTransactionsByAccount(st sebakstorage.DBBackend, accountAddress string) (int, error) {
+	return BlockTransactionModel.Count(st, BlockTransactionIndexAccount, accountAddress)
+}
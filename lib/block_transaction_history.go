@@ -1,8 +1,6 @@
 package sebak
 
 import (
-	"fmt"
-
 	"boscoin.io/sebak/lib/common"
 	"boscoin.io/sebak/lib/error"
 	"boscoin.io/sebak/lib/storage"
@@ -15,7 +13,18 @@ import (
 //  * sort by `Created`
 
 const (
-	BlockTransactionHistoryPrefixHash string = "bth-hash-" // bt-hash-<BlockTransactionHistory.Hash>
+	BlockTransactionHistoryIndexHash      string = "hash"      // bth-hash-<BlockTransactionHistory.Hash>
+	BlockTransactionHistoryIndexConfirmed string = "confirmed" // bth-confirmed-<BlockTransactionHistory.Confirmed>-<created>
+)
+
+var BlockTransactionHistoryModel = sebakstorage.NewModel(
+	"bth-",
+	sebakstorage.NewIndex(BlockTransactionHistoryIndexHash, true, func(v interface{}) []string {
+		return []string{v.(*BlockTransactionHistory).Hash}
+	}),
+	sebakstorage.NewIndex(BlockTransactionHistoryIndexConfirmed, false, func(v interface{}) []string {
+		return []string{v.(*BlockTransactionHistory).Confirmed}
+	}),
 )
 
 // TODO Is it correct to save raw `message` in BlockTransactionHistory?
@@ -42,22 +51,20 @@ func NewTransactionHistoryFromTransaction(tx Transaction, message []byte) BlockT
 }
 
 func GetBlockTransactionHistoryKey(hash string) string {
-	return fmt.Sprintf("%s%s", BlockTransactionHistoryPrefixHash, hash)
+	return BlockTransactionHistoryModel.Key(hash)
 }
 
 func (bt BlockTransactionHistory) Serialize() (encoded []byte, err error) {
 	encoded, err = sebakcommon.EncodeJSONValue(bt)
 	return
 }
-func (bt *BlockTransactionHistory) Save(st *sebakstorage.LevelDBBackend) (err error) {
+func (bt *BlockTransactionHistory) Save(st sebakstorage.DBBackend) (err error) {
 	if bt.isSaved {
 		return sebakerror.ErrorAlreadySaved
 	}
 
-	key := GetBlockTransactionHistoryKey(bt.Hash)
-
 	var exists bool
-	exists, err = st.Has(key)
+	exists, err = BlockTransactionHistoryModel.Has(st, bt.Hash)
 	if err != nil {
 		return
 	} else if exists {
@@ -65,7 +72,7 @@ func (bt *BlockTransactionHistory) Save(st *sebakstorage.LevelDBBackend) (err er
 	}
 
 	bt.Confirmed = sebakcommon.NowISO8601()
-	if err = st.New(GetBlockTransactionHistoryKey(bt.Hash), bt); err != nil {
+	if err = BlockTransactionHistoryModel.Save(st, bt); err != nil {
 		return
 	}
 
@@ -74,8 +81,8 @@ func (bt *BlockTransactionHistory) Save(st *sebakstorage.LevelDBBackend) (err er
 	return nil
 }
 
-func GetBlockTransactionHistory(st *sebakstorage.LevelDBBackend, hash string) (bt BlockTransactionHistory, err error) {
-	if err = st.Get(GetBlockTransactionHistoryKey(hash), &bt); err != nil {
+func GetBlockTransactionHistory(st sebakstorage.DBBackend, hash string) (bt BlockTransactionHistory, err error) {
+	if err = BlockTransactionHistoryModel.Get(st, hash, &bt); err != nil {
 		return
 	}
 
@@ -87,8 +94,77 @@ func GetBlockTransactionHistory(st *sebakstorage.LevelDBBackend, hash string) (b
 // the storage should support,
 //  * find by `Hash`
 
+const BlockTransactionErrorIndexHash string = "hash" // bte-hash-<BlockTransactionError.Hash>
+
+var BlockTransactionErrorModel = sebakstorage.NewModel(
+	"bte-",
+	sebakstorage.NewIndex(BlockTransactionErrorIndexHash, true, func(v interface{}) []string {
+		return []string{v.(*BlockTransactionError).Hash}
+	}),
+)
+
 type BlockTransactionError struct {
 	Hash string
 
+	State  sebakcommon.BallotState // `BallotState`, which the voting was closed
 	Reason string
+	Failed string
+}
+
+// NewBlockTransactionErrorFromStaging makes `BlockTransactionError` from the
+// closed voting; the reason is collected from the ballots of `VotingNO`.
+func NewBlockTransactionErrorFromStaging(vs VotingStateStaging) BlockTransactionError {
+	var reason string
+	if vs.Reason != nil {
+		reason = vs.Reason.Error()
+	} else {
+		for _, vrb := range vs.Ballots {
+			if vrb.VotingHole == VotingNO && len(vrb.Reason) > 0 {
+				reason = vrb.Reason
+				break
+			}
+		}
+	}
+	if len(reason) < 1 {
+		reason = sebakerror.ErrorTransactionFailed.Message
+	}
+
+	return BlockTransactionError{
+		Hash:   vs.MessageHash,
+		State:  vs.State,
+		Reason: reason,
+		Failed: sebakcommon.NowISO8601(),
+	}
+}
+
+func (bte BlockTransactionError) Serialize() (encoded []byte, err error) {
+	encoded, err = sebakcommon.EncodeJSONValue(bte)
+	return
+}
+
+// Save stores the `BlockTransactionError`; the same transaction can fail
+// again, so the previous one is overwritten.
+func (bte *BlockTransactionError) Save(st sebakstorage.DBBackend) (err error) {
+	var exists bool
+	if exists, err = BlockTransactionErrorModel.Has(st, bte.Hash); err != nil {
+		return
+	} else if !exists {
+		return BlockTransactionErrorModel.Save(st, bte)
+	}
+
+	batch := sebakstorage.NewBatch()
+	if err = BlockTransactionErrorModel.Update(st, batch, bte); err != nil {
+		return
+	}
+
+	return st.Write(batch)
+}
+
+func GetBlockTransactionError(st sebakstorage.DBBackend, hash string) (bte BlockTransactionError, err error) {
+	err = BlockTransactionErrorModel.Get(st, hash, &bte)
+	return
+}
+
+func ExistBlockTransactionError(st sebakstorage.DBBackend, hash string) (bool, error) {
+	return BlockTransactionErrorModel.Has(st, hash)
 }
@@ -329,3 +329,34 @@ func TestMultipleBlockTransactionGetByAccount(t *testing.T) {
 		}
 	}
 }
+
+func TestCountBlockTransactions(t *testing.T) {
+	kp, _ := keypair.Random()
+	kpAnother, _ := keypair.Random()
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	for i := 0; i < 3; i++ {
+		tx := TestMakeTransactionWithKeypair(networkID, 1, kp)
+		a, _ := tx.Serialize()
+		bt := NewBlockTransactionFromTransaction(tx, a)
+		require.Nil(t, bt.Save(st))
+	}
+	for i := 0; i < 2; i++ {
+		tx := TestMakeTransactionWithKeypair(networkID, 1, kpAnother)
+		a, _ := tx.Serialize()
+		bt := NewBlockTransactionFromTransaction(tx, a)
+		require.Nil(t, bt.Save(st))
+	}
+
+	n, err := CountBlockTransactions(st)
+	require.Nil(t, err)
+	require.Equal(t, 5, n)
+
+	n, err = CountBlockTransactionsBySource(st, kp.Address())
+	require.Nil(t, err)
+	require.Equal(t, 3, n)
+
+	n, err = CountBlockTransactionsByAccount(st, kpAnother.Address())
+	require.Nil(t, err)
+	require.Equal(t, 2, n)
+}
@@ -0,0 +1,102 @@
+package sebakclient
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"boscoin.io/sebak/lib"
+	"boscoin.io/sebak/lib/block"
+)
+
+func accountPath(pattern, address string) string {
+	return strings.Replace(pattern, "{address}", address, 1)
+}
+
+type AccountPage struct {
+	Records []block.BlockAccount `json:"records"`
+	Next    string               `json:"next"`
+}
+
+type AccountHistoryPage struct {
+	Records []sebak.BlockAccountHistory `json:"records"`
+	Next    string                      `json:"next"`
+}
+
+// GetAccounts returns the accounts by the created order.
+func (c *Client) GetAccounts(q PageQuery) (page AccountPage, err error) {
+	err = c.get(sebak.GetAccountsHandlerPattern, q.values(), &page)
+	return
+}
+
+// GetAccount returns the account; the `Checkpoint` of account is used for the
+// next transaction of it.
+func (c *Client) GetAccount(address string) (ba block.BlockAccount, err error) {
+	err = c.get(accountPath(sebak.GetAccountHandlerPattern, address), nil, &ba)
+	return
+}
+
+// GetAccountHistory returns the balance histories of account; with `daily`,
+// the closing balance of each day is returned.
+func (c *Client) GetAccountHistory(address string, q PageQuery, daily bool) (page AccountHistoryPage, err error) {
+	query := q.values()
+	if daily {
+		query.Set("bucket", "day")
+	}
+
+	err = c.get(accountPath(sebak.GetAccountHistoryHandlerPattern, address), query, &page)
+	return
+}
+
+// GetAccountTransactions returns the transactions, which the account is the
+// source or the target of operation.
+func (c *Client) GetAccountTransactions(address string) (bts []sebak.BlockTransaction, err error) {
+	err = c.get(accountPath(sebak.GetAccountTransactionsHandlerPattern, address), nil, &bts)
+	return
+}
+
+// GetAccountOperations returns the operations of account, which are selected
+// by `filter`.
+func (c *Client) GetAccountOperations(address string, filter sebak.BlockOperationFilter) (bos []sebak.BlockOperation, err error) {
+	err = c.get(accountPath(sebak.GetAccountOperationsHandlerPattern, address), operationFilterValues(filter), &bos)
+	return
+}
+
+// GetAccountOperationsWith returns the operations between two accounts in both
+// directions.
+func (c *Client) GetAccountOperationsWith(address, with string, filter sebak.BlockOperationFilter) (bos []sebak.BlockOperation, err error) {
+	path := strings.Replace(accountPath(sebak.GetAccountOperationsWithHandlerPattern, address), "{with}", with, 1)
+	err = c.get(path, operationFilterValues(filter), &bos)
+	return
+}
+
+// StreamAccount streams the account, whenever it is saved.
+func (c *Client) StreamAccount(address, lastID string) (*Stream, error) {
+	return c.stream(accountPath(sebak.GetAccountHandlerPattern, address), nil, lastID)
+}
+
+// StreamAccountTransactions streams the new transactions of account.
+func (c *Client) StreamAccountTransactions(address, lastID string) (*Stream, error) {
+	return c.stream(accountPath(sebak.GetAccountTransactionsHandlerPattern, address), nil, lastID)
+}
+
+// StreamAccountOperations streams the new operations of account, which are
+// selected by `filter`.
+func (c *Client) StreamAccountOperations(address string, filter sebak.BlockOperationFilter, lastID string) (*Stream, error) {
+	return c.stream(accountPath(sebak.GetAccountOperationsHandlerPattern, address), operationFilterValues(filter), lastID)
+}
+
+func operationFilterValues(filter sebak.BlockOperationFilter) url.Values {
+	values := url.Values{}
+	for _, t := range filter.Types {
+		values.Add("type", string(t))
+	}
+	if !filter.Since.IsZero() {
+		values.Set("since", filter.Since.Format(time.RFC3339Nano))
+	}
+	if !filter.Until.IsZero() {
+		values.Set("until", filter.Until.Format(time.RFC3339Nano))
+	}
+
+	return values
+}
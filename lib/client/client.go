@@ -0,0 +1,129 @@
+// Package sebakclient is the client of the node api, `/api`. The responses
+// are decoded to the types of `sebak` and the error responses are decoded to
+// `Error`.
+package sebakclient
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"boscoin.io/sebak/lib"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/network"
+)
+
+type Client struct {
+	endpoint *sebakcommon.Endpoint
+	client   *sebakcommon.HTTP2Client
+}
+
+// NewClient makes the client of node, which serves the api at `endpoint`.
+// Without `client`, the keep-alive client is used; it has no timeout, because
+// the streams and the transaction, which waits for confirmation are kept
+// long.
+func NewClient(endpoint *sebakcommon.Endpoint, client *sebakcommon.HTTP2Client) (c *Client, err error) {
+	if client == nil {
+		if client, err = sebakcommon.NewHTTP2Client(0, 0, true); err != nil {
+			return
+		}
+	}
+
+	c = &Client{endpoint: endpoint, client: client}
+	return
+}
+
+func (c *Client) Endpoint() *sebakcommon.Endpoint {
+	return c.endpoint
+}
+
+func (c *Client) Close() {
+	c.client.Close()
+}
+
+func (c *Client) resolvePath(path string, query url.Values) string {
+	u := (*url.URL)(c.endpoint).ResolveReference(&url.URL{Path: sebaknetwork.UrlPathPrefixAPI + path})
+	if len(query) > 0 {
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
+
+func (c *Client) get(path string, query url.Values, v interface{}) (err error) {
+	headers := http.Header{}
+	headers.Set("Accept", "application/json")
+
+	var response *http.Response
+	if response, err = c.client.Get(c.resolvePath(path, query), headers); err != nil {
+		return
+	}
+
+	return decodeResponse(response, v)
+}
+
+func (c *Client) post(path string, query url.Values, body []byte, v interface{}) (err error) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+
+	var response *http.Response
+	if response, err = c.client.Post(c.resolvePath(path, query), body, headers); err != nil {
+		return
+	}
+
+	return decodeResponse(response, v)
+}
+
+// decodeResponse decodes the body of successful response to `v`; the error
+// response is returned as `Error`.
+func decodeResponse(response *http.Response, v interface{}) (err error) {
+	defer response.Body.Close()
+
+	var body []byte
+	if body, err = ioutil.ReadAll(response.Body); err != nil {
+		return
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return newErrorFromResponse(response, body)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// PageQuery is the pagination of the list, which responds `sebak.Page`; the
+// `Next` of page is the `Cursor` of the next page.
+type PageQuery struct {
+	Cursor  string
+	Limit   int // `sebak.DefaultPageLimit` is used for 0
+	Reverse bool
+}
+
+func (q PageQuery) values() url.Values {
+	values := url.Values{}
+	if len(q.Cursor) > 0 {
+		values.Set("cursor", q.Cursor)
+	}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Reverse {
+		values.Set("order", "desc")
+	}
+
+	return values
+}
+
+// GetStats returns the totals of ledger.
+func (c *Client) GetStats() (stats sebak.LedgerStats, err error) {
+	err = c.get(sebak.GetLedgerStatsHandlerPattern, nil, &stats)
+	return
+}
+
+// GetNodeStatus returns the status of node and it's consensus.
+func (c *Client) GetNodeStatus() (status sebak.NodeStatus, err error) {
+	err = c.get(sebak.GetNodeStatusHandlerPattern, nil, &status)
+	return
+}
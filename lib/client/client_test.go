@@ -0,0 +1,342 @@
+package sebakclient
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib"
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+	"boscoin.io/sebak/lib/node"
+	"boscoin.io/sebak/lib/storage"
+)
+
+var networkID []byte = []byte("sebak-test-network")
+
+// testNode is the in-process node; the validators are connected by the
+// memory network and the api of first node is served by `Server`.
+type testNode struct {
+	NodeRunners []*sebak.NodeRunner
+	Server      *httptest.Server
+	Client      *Client
+}
+
+func newTestNode(t *testing.T, n int) *testNode {
+	var networks []*sebaknetwork.MemoryNetwork
+	var nodes []*sebaknode.LocalNode
+	for i := 0; i < n; i++ {
+		mn := sebaknetwork.NewMemoryNetwork()
+		kp, _ := keypair.Random()
+		localNode, _ := sebaknode.NewLocalNode(kp, mn.Endpoint(), "")
+		mn.SetContext(context.WithValue(context.Background(), "localNode", localNode))
+
+		networks = append(networks, mn)
+		nodes = append(nodes, localNode)
+	}
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j {
+				nodes[i].AddValidators(nodes[j].ConvertToValidator())
+			}
+		}
+	}
+
+	tn := &testNode{}
+	for i := 0; i < n; i++ {
+		p, _ := sebak.NewDefaultVotingThresholdPolicy(100, 30, 30)
+		p.SetValidators(n)
+		is, _ := sebak.NewISAAC(networkID, nodes[i], p)
+		st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+		nr := sebak.NewNodeRunner(string(networkID), nodes[i], p, networks[i], is, st)
+		tn.NodeRunners = append(tn.NodeRunners, nr)
+
+		go nr.Start()
+	}
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(100 * time.Millisecond) {
+		var connected int
+		for _, nr := range tn.NodeRunners {
+			if nr.ConnectionManager().CountConnected() == n-1 {
+				connected++
+			}
+		}
+		if connected == n {
+			break
+		}
+	}
+
+	nr := tn.NodeRunners[0]
+	st := nr.Storage()
+
+	router := mux.NewRouter()
+	api := router.PathPrefix(sebaknetwork.UrlPathPrefixAPI).Subrouter()
+	api.HandleFunc(sebak.GetAccountsHandlerPattern, sebak.GetAccountsHandler(st)).Methods("GET")
+	api.HandleFunc(sebak.GetAccountHandlerPattern, sebak.GetAccountHandler(st)).Methods("GET")
+	api.HandleFunc(sebak.GetAccountHistoryHandlerPattern, sebak.GetAccountHistoryHandler(st)).Methods("GET")
+	api.HandleFunc(sebak.GetAccountTransactionsHandlerPattern, sebak.GetAccountTransactionsHandler(st)).Methods("GET")
+	api.HandleFunc(sebak.GetAccountOperationsHandlerPattern, sebak.GetAccountOperationsHandler(st)).Methods("GET")
+	api.HandleFunc(sebak.GetAccountOperationsWithHandlerPattern, sebak.GetAccountOperationsWithHandler(st)).Methods("GET")
+	api.HandleFunc(sebak.GetTransactionsHandlerPattern, sebak.GetTransactionsHandler(st)).Methods("GET")
+	api.HandleFunc(sebak.GetTransactionByHashHandlerPattern, sebak.GetTransactionByHashHandler(st)).Methods("GET")
+	api.HandleFunc(sebak.GetOperationsHandlerPattern, sebak.GetOperationsHandler(st)).Methods("GET")
+	api.HandleFunc(sebak.GetLedgerStatsHandlerPattern, sebak.GetLedgerStatsHandler(st)).Methods("GET")
+	api.HandleFunc(sebak.WebSocketHandlerPattern, sebak.WebSocketHandler(nr.WebSocketHub(), nil)).Methods("GET")
+	api.HandleFunc(sebak.PostTransactionHandlerPattern, sebak.PostTransactionHandler(nr)).Methods("POST")
+	api.HandleFunc(sebak.GetTransactionStatusHandlerPattern, sebak.GetTransactionStatusHandler(nr)).Methods("GET")
+	api.HandleFunc(sebak.GetNodeStatusHandlerPattern, sebak.GetNodeStatusHandler(nr)).Methods("GET")
+
+	tn.Server = httptest.NewServer(router)
+
+	endpoint, err := sebakcommon.ParseEndpoint(tn.Server.URL)
+	require.Nil(t, err)
+	tn.Client, err = NewClient(endpoint, nil)
+	require.Nil(t, err)
+
+	return tn
+}
+
+func (tn *testNode) Close() {
+	tn.Client.Close()
+	tn.Server.Close()
+	for _, nr := range tn.NodeRunners {
+		nr.Stop()
+		nr.Storage().Close()
+	}
+	sebaknetwork.CleanUpMemoryNetwork()
+}
+
+// SaveAccount saves the account in all the nodes, like genesis.
+func (tn *testNode) SaveAccount(t *testing.T, address string, balance sebakcommon.Amount) {
+	for _, nr := range tn.NodeRunners {
+		ba := block.NewBlockAccount(address, balance, sebakcommon.MakeGenesisCheckpoint(networkID))
+		require.Nil(t, ba.Save(nr.Storage()))
+	}
+}
+
+// waitAccount waits until the balance of account is changed; the account is
+// committed after the transaction is confirmed.
+func waitAccount(t *testing.T, c *Client, address, balance string) (ba block.BlockAccount) {
+	var err error
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(50 * time.Millisecond) {
+		if ba, err = c.GetAccount(address); err == nil && ba.Balance == balance {
+			return
+		}
+	}
+	require.Nil(t, err)
+	require.Equal(t, balance, ba.Balance)
+
+	return
+}
+
+func TestClientTransactionBuilder(t *testing.T) {
+	tn := newTestNode(t, 3)
+	defer tn.Close()
+	c := tn.Client
+
+	kpSource, _ := keypair.Random()
+	kpTarget, _ := keypair.Random()
+	tn.SaveAccount(t, kpSource.Address(), sebakcommon.Amount(1000000))
+
+	{ // create account
+		result, err := c.NewTransactionBuilder(networkID, kpSource).
+			CreateAccount(kpTarget.Address(), sebakcommon.Amount(100000)).
+			Submit(true, 10*time.Second)
+		require.Nil(t, err)
+		require.Equal(t, sebak.TransactionStatusConfirmed, result.Status)
+
+		ba := waitAccount(t, c, kpTarget.Address(), "100000")
+		require.Equal(t, kpTarget.Address(), ba.Address)
+		waitAccount(t, c, kpSource.Address(), sebakcommon.Amount(1000000-100000).MustSub(sebak.BaseFee).String())
+	}
+
+	{ // payment with the new checkpoint of source
+		result, err := c.NewTransactionBuilder(networkID, kpSource).
+			Payment(kpTarget.Address(), sebakcommon.Amount(100)).
+			Submit(true, 10*time.Second)
+		require.Nil(t, err)
+		require.Equal(t, sebak.TransactionStatusConfirmed, result.Status)
+		waitAccount(t, c, kpTarget.Address(), "100100")
+
+		bt, err := c.GetTransaction(result.Hash)
+		require.Nil(t, err)
+		require.Equal(t, kpSource.Address(), bt.Source)
+		require.NotEmpty(t, bt.Confirmed)
+
+		status, err := c.GetTransactionStatus(result.Hash)
+		require.Nil(t, err)
+		require.Equal(t, sebak.TransactionStageConfirmed, status.Stage)
+	}
+
+	{ // lists
+		bts, err := c.GetAccountTransactions(kpSource.Address())
+		require.Nil(t, err)
+		require.Equal(t, 2, len(bts))
+
+		page, err := c.GetTransactions(PageQuery{Limit: 1})
+		require.Nil(t, err)
+		require.Equal(t, 1, len(page.Records))
+		require.NotEmpty(t, page.Next)
+
+		page, err = c.GetTransactions(PageQuery{Limit: 1, Cursor: page.Next})
+		require.Nil(t, err)
+		require.Equal(t, 1, len(page.Records))
+		require.Empty(t, page.Next)
+
+		bos, err := c.GetAccountOperationsWith(kpTarget.Address(), kpSource.Address(), sebak.BlockOperationFilter{})
+		require.Nil(t, err)
+		require.Equal(t, 2, len(bos))
+
+		bos, err = c.GetAccountOperations(kpSource.Address(), sebak.BlockOperationFilter{Types: []sebak.OperationType{sebak.OperationPayment}})
+		require.Nil(t, err)
+		require.Equal(t, 1, len(bos))
+
+		opPage, err := c.GetOperations(PageQuery{Reverse: true}, sebak.BlockOperationFilter{})
+		require.Nil(t, err)
+		require.Equal(t, 2, len(opPage.Records))
+		require.Equal(t, sebak.OperationType(sebak.OperationPayment), opPage.Records[0].Type)
+
+		accounts, err := c.GetAccounts(PageQuery{})
+		require.Nil(t, err)
+		require.Equal(t, 2, len(accounts.Records))
+
+		history, err := c.GetAccountHistory(kpTarget.Address(), PageQuery{}, false)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(history.Records))
+
+		stats, err := c.GetStats()
+		require.Nil(t, err)
+		require.Equal(t, uint64(2), stats.Transactions)
+		require.Equal(t, uint64(2), stats.Accounts)
+
+		status, err := c.GetNodeStatus()
+		require.Nil(t, err)
+		require.Equal(t, tn.NodeRunners[0].Node().Address(), status.Node.Address)
+		require.Equal(t, 2, len(status.Validators))
+	}
+
+	{ // same transaction is rejected
+		tx, err := c.NewTransactionBuilder(networkID, kpSource).
+			Payment(kpTarget.Address(), sebakcommon.Amount(100)).
+			Build()
+		require.Nil(t, err)
+
+		_, err = c.SubmitTransaction(tx, true, 10*time.Second)
+		require.Nil(t, err)
+
+		_, err = c.SubmitTransaction(tx, false, 0)
+		require.NotNil(t, err)
+		e, ok := err.(*Error)
+		require.True(t, ok)
+		require.True(t, e.Status >= 400)
+		require.Equal(t, tx.GetHash(), e.Data["hash"])
+		require.Equal(t, sebak.TransactionStatusRejected, e.Data["status"])
+	}
+}
+
+func TestClientError(t *testing.T) {
+	tn := newTestNode(t, 1)
+	defer tn.Close()
+	c := tn.Client
+
+	unknown, _ := keypair.Random()
+
+	_, err := c.GetAccount(unknown.Address())
+	require.True(t, IsNotFound(err))
+	require.True(t, IsErrorCode(err, sebakerror.ErrorBlockAccountDoesNotExists))
+	require.Equal(t, unknown.Address(), err.(*Error).Data["address"])
+	require.Equal(t, sebakerror.ErrorBlockAccountDoesNotExists.Code, err.(*Error).SebakError().Code)
+
+	_, err = c.GetTransaction("showme")
+	require.True(t, IsErrorCode(err, sebakerror.ErrorBlockTransactionDoesNotExists))
+
+	_, err = c.GetOperations(PageQuery{Cursor: "findme"}, sebak.BlockOperationFilter{Types: []sebak.OperationType{"showme"}})
+	require.True(t, IsErrorCode(err, sebakerror.ErrorUnknownOperationType))
+
+	// the transaction of unknown account is not built
+	_, err = c.NewTransactionBuilder(networkID, unknown).Payment(unknown.Address(), sebakcommon.Amount(100)).Build()
+	require.True(t, IsNotFound(err))
+
+	// invalid operation
+	kp, _ := keypair.Random()
+	_, err = c.NewTransactionBuilder(networkID, kp).Payment(unknown.Address(), sebakcommon.Amount(0)).Build()
+	require.NotNil(t, err)
+	_, ok := err.(*Error)
+	require.False(t, ok)
+}
+
+func TestClientStream(t *testing.T) {
+	tn := newTestNode(t, 1)
+	defer tn.Close()
+	c := tn.Client
+	st := tn.NodeRunners[0].Storage()
+
+	kp, _ := keypair.Random()
+	tn.SaveAccount(t, kp.Address(), sebakcommon.Amount(1000000))
+
+	{ // server-sent events; the current account is sent at first
+		s, err := c.StreamAccount(kp.Address(), "")
+		require.Nil(t, err)
+		defer s.Close()
+
+		e, err := s.Next()
+		require.Nil(t, err)
+		require.Equal(t, "account", e.Name)
+
+		var ba block.BlockAccount
+		require.Nil(t, e.Decode(&ba))
+		require.Equal(t, "1000000", ba.Balance)
+
+		require.Nil(t, ba.Withdraw(sebakcommon.Amount(100), "showme"))
+		require.Nil(t, ba.Save(st))
+
+		e, err = s.Next()
+		require.Nil(t, err)
+		require.Nil(t, e.Decode(&ba))
+		require.Equal(t, "999900", ba.Balance)
+	}
+
+	{ // unknown account
+		unknown, _ := keypair.Random()
+		_, err := c.StreamAccount(unknown.Address(), "")
+		require.True(t, IsNotFound(err))
+	}
+
+	{ // websocket
+		ws, err := c.WebSocket()
+		require.Nil(t, err)
+		defer ws.Close()
+
+		subscription, err := ws.Subscribe(sebak.WebSocketTopicAccount, map[string][]string{"address": []string{kp.Address()}})
+		require.Nil(t, err)
+		require.NotEmpty(t, subscription)
+
+		_, err = ws.Subscribe("showme", nil)
+		require.Equal(t, sebakerror.ErrorInvalidSubscription.Code, err.(*sebakerror.Error).Code)
+
+		other := block.TestMakeBlockAccount()
+		require.Nil(t, other.Save(st))
+
+		ba, err := c.GetAccount(kp.Address())
+		require.Nil(t, err)
+		require.Nil(t, ba.Save(st))
+
+		r, err := ws.Next()
+		require.Nil(t, err)
+		require.Equal(t, subscription, r.Subscription)
+
+		var received block.BlockAccount
+		require.Nil(t, received.Deserialize(r.Data))
+		require.Equal(t, kp.Address(), received.Address)
+
+		require.Nil(t, ws.Unsubscribe(subscription))
+	}
+}
@@ -0,0 +1,70 @@
+package sebakclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+// Error is the error response of node. The `sebaknetwork.Problem` of response
+// is decoded into it; for the response, which is not problem, like the
+// unknown path, it has only `Status`, `Title` and the body as `Detail`.
+type Error struct {
+	sebaknetwork.Problem
+}
+
+func newErrorFromResponse(response *http.Response, body []byte) *Error {
+	e := &Error{}
+	if strings.HasPrefix(response.Header.Get("Content-Type"), sebaknetwork.ProblemContentType) {
+		if err := json.Unmarshal(body, &e.Problem); err == nil && e.Status > 0 {
+			return e
+		}
+	}
+
+	e.Problem = sebaknetwork.NewProblem(response.StatusCode, nil)
+	e.Detail = strings.TrimSpace(string(body))
+
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Code > 0 {
+		return fmt.Sprintf("%d %s: code=%d: %s", e.Status, e.Title, e.Code, e.Detail)
+	}
+	if len(e.Detail) > 0 {
+		return fmt.Sprintf("%d %s: %s", e.Status, e.Title, e.Detail)
+	}
+
+	return fmt.Sprintf("%d %s", e.Status, e.Title)
+}
+
+// SebakError returns the `sebakerror.Error` of response; it is nil, when the
+// response has no code.
+func (e *Error) SebakError() *sebakerror.Error {
+	if e.Code < 1 {
+		return nil
+	}
+
+	return sebakerror.NewError(e.Code, e.Detail)
+}
+
+// HasCode checks the code of response is same with `target`.
+func (e *Error) HasCode(target *sebakerror.Error) bool {
+	return e.Code > 0 && target != nil && e.Code == target.Code
+}
+
+// IsErrorCode checks `err` is the `Error`, which has the code of `target`.
+func IsErrorCode(err error, target *sebakerror.Error) bool {
+	e, ok := err.(*Error)
+	return ok && e.HasCode(target)
+}
+
+// IsNotFound checks `err` is the `Error` of `404 Not Found`.
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.Status == http.StatusNotFound
+}
@@ -0,0 +1,104 @@
+package sebakclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Event is one event of `Server-Sent Events` stream. `ID` is used as the
+// `lastID` to resume the stream.
+type Event struct {
+	ID   string
+	Name string
+	Data []byte
+}
+
+// Decode decodes the data of event, like `block.BlockAccount` of the account
+// stream.
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// Stream reads the events of streaming endpoint; it is not safe for the
+// concurrent use.
+type Stream struct {
+	response *http.Response
+	reader   *bufio.Reader
+}
+
+// stream requests the streaming endpoint; with `lastID`, the stored events
+// after it are sent again by the node.
+func (c *Client) stream(path string, query url.Values, lastID string) (s *Stream, err error) {
+	headers := http.Header{}
+	headers.Set("Accept", "text/event-stream")
+	if len(lastID) > 0 {
+		headers.Set("Last-Event-ID", lastID)
+	}
+
+	var response *http.Response
+	if response, err = c.client.Get(c.resolvePath(path, query), headers); err != nil {
+		return
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+
+		var body []byte
+		if body, err = ioutil.ReadAll(response.Body); err != nil {
+			return
+		}
+		err = newErrorFromResponse(response, body)
+		return
+	}
+
+	s = &Stream{response: response, reader: bufio.NewReader(response.Body)}
+	return
+}
+
+// Next waits the next event; the keep-alive comments are skipped. After the
+// stream is closed by node or `Close`, it returns error.
+func (s *Stream) Next() (e Event, err error) {
+	var data [][]byte
+	for {
+		var line string
+		if line, err = s.reader.ReadString('\n'); err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if len(line) < 1 {
+			if len(data) < 1 && len(e.ID) < 1 {
+				continue
+			}
+			e.Data = bytes.Join(data, []byte("\n"))
+			return
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "id":
+			e.ID = value
+		case "event":
+			e.Name = value
+		case "data":
+			data = append(data, []byte(value))
+		}
+	}
+}
+
+func (s *Stream) Close() error {
+	return s.response.Body.Close()
+}
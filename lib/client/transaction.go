@@ -0,0 +1,161 @@
+package sebakclient
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib"
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+)
+
+func transactionPath(pattern, hash string) string {
+	return strings.Replace(pattern, "{txid}", hash, 1)
+}
+
+type TransactionPage struct {
+	Records []sebak.BlockTransaction `json:"records"`
+	Next    string                   `json:"next"`
+}
+
+type OperationPage struct {
+	Records []sebak.BlockOperation `json:"records"`
+	Next    string                 `json:"next"`
+}
+
+// GetTransactions returns the confirmed transactions by the confirmed order.
+func (c *Client) GetTransactions(q PageQuery) (page TransactionPage, err error) {
+	err = c.get(sebak.GetTransactionsHandlerPattern, q.values(), &page)
+	return
+}
+
+// GetTransaction returns the transaction; the transaction, which is received,
+// but not confirmed yet has no `Confirmed`.
+func (c *Client) GetTransaction(hash string) (bt sebak.BlockTransaction, err error) {
+	err = c.get(transactionPath(sebak.GetTransactionByHashHandlerPattern, hash), nil, &bt)
+	return
+}
+
+// GetTransactionStatus returns the stage of transaction in the node.
+func (c *Client) GetTransactionStatus(hash string) (status sebak.TransactionStatus, err error) {
+	err = c.get(transactionPath(sebak.GetTransactionStatusHandlerPattern, hash), nil, &status)
+	return
+}
+
+// GetOperations returns the operations by the confirmed order, which are
+// selected by `filter`.
+func (c *Client) GetOperations(q PageQuery, filter sebak.BlockOperationFilter) (page OperationPage, err error) {
+	query := q.values()
+	for k, v := range operationFilterValues(filter) {
+		query[k] = v
+	}
+
+	err = c.get(sebak.GetOperationsHandlerPattern, query, &page)
+	return
+}
+
+// StreamTransactions streams the new confirmed transactions.
+func (c *Client) StreamTransactions(lastID string) (*Stream, error) {
+	return c.stream(sebak.GetTransactionsHandlerPattern, nil, lastID)
+}
+
+// StreamTransaction streams the transaction, when it is confirmed.
+func (c *Client) StreamTransaction(hash string) (*Stream, error) {
+	return c.stream(transactionPath(sebak.GetTransactionByHashHandlerPattern, hash), nil, "")
+}
+
+// SubmitTransaction sends the signed transaction. With `wait`, it waits until
+// the transaction is confirmed or failed, or `timeout` is expired; 0 `timeout`
+// is `sebak.DefaultTransactionWaitTimeout` of node. The rejected transaction
+// is returned as `Error`.
+func (c *Client) SubmitTransaction(tx sebak.Transaction, wait bool, timeout time.Duration) (result sebak.TransactionSubmitResult, err error) {
+	var body []byte
+	if body, err = tx.Serialize(); err != nil {
+		return
+	}
+
+	query := url.Values{}
+	if wait {
+		query.Set("wait", "true")
+		if timeout > 0 {
+			query.Set("timeout", timeout.String())
+		}
+	}
+
+	err = c.post(sebak.PostTransactionHandlerPattern, query, body, &result)
+	return
+}
+
+// TransactionBuilder makes the signed transaction of the operations. The
+// checkpoint of source account is fetched from node, when it is built.
+type TransactionBuilder struct {
+	client    *Client
+	networkID []byte
+	source    *keypair.Full
+
+	operations []sebak.Operation
+	err        error
+}
+
+func (c *Client) NewTransactionBuilder(networkID []byte, source *keypair.Full) *TransactionBuilder {
+	return &TransactionBuilder{client: c, networkID: networkID, source: source}
+}
+
+func (b *TransactionBuilder) add(t sebak.OperationType, body sebak.OperationBody) *TransactionBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	var op sebak.Operation
+	if op, b.err = sebak.NewOperation(t, body); b.err != nil {
+		return b
+	}
+	b.operations = append(b.operations, op)
+
+	return b
+}
+
+// Payment adds the payment operation to the existing account.
+func (b *TransactionBuilder) Payment(target string, amount sebakcommon.Amount) *TransactionBuilder {
+	return b.add(sebak.OperationPayment, sebak.NewOperationBodyPayment(target, amount))
+}
+
+// CreateAccount adds the operation, which creates the new account with
+// `amount`.
+func (b *TransactionBuilder) CreateAccount(target string, amount sebakcommon.Amount) *TransactionBuilder {
+	return b.add(sebak.OperationCreateAccount, sebak.NewOperationBodyCreateAccount(target, amount))
+}
+
+// Build fetches the current checkpoint of source account and makes the signed
+// transaction; the invalid operation is returned as error.
+func (b *TransactionBuilder) Build() (tx sebak.Transaction, err error) {
+	if b.err != nil {
+		err = b.err
+		return
+	}
+
+	var ba block.BlockAccount
+	if ba, err = b.client.GetAccount(b.source.Address()); err != nil {
+		return
+	}
+
+	if tx, err = sebak.NewTransaction(b.source.Address(), ba.Checkpoint, b.operations...); err != nil {
+		return
+	}
+	tx.Sign(b.source, b.networkID)
+
+	return
+}
+
+// Submit builds the transaction and submits it, see `Client.SubmitTransaction`.
+func (b *TransactionBuilder) Submit(wait bool, timeout time.Duration) (result sebak.TransactionSubmitResult, err error) {
+	var tx sebak.Transaction
+	if tx, err = b.Build(); err != nil {
+		return
+	}
+
+	return b.client.SubmitTransaction(tx, wait, timeout)
+}
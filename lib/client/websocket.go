@@ -0,0 +1,125 @@
+package sebakclient
+
+import (
+	"crypto/tls"
+	"strconv"
+
+	"golang.org/x/net/websocket"
+
+	"boscoin.io/sebak/lib"
+	"boscoin.io/sebak/lib/error"
+)
+
+// WebSocket is the connection of `sebak.WebSocketHandler`, which receives the
+// events of subscriptions. It is not safe for the concurrent use.
+type WebSocket struct {
+	conn   *websocket.Conn
+	serial int
+
+	// events, which are received while waiting the response of request
+	events []sebak.WebSocketResponse
+}
+
+// WebSocket connects to the subscription endpoint of node. Like the default
+// client, the certificate of node is not verified.
+func (c *Client) WebSocket() (ws *WebSocket, err error) {
+	origin := c.resolvePath("", nil)
+	location := c.resolvePath(sebak.WebSocketHandlerPattern, nil)
+	switch c.endpoint.Scheme {
+	case "https":
+		location = "wss" + location[len("https"):]
+	default:
+		location = "ws" + location[len("http"):]
+	}
+
+	var config *websocket.Config
+	if config, err = websocket.NewConfig(location, origin); err != nil {
+		return
+	}
+	config.TlsConfig = &tls.Config{InsecureSkipVerify: true}
+
+	var conn *websocket.Conn
+	if conn, err = websocket.DialConfig(config); err != nil {
+		return
+	}
+
+	ws = &WebSocket{conn: conn}
+	return
+}
+
+// request sends the request and waits it's response; the events, which are
+// received before the response are kept for `Next`.
+func (ws *WebSocket) request(req sebak.WebSocketRequest) (r sebak.WebSocketResponse, err error) {
+	ws.serial++
+	req.ID = strconv.Itoa(ws.serial)
+
+	if err = websocket.JSON.Send(ws.conn, req); err != nil {
+		return
+	}
+
+	for {
+		if err = websocket.JSON.Receive(ws.conn, &r); err != nil {
+			return
+		}
+		if r.Type == sebak.WebSocketResponseEvent {
+			ws.events = append(ws.events, r)
+			continue
+		}
+		if r.ID != req.ID {
+			continue
+		}
+		if r.Type == sebak.WebSocketResponseError {
+			err = sebakerror.ErrorInvalidMessage
+			if r.Error != nil {
+				err = r.Error
+			}
+		}
+		return
+	}
+}
+
+// Subscribe subscribes the topic, like `sebak.WebSocketTopicTransaction`,
+// and returns the id of subscription; see `sebak.WebSocketTopicAccount` for
+// the filters of topic.
+func (ws *WebSocket) Subscribe(topic string, filter map[string][]string) (subscription string, err error) {
+	var r sebak.WebSocketResponse
+	if r, err = ws.request(sebak.WebSocketRequest{Action: sebak.WebSocketActionSubscribe, Topic: topic, Filter: filter}); err != nil {
+		return
+	}
+
+	subscription = r.Subscription
+	return
+}
+
+func (ws *WebSocket) Unsubscribe(subscription string) (err error) {
+	_, err = ws.request(sebak.WebSocketRequest{Action: sebak.WebSocketActionUnsubscribe, Subscription: subscription})
+	return
+}
+
+// Next waits the next event of subscriptions; `Data` of event is the record
+// of topic, like `sebak.BlockTransaction`.
+func (ws *WebSocket) Next() (r sebak.WebSocketResponse, err error) {
+	if len(ws.events) > 0 {
+		r, ws.events = ws.events[0], ws.events[1:]
+		return
+	}
+
+	for {
+		if err = websocket.JSON.Receive(ws.conn, &r); err != nil {
+			return
+		}
+		switch r.Type {
+		case sebak.WebSocketResponseEvent:
+			return
+		case sebak.WebSocketResponseError:
+			if r.ID == "" && r.Error != nil {
+				err = r.Error
+				return
+			}
+		}
+	}
+}
+
+func (ws *WebSocket) Close() error {
+	return ws.conn.Close()
+}
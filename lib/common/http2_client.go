@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"sync"
@@ -21,6 +23,12 @@ type HTTP2Client struct {
 }
 
 func NewHTTP2Client(timeout, idleTimeout time.Duration, keepAlive bool) (client *HTTP2Client, err error) {
+	return NewHTTP2ClientWithTLS(timeout, idleTimeout, keepAlive, &tls.Config{InsecureSkipVerify: true})
+}
+
+// NewHTTP2ClientWithTLS creates the client with the given `tls.Config`, which
+// can verify the server certificate and present the client certificate.
+func NewHTTP2ClientWithTLS(timeout, idleTimeout time.Duration, keepAlive bool, tlsConfig *tls.Config) (client *HTTP2Client, err error) {
 	if keepAlive {
 		timeout, idleTimeout = 0, 0
 	}
@@ -34,9 +42,7 @@ func NewHTTP2Client(timeout, idleTimeout time.Duration, keepAlive bool) (client
 	client = &HTTP2Client{}
 
 	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true,
-		},
+		TLSClientConfig:   tlsConfig,
 		IdleConnTimeout:   idleTimeout,
 		DisableKeepAlives: !keepAlive,
 		DialContext: func(ctx context.Context, network, addr string) (conn net.Conn, err error) {
@@ -96,20 +102,47 @@ func (c *HTTP2Client) Post(url string, b []byte, headers http.Header) (response
 	return
 }
 
-func HTTP2ClientPing(client *HTTP2Client, url string, interval time.Duration) (err error) {
+// PostStream posts the body, which is written after the request is sent; in
+// HTTP2, the response can be read while the body is being written.
+func (c *HTTP2Client) PostStream(url string, body io.Reader, headers http.Header) (response *http.Response, err error) {
+	var request *http.Request
+	if request, err = http.NewRequest("POST", url, body); err != nil {
+		return
+	}
+	request.Header = headers
 
-	do := func(url string) (err error) {
-		_, err = client.Get(url, nil)
+	if response, err = c.client.Do(request); err != nil {
 		return
 	}
+	return
+}
 
-	if err = do(url); err != nil {
+// HTTP2ClientPingOnce requests `url` once; it fails when the server does not
+// respond or responds with the error status.
+func HTTP2ClientPingOnce(client *HTTP2Client, url string, headers http.Header) (err error) {
+	var response *http.Response
+	if response, err = client.Get(url, headers); err != nil {
+		return
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode >= http.StatusBadRequest {
+		err = fmt.Errorf("ping failed: %s", response.Status)
+		return
+	}
+
+	return
+}
+
+func HTTP2ClientPing(client *HTTP2Client, url string, interval time.Duration) (err error) {
+	if err = HTTP2ClientPingOnce(client, url, nil); err != nil {
 		return
 	}
 
 	ticker := time.NewTicker(interval)
 	for _ = range ticker.C {
-		if err = do(url); err != nil {
+		if err = HTTP2ClientPingOnce(client, url, nil); err != nil {
 			return
 		}
 	}
@@ -8,5 +8,5 @@ type Message interface {
 	IsWellFormed([]byte) error
 	Equal(Message) bool
 	Source() string
-	// Validate(sebakstorage.LevelDBBackend) error
+	// Validate(sebakstorage.DBBackend) error
 }
@@ -1,6 +1,7 @@
 package sebakcommon
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,14 +17,50 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
+const ISO8601Layout string = "2006-01-02T15:04:05.000000000Z07:00"
+
 func NowISO8601() string {
-	return time.Now().Format("2006-01-02T15:04:05.000000000Z07:00")
+	return FormatISO8601(time.Now())
+}
+
+func FormatISO8601(t time.Time) string {
+	return t.Format(ISO8601Layout)
+}
+
+func ParseISO8601(s string) (time.Time, error) {
+	return time.Parse(ISO8601Layout, s)
 }
 
 func GetUniqueIDFromUUID() string {
 	return uuid.Must(uuid.NewV1(), nil).String()
 }
 
+// uuidEpochStart is the number of 100-nanosecond intervals between the start
+// of uuid time, 1582-10-15 and unix epoch.
+const uuidEpochStart uint64 = 122192928000000000
+
+// GetTimeFromUUID returns the created time of the uuid1 from
+// `GetUniqueIDFromUUID`.
+func GetTimeFromUUID(s string) (t time.Time, err error) {
+	var u uuid.UUID
+	if u, err = uuid.FromString(s); err != nil {
+		return
+	}
+	if u.Version() != uuid.V1 {
+		err = errors.New("not uuid1")
+		return
+	}
+
+	b := u.Bytes()
+	timestamp := uint64(binary.BigEndian.Uint32(b[0:4]))
+	timestamp |= uint64(binary.BigEndian.Uint16(b[4:6])) << 32
+	timestamp |= uint64(binary.BigEndian.Uint16(b[6:8])&0x0fff) << 48
+
+	t = time.Unix(0, int64(timestamp-uuidEpochStart)*100)
+
+	return
+}
+
 func GenerateUUID() string {
 	return uuid.Must(uuid.NewV4(), nil).String()
 }
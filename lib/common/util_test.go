@@ -0,0 +1,22 @@
+package sebakcommon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTimeFromUUID(t *testing.T) {
+	before := time.Now()
+	id := GetUniqueIDFromUUID()
+	after := time.Now()
+
+	created, err := GetTimeFromUUID(id)
+	require.Nil(t, err)
+	require.False(t, created.Before(before.Truncate(time.Microsecond)))
+	require.False(t, created.After(after))
+
+	_, err = GetTimeFromUUID(GenerateUUID())
+	require.NotNil(t, err)
+}
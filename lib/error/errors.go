@@ -36,4 +36,22 @@ var (
 	ErrorTransactionInvalidCheckpoint     = NewError(133, "invalid checkpoint found")
 	ErrorBlockTransactionDoesNotExists    = NewError(134, "transaction does not exists in block")
 	ErrorBlockOperationDoesNotExists      = NewError(135, "operation does not exists in block")
+	ErrorStorageRecordAlreadyExists       = NewError(136, "record already exists in storage")
+	ErrorReindexGenesisNotFound           = NewError(137, "genesis account not found")
+	ErrorReindexMessageNotFound           = NewError(138, "raw message of transaction not found; it may be pruned")
+	ErrorEnvelopeUnknownSender            = NewError(139, "envelope is not from the known validators")
+	ErrorEnvelopeNetworkIDMismatch        = NewError(140, "network id of envelope does not match")
+	ErrorEnvelopeExpired                  = NewError(141, "envelope is expired or from the future")
+	ErrorEnvelopeReplayed                 = NewError(142, "envelope was already received")
+	ErrorHandshakeNetworkIDMismatch       = NewError(143, "network id of handshake does not match")
+	ErrorHandshakeIncompatibleProtocol    = NewError(144, "protocol version of handshake is not supported")
+	ErrorHandshakeSenderMismatch          = NewError(145, "node of handshake is not the sender")
+	ErrorTransactionRejected              = NewError(146, "transaction was rejected")
+	ErrorTransactionFailed                = NewError(147, "transaction failed in consensus")
+	ErrorInvalidSubscription              = NewError(148, "unknown topic or filter of subscription")
+	ErrorTooManySubscriptions             = NewError(149, "too many subscriptions or filter values")
+	ErrorSubscriptionNotFound             = NewError(150, "subscription not found")
+	ErrorNodeStopped                      = NewError(151, "node is stopped")
+	ErrorReindexTargetNotEmpty            = NewError(152, "target storage of reindex is not empty")
+	ErrorTooManyStreamingEvents           = NewError(153, "too many events after Last-Event-ID; read them from the list api")
 )
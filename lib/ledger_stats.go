@@ -0,0 +1,94 @@
+package sebak
+
+import (
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/storage"
+)
+
+const BlockTransactionStatsKey string = "stats-transaction"
+
+// BlockTransactionStats is the totals of the confirmed transactions. It is
+// written with the `BlockTransaction` in the same batch of
+// `BlockTransaction.Save`. `Fees` is the decimal string of the collected fees.
+type BlockTransactionStats struct {
+	Transactions uint64
+	Operations   uint64
+	Fees         string
+}
+
+func GetBlockTransactionStats(st sebakstorage.DBBackend) (s BlockTransactionStats, err error) {
+	s.Fees = "0"
+
+	var exists bool
+	if exists, err = st.Has(BlockTransactionStatsKey); err != nil || !exists {
+		return
+	}
+	err = st.Get(BlockTransactionStatsKey, &s)
+
+	return
+}
+
+// putBlockTransactionStats adds the stats, which include `bt`, to `batch`.
+func putBlockTransactionStats(st sebakstorage.DBBackend, batch *sebakstorage.Batch, bt BlockTransaction) (err error) {
+	var s BlockTransactionStats
+	if s, err = GetBlockTransactionStats(st); err != nil {
+		return
+	}
+
+	s.Transactions++
+	s.Operations += uint64(len(bt.Operations))
+
+	// the fee is charged by operation
+	var fee sebakcommon.Amount
+	if fee, err = bt.Fee.MultInt(len(bt.Operations)); err != nil {
+		return
+	}
+	if s.Fees, err = block.AddBigAmount(s.Fees, fee.String()); err != nil {
+		return
+	}
+
+	var encoded []byte
+	if encoded, err = sebakcommon.EncodeJSONValue(s); err != nil {
+		return
+	}
+	batch.Put(BlockTransactionStatsKey, encoded)
+
+	return
+}
+
+// LedgerStats is the totals of ledger; see `block.BlockAccountStats` and
+// `BlockTransactionStats`.
+type LedgerStats struct {
+	Accounts          uint64 `json:"accounts"`
+	Transactions      uint64 `json:"transactions"`
+	Operations        uint64 `json:"operations"`
+	CirculatingSupply string `json:"circulating_supply"`
+	FeesCollected     string `json:"fees_collected"`
+}
+
+func (s LedgerStats) Serialize() ([]byte, error) {
+	return sebakcommon.EncodeJSONValue(s)
+}
+
+func GetLedgerStats(st sebakstorage.DBBackend) (s LedgerStats, err error) {
+	var as block.BlockAccountStats
+	if as, err = block.GetBlockAccountStats(st); err != nil {
+		return
+	}
+
+	var ts BlockTransactionStats
+	if ts, err = GetBlockTransactionStats(st); err != nil {
+		return
+	}
+
+	s = LedgerStats{
+		Accounts:          as.Accounts,
+		Transactions:      ts.Transactions,
+		Operations:        ts.Operations,
+		CirculatingSupply: as.Supply,
+		FeesCollected:     ts.Fees,
+	}
+
+	return
+}
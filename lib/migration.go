@@ -0,0 +1,285 @@
+package sebak
+
+import (
+	"encoding/json"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// Migration backfills the records, which were stored by the older node, so the
+// new indexes and the stats cover the existing history. The applied migration
+// is marked by `MigrationMarkerPrefix` and it's name, so it runs only once.
+type Migration struct {
+	Name string
+	Run  func(sebakstorage.DBBackend) error
+}
+
+const MigrationMarkerPrefix string = "migration-"
+
+// migrationBatchSize is the number of records, which are migrated at once.
+var migrationBatchSize int = 1000
+
+// Migrations are applied by the declared order.
+var Migrations = []Migration{
+	{Name: "bth-confirmed-index", Run: migrateBlockTransactionHistoryIndexes},
+	{Name: "bah-index", Run: migrateBlockAccountHistories},
+	{Name: "bo-confirmed-index", Run: migrateBlockOperationIndexes},
+	{Name: "ledger-stats", Run: migrateLedgerStats},
+	{Name: "bt-source-confirmed-index", Run: migrateBlockTransactionIndexes},
+	{Name: "bo-account-confirmed-index", Run: migrateBlockOperationIndexes},
+}
+
+// Migrate applies the `Migrations`, which are not applied yet; it should be
+// called after the storage is opened, before the node starts.
+func Migrate(st sebakstorage.DBBackend) (applied []string, err error) {
+	for _, m := range Migrations {
+		key := MigrationMarkerPrefix + m.Name
+
+		var exists bool
+		if exists, err = st.Has(key); err != nil {
+			return
+		} else if exists {
+			continue
+		}
+
+		if err = m.Run(st); err != nil {
+			return
+		}
+		if err = st.New(key, sebakcommon.NowISO8601()); err != nil {
+			return
+		}
+		applied = append(applied, m.Name)
+	}
+
+	return
+}
+
+// migrateModelIndexes writes the missing indexes of the records of `Model`.
+// `load` decodes the stored record.
+func migrateModelIndexes(st sebakstorage.DBBackend, m *sebakstorage.Model, load func([]byte) (interface{}, error)) (err error) {
+	iterFunc, closeFunc := st.GetIterator(m.Key(""), false)
+	defer closeFunc()
+
+	batch := sebakstorage.NewBatch()
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var record interface{}
+		if record, err = load(item.Value); err != nil {
+			return
+		}
+		if _, err = m.PutMissingIndexes(st, batch, record); err != nil {
+			return
+		}
+
+		if batch.Len() >= migrationBatchSize {
+			if err = st.Write(batch); err != nil {
+				return
+			}
+			batch = sebakstorage.NewBatch()
+		}
+	}
+
+	return st.Write(batch)
+}
+
+// migrateBlockTransactionHistoryIndexes indexes the `BlockTransactionHistory`
+// by `Confirmed`, which were stored before `Pruner`; without it, `Pruner`
+// never reaches them.
+func migrateBlockTransactionHistoryIndexes(st sebakstorage.DBBackend) error {
+	return migrateModelIndexes(st, BlockTransactionHistoryModel, func(b []byte) (interface{}, error) {
+		var bth BlockTransactionHistory
+		if err := json.Unmarshal(b, &bth); err != nil {
+			return nil, err
+		}
+		return &bth, nil
+	})
+}
+
+// migrateBlockTransactionIndexes indexes the `BlockTransaction`s by the
+// indexes, which were added after they had been stored.
+func migrateBlockTransactionIndexes(st sebakstorage.DBBackend) error {
+	return migrateModelIndexes(st, BlockTransactionModel, func(b []byte) (interface{}, error) {
+		var bt BlockTransaction
+		if err := json.Unmarshal(b, &bt); err != nil {
+			return nil, err
+		}
+		return &bt, nil
+	})
+}
+
+// migrateBlockAccountHistories indexes the balance histories of accounts by
+// `BlockAccountHistoryPrefix`, which were stored only as
+// `block.BlockAccountCheckpoint`; the confirmed time is found by the hash of
+// the last transaction in the checkpoint.
+func migrateBlockAccountHistories(st sebakstorage.DBBackend) (err error) {
+	accountIterFunc, accountCloseFunc := block.GetBlockAccountsByCreated(st, false)
+	defer accountCloseFunc()
+
+	batch := sebakstorage.NewBatch()
+	for {
+		ba, hasNext := accountIterFunc()
+		if !hasNext {
+			break
+		}
+
+		iterFunc, closeFunc := block.GetBlockAccountCheckpointByAddress(st, ba.Address, false)
+		for {
+			bac, hasNext := iterFunc()
+			if !hasNext {
+				break
+			}
+
+			var h BlockAccountHistory
+			if h, err = loadBlockAccountHistory(st, bac); err != nil {
+				closeFunc()
+				return
+			}
+
+			var exists bool
+			if exists, err = st.Has(h.Key()); err != nil {
+				closeFunc()
+				return
+			} else if exists || batch.Has(h.Key()) {
+				continue
+			}
+
+			var encoded []byte
+			if encoded, err = sebakcommon.EncodeJSONValue(h); err != nil {
+				closeFunc()
+				return
+			}
+			batch.Put(h.Key(), encoded)
+
+			if batch.Len() >= migrationBatchSize {
+				if err = st.Write(batch); err != nil {
+					closeFunc()
+					return
+				}
+				batch = sebakstorage.NewBatch()
+			}
+		}
+		closeFunc()
+	}
+
+	return st.Write(batch)
+}
+
+// loadBlockAccountHistory makes the history of the stored checkpoint; the
+// added part of checkpoint is the hash of the last transaction.
+func loadBlockAccountHistory(st sebakstorage.DBBackend, bac block.BlockAccountCheckpoint) (h BlockAccountHistory, err error) {
+	var parsed [2]string
+	if parsed, err = sebakcommon.ParseCheckpoint(bac.Checkpoint); err != nil {
+		return
+	}
+
+	ba := &block.BlockAccount{Address: bac.Address, Balance: bac.Balance, Checkpoint: bac.Checkpoint}
+
+	var found bool
+	if found, err = ExistBlockTransaction(st, parsed[1]); err != nil {
+		return
+	} else if !found {
+		h = NewBlockAccountHistory(ba, nil)
+		return
+	}
+
+	var bt BlockTransaction
+	if bt, err = GetBlockTransaction(st, parsed[1]); err != nil {
+		return
+	}
+	h = NewBlockAccountHistory(ba, &bt)
+
+	return
+}
+
+// migrateBlockOperationIndexes indexes the `BlockOperation`s by `Confirmed`
+// and the other indexes, which were added after they had been stored. The operation without `Confirmed` gets
+// it from it's transaction and is saved again.
+func migrateBlockOperationIndexes(st sebakstorage.DBBackend) error {
+	return migrateModelIndexes(st, BlockOperationModel, func(b []byte) (interface{}, error) {
+		var bo BlockOperation
+		if err := json.Unmarshal(b, &bo); err != nil {
+			return nil, err
+		}
+		if len(bo.Confirmed) < 1 {
+			bt, err := GetBlockTransaction(st, bo.TxHash)
+			if err != nil {
+				return nil, err
+			}
+			bo.Confirmed = bt.Confirmed
+			if err = st.Set(BlockOperationModel.Key(bo.Hash), bo); err != nil {
+				return nil, err
+			}
+		}
+		return &bo, nil
+	})
+}
+
+// migrateLedgerStats counts `block.BlockAccountStats` and
+// `BlockTransactionStats` from the stored accounts and transactions; the
+// stats were updated only by the records, which were saved after them.
+func migrateLedgerStats(st sebakstorage.DBBackend) (err error) {
+	as := block.BlockAccountStats{Supply: "0"}
+
+	accountIterFunc, accountCloseFunc := block.GetBlockAccountsByCreated(st, false)
+	for {
+		ba, hasNext := accountIterFunc()
+		if !hasNext {
+			break
+		}
+
+		as.Accounts++
+		if as.Supply, err = block.AddBigAmount(as.Supply, ba.Balance); err != nil {
+			accountCloseFunc()
+			return
+		}
+	}
+	accountCloseFunc()
+
+	ts := BlockTransactionStats{Fees: "0"}
+
+	iterFunc, closeFunc := st.GetIterator(BlockTransactionModel.Key(""), false)
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var bt BlockTransaction
+		if err = json.Unmarshal(item.Value, &bt); err != nil {
+			closeFunc()
+			return
+		}
+
+		ts.Transactions++
+		ts.Operations += uint64(len(bt.Operations))
+
+		// the fee is charged by operation
+		var fee sebakcommon.Amount
+		if fee, err = bt.Fee.MultInt(len(bt.Operations)); err != nil {
+			closeFunc()
+			return
+		}
+		if ts.Fees, err = block.AddBigAmount(ts.Fees, fee.String()); err != nil {
+			closeFunc()
+			return
+		}
+	}
+	closeFunc()
+
+	batch := sebakstorage.NewBatch()
+	for key, s := range map[string]interface{}{block.BlockAccountStatsKey: as, BlockTransactionStatsKey: ts} {
+		var encoded []byte
+		if encoded, err = sebakcommon.EncodeJSONValue(s); err != nil {
+			return
+		}
+		batch.Put(key, encoded)
+	}
+
+	return st.Write(batch)
+}
@@ -0,0 +1,201 @@
+package sebak
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestMigrateBlockTransactionHistoryIndexes(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	// the history, which was stored before `Pruner`
+	_, tx := TestMakeTransaction(networkID, 1)
+	bth := NewTransactionHistoryFromTransaction(tx, sebakcommon.MustJSONMarshal(tx))
+	require.Nil(t, st.New(GetBlockTransactionHistoryKey(bth.Hash), bth))
+
+	pruner := NewPruner(st, PrunePolicy{Window: time.Hour, Interval: time.Hour})
+	result, err := pruner.Prune(time.Now().Add(time.Hour * 2))
+	require.Nil(t, err)
+	require.Equal(t, 0, result.TransactionHistories)
+
+	applied, err := Migrate(st)
+	require.Nil(t, err)
+	require.Equal(t, []string{"bth-confirmed-index", "bah-index", "bo-confirmed-index", "ledger-stats", "bt-source-confirmed-index", "bo-account-confirmed-index"}, applied)
+
+	// applied once
+	applied, err = Migrate(st)
+	require.Nil(t, err)
+	require.Empty(t, applied)
+
+	result, err = pruner.Prune(time.Now().Add(time.Hour * 2))
+	require.Nil(t, err)
+	require.Equal(t, 1, result.TransactionHistories)
+
+	_, err = GetBlockTransactionHistory(st, bth.Hash)
+	require.Equal(t, sebakerror.ErrorStorageRecordDoesNotExist, err)
+}
+
+func TestMigrateBlockAccountHistories(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	// the checkpoints, which were stored before `BlockAccountHistory`
+	kp, _ := keypair.Random()
+	ba := block.NewBlockAccount(kp.Address(), sebakcommon.Amount(10000), sebakcommon.MakeGenesisCheckpoint(networkID))
+	require.Nil(t, ba.Save(st))
+
+	tx := TestMakeTransactionWithKeypair(networkID, 1, kp)
+	bt := NewBlockTransactionFromTransaction(tx, nil)
+	require.Nil(t, bt.Save(st))
+	require.Nil(t, ba.Withdraw(sebakcommon.Amount(100), tx.NextSourceCheckpoint()))
+	require.Nil(t, ba.Save(st))
+
+	_, err := Migrate(st)
+	require.Nil(t, err)
+
+	var histories []BlockAccountHistory
+	iterFunc, closeFunc := GetBlockAccountHistory(st, ba.Address, "", false)
+	for {
+		h, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		histories = append(histories, h)
+	}
+	closeFunc()
+
+	require.Equal(t, 2, len(histories))
+	require.Equal(t, "10000", histories[0].Balance)
+	require.Empty(t, histories[0].Confirmed)
+	require.Equal(t, bt.Hash, histories[1].Hash)
+	require.Equal(t, bt.Confirmed, histories[1].Confirmed)
+	require.Equal(t, ba.Balance, histories[1].Balance)
+}
+
+func TestMigrateLedgerStatsAndBlockOperationIndexes(t *testing.T) {
+	st, _, _ := makeReindexTestStorage(t)
+	defer st.Close()
+
+	expected, err := GetLedgerStats(st)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), expected.Transactions)
+
+	// the stats and the confirmed index of operations, which were not stored
+	// by the older node
+	require.Nil(t, st.Remove(block.BlockAccountStatsKey))
+	require.Nil(t, st.Remove(BlockTransactionStatsKey))
+
+	prefix := BlockOperationModel.IndexPrefix(BlockOperationIndexConfirmed, "")
+	iterFunc, closeFunc := GetBlockOperationsFromConfirmed(st, "")
+	for {
+		bo, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var keys, kept []string
+		indexesKey := BlockOperationModel.Prefix + "indexes-" + bo.Hash
+		require.Nil(t, st.Get(indexesKey, &keys))
+		for _, key := range keys {
+			if strings.HasPrefix(key, prefix) {
+				require.Nil(t, st.Remove(key))
+				continue
+			}
+			kept = append(kept, key)
+		}
+		require.Nil(t, st.Set(indexesKey, kept))
+	}
+	closeFunc()
+
+	stats, err := GetLedgerStats(st)
+	require.Nil(t, err)
+	require.Equal(t, uint64(0), stats.Transactions)
+
+	_, err = Migrate(st)
+	require.Nil(t, err)
+
+	stats, err = GetLedgerStats(st)
+	require.Nil(t, err)
+	require.Equal(t, expected, stats)
+
+	var n int
+	iterFunc, closeFunc = GetBlockOperationsFromConfirmed(st, "")
+	for {
+		if _, hasNext := iterFunc(); !hasNext {
+			break
+		}
+		n++
+	}
+	closeFunc()
+	require.Equal(t, 2, n)
+}
+
+func TestMigrateBlockOperationIndexesBaselineLayout(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	kpSource, _ := keypair.Random()
+	kpTarget, _ := keypair.Random()
+
+	op, _ := NewOperation(
+		OperationPayment,
+		OperationBodyPayment{Target: kpTarget.Address(), Amount: sebakcommon.Amount(100)},
+	)
+	tx, _ := NewTransaction(kpSource.Address(), sebakcommon.MakeGenesisCheckpoint(networkID), op)
+	tx.Sign(kpSource, networkID)
+
+	// the transaction and the operation, which were stored by the older node;
+	// the operation has the record and the index keys without `Confirmed` and
+	// 'bo-indexes-<hash>'
+	bt := NewBlockTransactionFromTransaction(tx, sebakcommon.MustJSONMarshal(tx))
+	bt.Confirmed = sebakcommon.NowISO8601()
+	require.Nil(t, st.New(BlockTransactionModel.Key(bt.Hash), bt))
+
+	bo := NewBlockOperationFromOperation(op, tx)
+	require.Nil(t, st.New(BlockOperationModel.Key(bo.Hash), bo))
+	for name, value := range map[string]string{
+		BlockOperationIndexTxHash: bo.TxHash,
+		BlockOperationIndexSource: bo.Source,
+		BlockOperationIndexTarget: bo.Target,
+	} {
+		key := BlockOperationModel.IndexPrefix(name, value) + sebakcommon.GetUniqueIDFromUUID()
+		require.Nil(t, st.New(key, bo.Hash))
+	}
+
+	_, err := Migrate(st)
+	require.Nil(t, err)
+
+	// the stored index keys are not written again
+	for name, value := range map[string]string{
+		BlockOperationIndexTxHash: bo.TxHash,
+		BlockOperationIndexSource: bo.Source,
+		BlockOperationIndexTarget: bo.Target,
+		BlockOperationIndexPeers:  blockOperationPeers(bo.Source, bo.Target),
+	} {
+		n, err := BlockOperationModel.Count(st, name, value)
+		require.Nil(t, err)
+		require.Equal(t, 1, n, name)
+	}
+
+	migrated, err := GetBlockOperation(st, bo.Hash)
+	require.Nil(t, err)
+	require.Equal(t, bt.Confirmed, migrated.Confirmed)
+
+	iterFunc, closeFunc := GetBlockOperationsFromConfirmed(st, "")
+	defer closeFunc()
+	found, hasNext := iterFunc()
+	require.True(t, hasNext)
+	require.Equal(t, bo.Hash, found.Hash)
+	_, hasNext = iterFunc()
+	require.False(t, hasNext)
+}
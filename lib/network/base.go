@@ -8,7 +8,6 @@ import (
 	"net/http"
 
 	"boscoin.io/sebak/lib/common"
-	"boscoin.io/sebak/lib/node"
 )
 
 type Network interface {
@@ -40,6 +39,13 @@ func NewNetwork(endpoint *sebakcommon.Endpoint) (n Network, err error) {
 			return
 		}
 		n = NewHTTP2Network(config)
+	case StreamNetworkScheme:
+		var config HTTP2NetworkConfig
+		config, err = NewHTTP2NetworkConfigFromEndpoint(endpoint)
+		if err != nil {
+			return
+		}
+		n = NewStreamNetwork(config)
 	}
 
 	return
@@ -48,10 +54,14 @@ func NewNetwork(endpoint *sebakcommon.Endpoint) (n Network, err error) {
 type NetworkClient interface {
 	Endpoint() *sebakcommon.Endpoint
 
-	Connect(node sebaknode.Node) ([]byte, error)
+	Connect(Handshake) ([]byte, error)
+	Ping() error
 	GetNodeInfo() ([]byte, error)
 	SendMessage(sebakcommon.Serializable) ([]byte, error)
 	SendBallot(sebakcommon.Serializable) ([]byte, error)
+	SendBallots([]sebakcommon.Serializable) ([]byte, error)
+	ExchangePeers(sebakcommon.Serializable) ([]byte, error)
+	SendGossip(sebakcommon.Serializable) ([]byte, error)
 }
 
 type MessageType string
@@ -65,6 +75,7 @@ const (
 	ConnectMessage                 = "connect"
 	BallotMessage                  = "ballot"
 	GetNodeInfoMessage             = "get-node-info"
+	GossipMessage                  = "gossip"
 )
 
 // TODO versioning
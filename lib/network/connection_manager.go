@@ -4,6 +4,8 @@ import (
 	"errors"
 	"net"
 	"net/http"
+	"net/url"
+	"sort"
 	"sync"
 	"time"
 
@@ -12,6 +14,19 @@ import (
 	logging "github.com/inconshreveable/log15"
 )
 
+var (
+	// DefaultPingInterval is the period of checking the connected validators.
+	DefaultPingInterval time.Duration = 5 * time.Second
+	// DefaultPingMaxFailures is the number of the continuous ping failures,
+	// after which the validator is marked as disconnected.
+	DefaultPingMaxFailures int = 3
+
+	// DefaultReconnectMin and DefaultReconnectMax bound the backoff of
+	// reconnecting to the disconnected validator.
+	DefaultReconnectMin time.Duration = 1 * time.Second
+	DefaultReconnectMax time.Duration = 1 * time.Minute
+)
+
 type ConnectionManager struct {
 	sync.Mutex
 
@@ -22,8 +37,19 @@ type ConnectionManager struct {
 	validators map[ /* nodd.Address() */ string]*sebaknode.Validator
 	clients    map[ /* nodd.Address() */ string]NetworkClient
 	connected  map[ /* nodd.Address() */ string]bool
+	queues     map[ /* nodd.Address() */ string]*outboundQueue
+	health     map[ /* nodd.Address() */ string]*ValidatorHealth
+	handshakes map[ /* nodd.Address() */ string]Handshake
+	checks     map[ /* nodd.Address() */ string]chan struct{}
 
-	log logging.Logger
+	pingInterval    time.Duration
+	pingMaxFailures int
+	reconnectMin    time.Duration
+	reconnectMax    time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	log      logging.Logger
 }
 
 func NewConnectionManager(
@@ -39,9 +65,20 @@ func NewConnectionManager(
 		policy:     policy,
 		validators: validators,
 
-		clients:   map[string]NetworkClient{},
-		connected: map[string]bool{},
-		log:       log.New(logging.Ctx{"node": localNode.Alias()}),
+		clients:    map[string]NetworkClient{},
+		connected:  map[string]bool{},
+		queues:     map[string]*outboundQueue{},
+		health:     map[string]*ValidatorHealth{},
+		handshakes: map[string]Handshake{},
+		checks:     map[string]chan struct{}{},
+
+		pingInterval:    DefaultPingInterval,
+		pingMaxFailures: DefaultPingMaxFailures,
+		reconnectMin:    DefaultReconnectMin,
+		reconnectMax:    DefaultReconnectMax,
+
+		stop: make(chan struct{}),
+		log:  log.New(logging.Ctx{"node": localNode.Alias()}),
 	}
 }
 
@@ -83,7 +120,9 @@ func (c *ConnectionManager) setConnected(v *sebaknode.Validator, connected bool)
 
 	_, ok := c.connected[v.Address()]
 	if !connected {
+		// the new handshake will be made at reconnecting
 		delete(c.connected, v.Address())
+		delete(c.handshakes, v.Address())
 		return ok
 	}
 	if ok {
@@ -99,6 +138,14 @@ func (c *ConnectionManager) CountConnected() int {
 	return len(c.connected)
 }
 
+func (c *ConnectionManager) IsConnected(address string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	_, found := c.connected[address]
+	return found
+}
+
 func (c *ConnectionManager) connectValidators() {
 	c.log.Debug("> starting to connect to validators", "validators", c.validators)
 	for _, v := range c.validators {
@@ -106,39 +153,166 @@ func (c *ConnectionManager) connectValidators() {
 	}
 }
 
+// connectingValidator keeps the connection to the validator. The connected
+// validator is pinged periodically, and after `pingMaxFailures` failures it
+// is marked as disconnected; the disconnected one is reconnected with the
+// exponential backoff.
 func (c *ConnectionManager) connectingValidator(v *sebaknode.Validator) {
-	ticker := time.NewTicker(time.Second * 1)
-	for _ = range ticker.C {
-		err := c.connectValidator(v)
-		if err != nil {
-			c.log.Error("failed to connect", "validator", v, "error", err)
-			continue
-		}
+	check := c.checkChannel(v.Address())
+	backoff := c.reconnectMin
+
+	for {
+		wait := c.pingInterval
+		if c.IsConnected(v.Address()) {
+			c.pingValidator(v)
+		} else if err := c.connectValidator(v); err != nil {
+			c.log.Error("failed to connect", "validator", v, "error", err, "retry", backoff)
+			c.updateHealth(v, err, 0)
 
-		if c.setConnected(v, err == nil) {
-			if err == nil {
+			wait = backoff
+			if backoff *= 2; backoff > c.reconnectMax {
+				backoff = c.reconnectMax
+			}
+		} else {
+			backoff = c.reconnectMin
+			if c.setConnected(v, true) {
 				c.log.Debug("validator is connected", "validator", v)
-			} else {
-				c.log.Debug("validator is disconnected", "validator", v)
 			}
 		}
+
+		select {
+		case <-time.After(wait):
+		case <-check:
+		case <-c.stop:
+			return
+		}
 	}
+}
 
-	return
+func (c *ConnectionManager) pingValidator(v *sebaknode.Validator) {
+	var err error
+	started := time.Now()
+
+	client := c.GetConnection(v.Address())
+	if client == nil {
+		err = errors.New("failed to create client")
+	} else {
+		err = client.Ping()
+	}
+
+	if failures := c.updateHealth(v, err, time.Since(started)); failures < c.pingMaxFailures {
+		return
+	}
+
+	if c.setConnected(v, false) {
+		c.log.Debug("validator is disconnected", "validator", v, "error", err)
+	}
+
+	// the new client will be made at reconnecting
+	c.Lock()
+	delete(c.clients, v.Address())
+	c.Unlock()
+}
+
+func (c *ConnectionManager) checkChannel(address string) chan struct{} {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, found := c.checks[address]; !found {
+		c.checks[address] = make(chan struct{}, 1)
+	}
+
+	return c.checks[address]
+}
+
+// updateHealth records the result of connecting or ping and returns the
+// number of the continuous failures.
+func (c *ConnectionManager) updateHealth(v *sebaknode.Validator, err error, latency time.Duration) int {
+	c.Lock()
+	defer c.Unlock()
+
+	h, found := c.health[v.Address()]
+	if !found {
+		h = &ValidatorHealth{Address: v.Address(), Endpoint: v.Endpoint().String()}
+		c.health[v.Address()] = h
+	}
+
+	if err != nil {
+		h.Failures++
+		h.LastError = err.Error()
+		return h.Failures
+	}
+
+	h.Failures = 0
+	h.LastError = ""
+	h.Latency = latency
+	h.LastSeen = time.Now()
+
+	return 0
+}
+
+// Health returns the liveness of all the validators.
+func (c *ConnectionManager) Health() []ValidatorHealth {
+	c.Lock()
+	defer c.Unlock()
+
+	var hs []ValidatorHealth
+	for address, v := range c.validators {
+		h := ValidatorHealth{Address: address, Endpoint: v.Endpoint().String()}
+		if found, ok := c.health[address]; ok {
+			h = *found
+		}
+
+		h.State = ValidatorStateDisconnected
+		if _, connected := c.connected[address]; connected {
+			h.State = ValidatorStateConnected
+		}
+		if handshake, found := c.handshakes[address]; found {
+			h.ProtocolVersion = handshake.ProtocolVersion
+			h.Version = handshake.Version
+		}
+		hs = append(hs, h)
+	}
+
+	sort.Slice(hs, func(i, j int) bool { return hs[i].Address < hs[j].Address })
+
+	return hs
 }
 
 func (c *ConnectionManager) connectValidator(v *sebaknode.Validator) (err error) {
 	client := c.GetConnection(v.Address())
+	if client == nil {
+		err = errors.New("failed to create client")
+		return
+	}
+
+	networkID := c.networkID()
+
+	var h Handshake
+	if h, err = NewHandshake(c.localNode, networkID); err != nil {
+		return
+	}
+
+	started := time.Now()
 
 	var b []byte
-	b, err = client.Connect(c.localNode)
+	b, err = client.Connect(h)
 	if err != nil {
 		return
 	}
+	latency := time.Since(started)
 
 	// load and check validator info; addresses are same?
+	var peer Handshake
+	if peer, err = NewHandshakeFromJSON(b); err != nil {
+		return
+	}
+	if err = peer.Check(networkID); err != nil {
+		return
+	}
+
 	var validator *sebaknode.Validator
-	validator, err = sebaknode.NewValidatorFromString(b)
+	validator, err = peer.Validator()
 	if err != nil {
 		return
 	}
@@ -147,24 +321,154 @@ func (c *ConnectionManager) connectValidator(v *sebaknode.Validator) (err error)
 		return
 	}
 
+	c.Lock()
+	c.handshakes[v.Address()] = peer
+	c.Unlock()
+
+	c.log.Debug(
+		"handshake with validator",
+		"validator", v.Address(),
+		"protocol", peer.ProtocolVersion,
+		"version", peer.Version,
+		"capabilities", peer.Capabilities,
+	)
+	c.updateHealth(v, nil, latency)
+
 	return
 }
 
-func (c *ConnectionManager) ConnectionWatcher(t Network, conn net.Conn, state http.ConnState) {
+func (c *ConnectionManager) networkID() (networkID []byte) {
+	if ctx := c.network.Context(); ctx != nil {
+		networkID, _ = ctx.Value("networkID").([]byte)
+	}
+
 	return
 }
 
+// Supports checks the validator supports the capability by the last
+// handshake.
+func (c *ConnectionManager) Supports(address, capability string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	h, found := c.handshakes[address]
+	return found && h.Supports(capability)
+}
+
+// SupportedByAll checks all the validators support the capability; the newer
+// features must be enabled only when it is `true`.
+func (c *ConnectionManager) SupportedByAll(capability string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	for address, _ := range c.validators {
+		h, found := c.handshakes[address]
+		if !found || !h.Supports(capability) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ConnectionWatcher checks the validators at once, when the connection from
+// their hosts is closed.
+func (c *ConnectionManager) ConnectionWatcher(t Network, conn net.Conn, state http.ConnState) {
+	if state != http.StateClosed && state != http.StateHijacked {
+		return
+	}
+
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return
+	}
+
+	// the watcher runs in it's own goroutine, so the validators are collected
+	// under lock; `checkChannel` takes the lock again.
+	var addresses []string
+	c.Lock()
+	for address, v := range c.validators {
+		if (*url.URL)(v.Endpoint()).Hostname() == host {
+			addresses = append(addresses, address)
+		}
+	}
+	c.Unlock()
+
+	for _, address := range addresses {
+		select {
+		case c.checkChannel(address) <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Broadcast puts the message into the outbound queues of the connected
+// validators; each queue delivers it in order and retries on failure.
 func (c *ConnectionManager) Broadcast(message sebakcommon.Message) {
+	c.Lock()
+	var queues []*outboundQueue
 	for addr, _ := range c.connected {
-		go func(v *sebaknode.Validator) {
-			if v == nil {
-				panic("Validator connected but not registered")
-			}
+		v, found := c.validators[addr]
+		if !found {
+			panic("Validator connected but not registered")
+		}
+		queues = append(queues, c.outboundQueue(v))
+	}
+	c.Unlock()
+
+	for _, q := range queues {
+		q.Push(message)
+	}
+}
 
-			client := c.GetConnection(v.Address())
-			if _, err := client.SendBallot(message); err != nil {
-				c.log.Error("failed to SendBallot", "error", err, "validator", v)
+func (c *ConnectionManager) outboundQueue(v *sebaknode.Validator) *outboundQueue {
+	if q, found := c.queues[v.Address()]; found {
+		return q
+	}
+
+	address := v.Address()
+	q := newOutboundQueue(address, DefaultOutboundQueueSize, func(ms []sebakcommon.Message) (err error) {
+		client := c.GetConnection(address)
+		if client == nil {
+			return errors.New("failed to create client")
+		}
+
+		// the older validator does not support the batched ballots
+		if !c.Supports(address, CapabilityBallots) {
+			for _, m := range ms {
+				if _, err = client.SendBallot(m); err != nil {
+					return
+				}
 			}
-		}(c.validators[addr])
+			return
+		}
+
+		messages := make([]sebakcommon.Serializable, len(ms))
+		for i, m := range ms {
+			messages[i] = m
+		}
+
+		_, err = client.SendBallots(messages)
+		return
+	})
+	q.Start()
+	c.queues[address] = q
+
+	return q
+}
+
+// Stop stops connecting to the validators and the outbound queues; the
+// waiting messages are dropped.
+func (c *ConnectionManager) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+
+	c.Lock()
+	defer c.Unlock()
+
+	for address, q := range c.queues {
+		q.Stop()
+		delete(c.queues, address)
 	}
 }
@@ -0,0 +1,124 @@
+package sebaknetwork
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/node"
+)
+
+type testPolicy struct {
+	sebakcommon.VotingThresholdPolicy
+	connected int
+}
+
+func (p *testPolicy) SetConnected(n int) error {
+	p.connected = n
+	return nil
+}
+
+func waitConnected(cm *ConnectionManager, address string, connected bool) bool {
+	for i := 0; i < 100; i++ {
+		if cm.IsConnected(address) == connected {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return false
+}
+
+func TestConnectionManagerHealth(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	_, mn0, node0 := createNewMemoryNetwork()
+	_, mn1, node1 := createNewMemoryNetwork()
+	node0.AddValidators(node1.ConvertToValidator())
+
+	policy := &testPolicy{}
+	cm := NewConnectionManager(node0, mn0, policy, node0.GetValidators())
+	cm.pingInterval = 10 * time.Millisecond
+	cm.reconnectMin = 10 * time.Millisecond
+	cm.reconnectMax = 20 * time.Millisecond
+	cm.Start()
+	defer cm.Stop()
+
+	require.True(t, waitConnected(cm, node1.Address(), true))
+	require.Equal(t, 1, policy.connected)
+
+	hs := cm.Health()
+	require.Equal(t, 1, len(hs))
+	require.Equal(t, node1.Address(), hs[0].Address)
+	require.Equal(t, ValidatorStateConnected, hs[0].State)
+	require.False(t, hs[0].LastSeen.IsZero())
+	require.Equal(t, ProtocolVersion, hs[0].ProtocolVersion)
+	require.True(t, cm.Supports(node1.Address(), CapabilityBallots))
+	require.True(t, cm.SupportedByAll(CapabilityBallots))
+
+	// node1 goes down
+	removeMemoryNetwork(mn1)
+
+	require.True(t, waitConnected(cm, node1.Address(), false))
+	require.Equal(t, 0, policy.connected)
+
+	hs = cm.Health()
+	require.Equal(t, ValidatorStateDisconnected, hs[0].State)
+	require.True(t, hs[0].Failures >= DefaultPingMaxFailures)
+
+	// the handshake of disconnected validator is forgotten
+	require.Equal(t, 0, hs[0].ProtocolVersion)
+	require.False(t, cm.Supports(node1.Address(), CapabilityBallots))
+	require.False(t, cm.SupportedByAll(CapabilityBallots))
+
+	// node1 comes back
+	addMemoryNetwork(mn1)
+
+	require.True(t, waitConnected(cm, node1.Address(), true))
+	require.Equal(t, 1, policy.connected)
+	require.Equal(t, 0, cm.Health()[0].Failures)
+	require.True(t, cm.Supports(node1.Address(), CapabilityBallots))
+}
+
+func TestConnectionManagerHealthNotConnected(t *testing.T) {
+	_, mn0, node0 := createNewMemoryNetwork()
+	validator, _ := sebaknode.NewValidator(node0.Address(), CreateNewMemoryEndpoint(), "")
+
+	cm := NewConnectionManager(node0, mn0, &testPolicy{}, map[string]*sebaknode.Validator{validator.Address(): validator})
+
+	hs := cm.Health()
+	require.Equal(t, 1, len(hs))
+	require.Equal(t, ValidatorStateDisconnected, hs[0].State)
+	require.True(t, hs[0].LastSeen.IsZero())
+}
+
+func TestConnectionManagerHandshakeNetworkIDMismatch(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	_, mn0, node0 := createNewMemoryNetwork()
+	_, mn1, node1 := createNewMemoryNetwork()
+	node0.AddValidators(node1.ConvertToValidator())
+
+	// node1 is in the other network
+	ctx := context.WithValue(context.Background(), "localNode", node1)
+	mn1.SetContext(context.WithValue(ctx, "networkID", []byte("other-network")))
+
+	cm := NewConnectionManager(node0, mn0, &testPolicy{}, node0.GetValidators())
+	cm.reconnectMin = 10 * time.Millisecond
+	cm.reconnectMax = 20 * time.Millisecond
+	cm.Start()
+	defer cm.Stop()
+
+	for i := 0; i < 100 && cm.Health()[0].Failures < 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	hs := cm.Health()
+	require.Equal(t, ValidatorStateDisconnected, hs[0].State)
+	require.Equal(t, sebakerror.ErrorHandshakeNetworkIDMismatch.Error(), hs[0].LastError)
+	require.False(t, cm.SupportedByAll(CapabilityBallots))
+}
@@ -0,0 +1,162 @@
+package sebaknetwork
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+// DefaultEnvelopeMaxClockSkew is the allowed difference between the created
+// time of `Envelope` and the local time.
+var DefaultEnvelopeMaxClockSkew time.Duration = time.Minute
+
+// Envelope wraps the message between the nodes. The sender signs the header
+// and the body together, so the receiving node can check who sent it before
+// the body reaches `NodeRunner`.
+type Envelope struct {
+	H EnvelopeHeader  `json:"H"`
+	B json.RawMessage `json:"B"`
+}
+
+type EnvelopeHeader struct {
+	Sender    string `json:"sender"`
+	NetworkID string `json:"network_id"`
+	Created   string `json:"created"`
+	Nonce     string `json:"nonce"`
+	Signature string `json:"signature"`
+}
+
+func NewEnvelope(kp *keypair.Full, networkID []byte, body []byte) (e Envelope, err error) {
+	e = Envelope{
+		H: EnvelopeHeader{
+			Sender:    kp.Address(),
+			NetworkID: string(networkID),
+			Created:   sebakcommon.NowISO8601(),
+			Nonce:     sebakcommon.GenerateUUID(),
+		},
+		B: json.RawMessage(body),
+	}
+
+	var signature []byte
+	if signature, err = kp.Sign(e.signingBytes()); err != nil {
+		return
+	}
+	e.H.Signature = base58.Encode(signature)
+
+	return
+}
+
+func NewEnvelopeFromJSON(b []byte) (e Envelope, err error) {
+	err = json.Unmarshal(b, &e)
+	return
+}
+
+// signingBytes does not hash the body; ed25519 hashes the whole message by
+// itself, so the cheap signature check can be done before the expensive
+// checks of body.
+func (e Envelope) signingBytes() []byte {
+	return bytes.Join(
+		[][]byte{
+			[]byte(e.H.NetworkID),
+			[]byte(e.H.Sender),
+			[]byte(e.H.Created),
+			[]byte(e.H.Nonce),
+			e.B,
+		},
+		[]byte{0},
+	)
+}
+
+func (e Envelope) Serialize() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func (e Envelope) VerifySignature() (err error) {
+	var kp keypair.KP
+	if kp, err = keypair.Parse(e.H.Sender); err != nil {
+		return sebakerror.ErrorSignatureVerificationFailed
+	}
+
+	if err = kp.Verify(e.signingBytes(), base58.Decode(e.H.Signature)); err != nil {
+		return sebakerror.ErrorSignatureVerificationFailed
+	}
+
+	return
+}
+
+// EnvelopeVerifier checks the received `Envelope`s and rejects the replayed
+// ones. The nonces are kept only for `MaxClockSkew`, because the older
+// envelopes are rejected by the created time.
+type EnvelopeVerifier struct {
+	sync.Mutex
+
+	networkID    []byte
+	maxClockSkew time.Duration
+	isAllowed    func(address string) bool
+
+	nonces  map[ /* Sender + Nonce */ string]time.Time
+	expired time.Time
+}
+
+func NewEnvelopeVerifier(networkID []byte, maxClockSkew time.Duration, isAllowed func(string) bool) *EnvelopeVerifier {
+	return &EnvelopeVerifier{
+		networkID:    networkID,
+		maxClockSkew: maxClockSkew,
+		isAllowed:    isAllowed,
+		nonces:       map[string]time.Time{},
+	}
+}
+
+func (v *EnvelopeVerifier) Verify(e Envelope, now time.Time) (err error) {
+	if e.H.NetworkID != string(v.networkID) {
+		return sebakerror.ErrorEnvelopeNetworkIDMismatch
+	}
+	if !v.isAllowed(e.H.Sender) {
+		return sebakerror.ErrorEnvelopeUnknownSender
+	}
+
+	var created time.Time
+	if created, err = sebakcommon.ParseISO8601(e.H.Created); err != nil {
+		return sebakerror.ErrorEnvelopeExpired
+	}
+	if skew := now.Sub(created); skew > v.maxClockSkew || skew < -v.maxClockSkew {
+		return sebakerror.ErrorEnvelopeExpired
+	}
+
+	if err = e.VerifySignature(); err != nil {
+		return
+	}
+
+	v.Lock()
+	defer v.Unlock()
+
+	v.expireNonces(now)
+
+	key := e.H.Sender + e.H.Nonce
+	if _, found := v.nonces[key]; found {
+		return sebakerror.ErrorEnvelopeReplayed
+	}
+	v.nonces[key] = created
+
+	return
+}
+
+func (v *EnvelopeVerifier) expireNonces(now time.Time) {
+	if now.Sub(v.expired) < time.Second {
+		return
+	}
+	v.expired = now
+
+	for key, created := range v.nonces {
+		if now.Sub(created) > v.maxClockSkew {
+			delete(v.nonces, key)
+		}
+	}
+}
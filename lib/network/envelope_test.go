@@ -0,0 +1,91 @@
+package sebaknetwork
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+var testEnvelopeNetworkID = []byte("sebak-test-network")
+
+func newTestEnvelopeVerifier(allowed ...string) *EnvelopeVerifier {
+	return NewEnvelopeVerifier(
+		testEnvelopeNetworkID,
+		DefaultEnvelopeMaxClockSkew,
+		func(address string) bool {
+			for _, a := range allowed {
+				if a == address {
+					return true
+				}
+			}
+			return false
+		},
+	)
+}
+
+func TestEnvelopeSign(t *testing.T) {
+	kp, _ := keypair.Random()
+
+	e, err := NewEnvelope(kp, testEnvelopeNetworkID, []byte(`{"findme":1}`))
+	require.Nil(t, err)
+	require.Nil(t, e.VerifySignature())
+
+	b, err := e.Serialize()
+	require.Nil(t, err)
+
+	parsed, err := NewEnvelopeFromJSON(b)
+	require.Nil(t, err)
+	require.Nil(t, parsed.VerifySignature())
+	require.Equal(t, `{"findme":1}`, string(parsed.B))
+
+	// tampered body
+	parsed.B = []byte(`{"findme":2}`)
+	require.Equal(t, sebakerror.ErrorSignatureVerificationFailed, parsed.VerifySignature())
+}
+
+func TestEnvelopeVerifier(t *testing.T) {
+	kp, _ := keypair.Random()
+	kpUnknown, _ := keypair.Random()
+
+	v := newTestEnvelopeVerifier(kp.Address())
+
+	e, _ := NewEnvelope(kp, testEnvelopeNetworkID, []byte(`{}`))
+	require.Nil(t, v.Verify(e, time.Now()))
+
+	// replayed
+	require.Equal(t, sebakerror.ErrorEnvelopeReplayed, v.Verify(e, time.Now()))
+
+	// not from validators
+	e, _ = NewEnvelope(kpUnknown, testEnvelopeNetworkID, []byte(`{}`))
+	require.Equal(t, sebakerror.ErrorEnvelopeUnknownSender, v.Verify(e, time.Now()))
+
+	// other network
+	e, _ = NewEnvelope(kp, []byte("other-network"), []byte(`{}`))
+	require.Equal(t, sebakerror.ErrorEnvelopeNetworkIDMismatch, v.Verify(e, time.Now()))
+
+	// too old or from the future
+	e, _ = NewEnvelope(kp, testEnvelopeNetworkID, []byte(`{}`))
+	require.Equal(t, sebakerror.ErrorEnvelopeExpired, v.Verify(e, time.Now().Add(DefaultEnvelopeMaxClockSkew*2)))
+	require.Equal(t, sebakerror.ErrorEnvelopeExpired, v.Verify(e, time.Now().Add(-DefaultEnvelopeMaxClockSkew*2)))
+
+	// forged sender
+	e, _ = NewEnvelope(kpUnknown, testEnvelopeNetworkID, []byte(`{}`))
+	e.H.Sender = kp.Address()
+	require.Equal(t, sebakerror.ErrorSignatureVerificationFailed, v.Verify(e, time.Now()))
+}
+
+func TestEnvelopeVerifierExpireNonces(t *testing.T) {
+	kp, _ := keypair.Random()
+	v := newTestEnvelopeVerifier(kp.Address())
+
+	e, _ := NewEnvelope(kp, testEnvelopeNetworkID, []byte(`{}`))
+	require.Nil(t, v.Verify(e, time.Now()))
+	require.Equal(t, 1, len(v.nonces))
+
+	v.expireNonces(time.Now().Add(DefaultEnvelopeMaxClockSkew * 2))
+	require.Equal(t, 0, len(v.nonces))
+}
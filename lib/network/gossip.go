@@ -0,0 +1,147 @@
+package sebaknetwork
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stellar/go/keypair"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+const (
+	GossipTransaction string = "transaction"
+	GossipConfirmed   string = "confirmed"
+)
+
+var (
+	// DefaultGossipTTL is the maximum hops of `Gossip`.
+	DefaultGossipTTL int = 5
+	// DefaultGossipFanout is the number of peers, which one node relays the
+	// `Gossip` to.
+	DefaultGossipFanout int = 4
+	// DefaultGossipSeenSize is the number of the recent `Gossip` ids, which
+	// are kept to drop the duplicated ones.
+	DefaultGossipSeenSize int = 10000
+)
+
+// Gossip is relayed between peers until it's `TTL` runs out. `ID` is the
+// hash of payload, so the same payload from the different nodes is relayed
+// only once. The origin signs the gossip except `TTL`, which is decreased by
+// the relaying peers, so `Origin` can be trusted after `VerifySignature`.
+type Gossip struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id"`
+	Origin    string          `json:"origin"` // address of node, which starts the gossip
+	TTL       int             `json:"ttl"`
+	Data      json.RawMessage `json:"data"`
+	Signature string          `json:"signature"`
+}
+
+func NewGossip(gossipType, id string, kp *keypair.Full, networkID []byte, data []byte) (g Gossip, err error) {
+	g = Gossip{
+		Type:   gossipType,
+		ID:     id,
+		Origin: kp.Address(),
+		TTL:    DefaultGossipTTL,
+		Data:   json.RawMessage(data),
+	}
+
+	var signature []byte
+	if signature, err = kp.Sign(g.signingBytes(networkID)); err != nil {
+		return
+	}
+	g.Signature = base58.Encode(signature)
+
+	return
+}
+
+func NewGossipFromJSON(b []byte) (g Gossip, err error) {
+	err = json.Unmarshal(b, &g)
+	return
+}
+
+func (g Gossip) Serialize() ([]byte, error) {
+	return json.Marshal(g)
+}
+
+func (g Gossip) String() string {
+	o, _ := json.Marshal(g)
+	return string(o)
+}
+
+// signingBytes uses the compacted `Data`; `Data` is compacted, when it is
+// encoded.
+func (g Gossip) signingBytes(networkID []byte) []byte {
+	data := new(bytes.Buffer)
+	if err := json.Compact(data, g.Data); err != nil {
+		data = bytes.NewBuffer(g.Data)
+	}
+
+	return bytes.Join(
+		[][]byte{
+			networkID,
+			[]byte(g.Type),
+			[]byte(g.ID),
+			[]byte(g.Origin),
+			data.Bytes(),
+		},
+		[]byte{0},
+	)
+}
+
+// VerifySignature checks the gossip was signed by `Origin`.
+func (g Gossip) VerifySignature(networkID []byte) (err error) {
+	var kp keypair.KP
+	if kp, err = keypair.Parse(g.Origin); err != nil {
+		return sebakerror.ErrorSignatureVerificationFailed
+	}
+
+	if err = kp.Verify(g.signingBytes(networkID), base58.Decode(g.Signature)); err != nil {
+		return sebakerror.ErrorSignatureVerificationFailed
+	}
+
+	return
+}
+
+func (g Gossip) key() string {
+	return g.Type + "-" + g.ID
+}
+
+// gossipSeen is the bounded set of the recent `Gossip`s; the oldest one is
+// forgotten first.
+type gossipSeen struct {
+	sync.Mutex
+
+	size  int
+	keys  []string
+	index map[string]struct{}
+}
+
+func newGossipSeen(size int) *gossipSeen {
+	return &gossipSeen{
+		size:  size,
+		index: map[string]struct{}{},
+	}
+}
+
+// add returns `false` if `key` was already seen.
+func (s *gossipSeen) add(key string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, found := s.index[key]; found {
+		return false
+	}
+
+	if len(s.keys) >= s.size {
+		delete(s.index, s.keys[0])
+		s.keys = s.keys[1:]
+	}
+	s.keys = append(s.keys, key)
+	s.index[key] = struct{}{}
+
+	return true
+}
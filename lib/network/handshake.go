@@ -0,0 +1,152 @@
+package sebaknetwork
+
+import (
+	"context"
+	"encoding/json"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/node"
+	"boscoin.io/sebak/lib/version"
+)
+
+// ProtocolVersion is the version of the messages between nodes; it must be
+// increased when the messages are changed incompatibly. The node accepts the
+// peers from `MinProtocolVersion` to `MaxProtocolVersion`, so the older nodes
+// can be upgraded one by one; the newer node must keep talking with the older
+// protocol until all the nodes are upgraded.
+const (
+	ProtocolVersion    int = 1
+	MinProtocolVersion int = 1
+	MaxProtocolVersion int = 1
+)
+
+// The capabilities are the message types, which node can handle. The newer
+// message types must be used only when the peers support them.
+const (
+	CapabilityBallot   string = "ballot"
+	CapabilityBallots  string = "ballots"
+	CapabilityGossip   string = "gossip"
+	CapabilityDiscover string = "discover"
+)
+
+var Capabilities = []string{
+	CapabilityBallot,
+	CapabilityBallots,
+	CapabilityGossip,
+	CapabilityDiscover,
+}
+
+// Handshake is exchanged by `Connect`; it has the node information with the
+// protocol version and the capabilities of node.
+type Handshake struct {
+	ProtocolVersion int             `json:"protocol_version"`
+	NetworkID       string          `json:"network_id"`
+	Capabilities    []string        `json:"capabilities"`
+	Version         string          `json:"version"`
+	Node            json.RawMessage `json:"node"`
+}
+
+func NewHandshake(node sebaknode.Node, networkID []byte) (h Handshake, err error) {
+	var b []byte
+	if b, err = node.Serialize(); err != nil {
+		return
+	}
+
+	h = Handshake{
+		ProtocolVersion: ProtocolVersion,
+		NetworkID:       string(networkID),
+		Capabilities:    Capabilities,
+		Version:         version.Version,
+		Node:            b,
+	}
+
+	return
+}
+
+func NewHandshakeFromJSON(b []byte) (h Handshake, err error) {
+	err = json.Unmarshal(b, &h)
+	return
+}
+
+func (h Handshake) Serialize() ([]byte, error) {
+	return json.Marshal(h)
+}
+
+func (h Handshake) String() string {
+	o, _ := json.MarshalIndent(h, "", "  ")
+	return string(o)
+}
+
+func (h Handshake) Validator() (*sebaknode.Validator, error) {
+	return sebaknode.NewValidatorFromString(h.Node)
+}
+
+// Check checks the peer of handshake can talk with local node. The legacy
+// node, which does not know the handshake answers with the empty one, so it is
+// checked before the network id.
+func (h Handshake) Check(networkID []byte) (err error) {
+	if h.ProtocolVersion < 1 || len(h.Node) < 1 {
+		err = sebakerror.ErrorHandshakeIncompatibleProtocol
+		return
+	}
+	if h.NetworkID != string(networkID) {
+		err = sebakerror.ErrorHandshakeNetworkIDMismatch
+		return
+	}
+	if h.ProtocolVersion < MinProtocolVersion || h.ProtocolVersion > MaxProtocolVersion {
+		err = sebakerror.ErrorHandshakeIncompatibleProtocol
+		return
+	}
+
+	return
+}
+
+func (h Handshake) Supports(capability string) bool {
+	for _, c := range h.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+
+	return false
+}
+
+// acceptHandshake checks the handshake from the connecting node, `sender`,
+// and returns the handshake of local node.
+func acceptHandshake(ctx context.Context, sender string, b []byte) (h Handshake, local []byte, err error) {
+	if h, err = NewHandshakeFromJSON(b); err != nil {
+		return
+	}
+
+	var networkID []byte
+	var localNode sebaknode.Node
+	if ctx != nil {
+		networkID, _ = ctx.Value("networkID").([]byte)
+		localNode, _ = ctx.Value("localNode").(sebaknode.Node)
+	}
+	if localNode == nil {
+		err = sebakerror.ErrorInvalidState
+		return
+	}
+
+	if err = h.Check(networkID); err != nil {
+		return
+	}
+
+	var v *sebaknode.Validator
+	if v, err = h.Validator(); err != nil {
+		return
+	}
+	if len(sender) > 0 && v.Address() != sender {
+		err = sebakerror.ErrorHandshakeSenderMismatch
+		return
+	}
+
+	var lh Handshake
+	if lh, err = NewHandshake(localNode, networkID); err != nil {
+		return
+	}
+	local, err = lh.Serialize()
+
+	return
+}
@@ -0,0 +1,66 @@
+package sebaknetwork
+
+import (
+	"testing"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/node"
+)
+
+func TestHandshakeCheck(t *testing.T) {
+	kp, _ := keypair.Random()
+	localNode, _ := sebaknode.NewLocalNode(kp, CreateNewMemoryEndpoint(), "")
+
+	networkID := []byte("sebak-test-network")
+	h, err := NewHandshake(localNode, networkID)
+	require.Nil(t, err)
+	require.Nil(t, h.Check(networkID))
+	require.Equal(t, sebakerror.ErrorHandshakeNetworkIDMismatch, h.Check([]byte("other-network")))
+
+	b, _ := h.Serialize()
+	returned, err := NewHandshakeFromJSON(b)
+	require.Nil(t, err)
+
+	v, err := returned.Validator()
+	require.Nil(t, err)
+	require.Equal(t, localNode.Address(), v.Address())
+}
+
+func TestHandshakeCheckProtocolVersion(t *testing.T) {
+	kp, _ := keypair.Random()
+	localNode, _ := sebaknode.NewLocalNode(kp, CreateNewMemoryEndpoint(), "")
+
+	networkID := []byte("sebak-test-network")
+	h, _ := NewHandshake(localNode, networkID)
+
+	older := h
+	older.ProtocolVersion = MinProtocolVersion - 1
+	require.Equal(t, sebakerror.ErrorHandshakeIncompatibleProtocol, older.Check(networkID))
+
+	newer := h
+	newer.ProtocolVersion = MaxProtocolVersion + 1
+	require.Equal(t, sebakerror.ErrorHandshakeIncompatibleProtocol, newer.Check(networkID))
+
+	// the legacy node answers with the empty handshake
+	legacy, err := NewHandshakeFromJSON([]byte("{}"))
+	require.Nil(t, err)
+	require.Equal(t, sebakerror.ErrorHandshakeIncompatibleProtocol, legacy.Check(networkID))
+}
+
+func TestHandshakeSupports(t *testing.T) {
+	kp, _ := keypair.Random()
+	localNode, _ := sebaknode.NewLocalNode(kp, CreateNewMemoryEndpoint(), "")
+
+	h, _ := NewHandshake(localNode, nil)
+	for _, c := range Capabilities {
+		require.True(t, h.Supports(c))
+	}
+
+	// the older node, which does not support the batched ballots
+	h.Capabilities = []string{CapabilityBallot}
+	require.True(t, h.Supports(CapabilityBallot))
+	require.False(t, h.Supports(CapabilityBallots))
+}
@@ -2,12 +2,16 @@ package sebaknetwork
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"time"
 
 	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/node"
 	"github.com/gorilla/handlers"
 
 	"github.com/gorilla/mux"
@@ -32,12 +36,17 @@ type HTTP2Network struct {
 	tlsCertFile string
 	tlsKeyFile  string
 
+	// caPool is set only in mutual TLS mode
+	caPool *x509.CertPool
+
 	server *http.Server
 	router *mux.Router
 
 	receiveChannel chan Message
 
 	messageBroker MessageBroker
+	envelopes     *EnvelopeVerifier
+	peerEnvelopes *EnvelopeVerifier
 	ready         bool
 
 	watchers []func(Network, net.Conn, http.ConnState)
@@ -65,6 +74,17 @@ func NewHTTP2Network(config HTTP2NetworkConfig) (h2n *HTTP2Network) {
 	}
 	server.SetKeepAlivesEnabled(true)
 
+	// `TLSCAFile` was already checked by `NewHTTP2NetworkConfigFromEndpoint`
+	var caPool *x509.CertPool
+	if len(config.TLSCAFile) > 0 {
+		caPool, _ = LoadCertPool(config.TLSCAFile)
+		server.TLSConfig = &tls.Config{
+			ClientCAs: caPool,
+			// the api router is still open to the clients without certificate
+			ClientAuth: tls.VerifyClientCertIfGiven,
+		}
+	}
+
 	http2.ConfigureServer(
 		server,
 		&http2.Server{
@@ -82,6 +102,7 @@ func NewHTTP2Network(config HTTP2NetworkConfig) (h2n *HTTP2Network) {
 		router:         baseRouter,
 		tlsCertFile:    config.TLSCertFile,
 		tlsKeyFile:     config.TLSKeyFile,
+		caPool:         caPool,
 		receiveChannel: make(chan Message),
 	}
 	h2n.handlers = map[string]func(http.ResponseWriter, *http.Request){}
@@ -118,11 +139,57 @@ func (t *HTTP2Network) SetContext(ctx context.Context) {
 	t.ctx = ctx
 }
 
+// IsMutualTLS returns `true` when the validators authenticate each other by
+// the certificates from local CA.
+func (t *HTTP2Network) IsMutualTLS() bool {
+	return t.caPool != nil
+}
+
+// newHTTP2Client creates the raw client to `endpoint`. In mutual TLS mode,
+// it presents the node certificate and checks the server certificate is bound
+// to the validator of `endpoint`; the endpoint of unknown node is refused.
+func (t *HTTP2Network) newHTTP2Client(endpoint *sebakcommon.Endpoint, timeout, idleTimeout time.Duration, keepAlive bool) (*sebakcommon.HTTP2Client, error) {
+	if !t.IsMutualTLS() {
+		return sebakcommon.NewHTTP2Client(timeout, idleTimeout, keepAlive)
+	}
+
+	var address string
+	if localNode, _ := t.localNode(); localNode != nil {
+		if localNode.Endpoint().Host == endpoint.Host {
+			address = localNode.Address()
+		}
+		for _, v := range localNode.GetValidators() {
+			if v.Endpoint().Host == endpoint.Host {
+				address = v.Address()
+				break
+			}
+		}
+	}
+
+	if len(address) < 1 {
+		return nil, fmt.Errorf("endpoint, '%s' is not the known validator", endpoint)
+	}
+
+	tlsConfig, err := NewNodeClientTLSConfig(t.tlsCertFile, t.tlsKeyFile, t.caPool, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return sebakcommon.NewHTTP2ClientWithTLS(timeout, idleTimeout, keepAlive, tlsConfig)
+}
+
 // GetClient creates new keep-alive HTTP2 client
 func (t *HTTP2Network) GetClient(endpoint *sebakcommon.Endpoint) NetworkClient {
-	rawClient, _ := sebakcommon.NewHTTP2Client(defaultTimeout, 0, true)
+	rawClient, err := t.newHTTP2Client(endpoint, defaultTimeout, 0, true)
+	if err != nil {
+		log.Error("failed to create client", "endpoint", endpoint, "error", err)
+		return nil
+	}
 
 	client := NewHTTP2NetworkClient(endpoint, rawClient)
+	if localNode, networkID := t.localNode(); localNode != nil {
+		client.SetEnvelopeKeypair(localNode.Keypair(), networkID)
+	}
 
 	headers := http.Header{}
 	headers.Set("User-Agent", fmt.Sprintf("v-%s", t.config.NodeName))
@@ -131,6 +198,60 @@ func (t *HTTP2Network) GetClient(endpoint *sebakcommon.Endpoint) NetworkClient {
 	return client
 }
 
+func (t *HTTP2Network) localNode() (localNode *sebaknode.LocalNode, networkID []byte) {
+	if t.ctx == nil {
+		return
+	}
+
+	localNode, _ = t.ctx.Value("localNode").(*sebaknode.LocalNode)
+	networkID, _ = t.ctx.Value("networkID").([]byte)
+
+	return
+}
+
+// isKnownNode allows only the validators of local node and local node itself.
+func (t *HTTP2Network) isKnownNode(address string) bool {
+	localNode, _ := t.localNode()
+	if localNode == nil {
+		return false
+	}
+
+	return address == localNode.Address() || localNode.HasValidators(address)
+}
+
+func isAnyNode(string) bool {
+	return true
+}
+
+// requireNodeCertificate wraps the handler of node router. In mutual TLS
+// mode, the client certificate is required and it's address must be allowed
+// by `isAllowed`; the chain of certificate is already verified by the
+// server.
+func (t *HTTP2Network) requireNodeCertificate(h http.HandlerFunc, isAllowed func(string) bool) http.HandlerFunc {
+	if !t.IsMutualTLS() {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) < 1 {
+			WriteProblem(w, http.StatusUnauthorized, errors.New("client certificate is required"))
+			return
+		}
+
+		address, err := GetAddressFromCertificate(r.TLS.PeerCertificates[0])
+		if err != nil {
+			WriteProblem(w, http.StatusUnauthorized, err)
+			return
+		}
+		if !isAllowed(address) {
+			WriteProblem(w, http.StatusForbidden, errors.New("client certificate is not from the known validators"))
+			return
+		}
+
+		h(w, r)
+	}
+}
+
 func (t *HTTP2Network) Endpoint() *sebakcommon.Endpoint {
 	host, port, _ := net.SplitHostPort(t.server.Addr)
 	return &sebakcommon.Endpoint{Scheme: "https", Host: fmt.Sprintf("%s:%s", host, port)}
@@ -149,7 +270,7 @@ func (t *HTTP2Network) ConnState(c net.Conn, state http.ConnState) {
 func (t *HTTP2Network) setNotReadyHandler() {
 	t.router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if !t.ready {
-			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			WriteProblem(w, http.StatusServiceUnavailable, nil)
 			return
 		}
 	})
@@ -172,12 +293,22 @@ func (t *HTTP2Network) SetMessageBroker(mb MessageBroker) {
 }
 
 func (t *HTTP2Network) Ready() error {
+	_, networkID := t.localNode()
+	t.envelopes = NewEnvelopeVerifier(networkID, DefaultEnvelopeMaxClockSkew, t.isKnownNode)
+	t.peerEnvelopes = NewEnvelopeVerifier(networkID, DefaultEnvelopeMaxClockSkew, isAnyNode)
+
+	// `/message` accepts the transactions from the clients; `/discover` and
+	// `/gossip` accept the nodes, which are not validators.
 	nodeRouter := t.routers[RouterNameNode]
-	nodeRouter.HandleFunc("/", NodeInfoHandler(t.Context(), t))
-	nodeRouter.HandleFunc("/connect", ConnectHandler(t.Context(), t)).Methods("POST")
+	nodeRouter.HandleFunc("/", t.requireNodeCertificate(http.HandlerFunc(NodeInfoHandler(t.Context(), t)), t.isKnownNode))
+	nodeRouter.HandleFunc("/connect", t.requireNodeCertificate(http.HandlerFunc(ConnectHandler(t.Context(), t)), t.isKnownNode)).Methods("POST")
 	nodeRouter.HandleFunc("/message", MessageHandler(t.Context(), t)).Methods("POST")
-	nodeRouter.HandleFunc("/ballot", BallotHandler(t.Context(), t)).Methods("POST")
-	nodeRouter.HandleFunc("/metrics", promhttp.Handler().ServeHTTP)
+	nodeRouter.HandleFunc("/ballot", t.requireNodeCertificate(http.HandlerFunc(BallotHandler(t.Context(), t)), t.isKnownNode)).Methods("POST")
+	nodeRouter.HandleFunc("/ballots", t.requireNodeCertificate(http.HandlerFunc(BallotsHandler(t.Context(), t)), t.isKnownNode)).Methods("POST")
+	nodeRouter.HandleFunc("/discover", t.requireNodeCertificate(http.HandlerFunc(DiscoverHandler(t.Context(), t)), isAnyNode)).Methods("POST")
+	nodeRouter.HandleFunc("/gossip", t.requireNodeCertificate(http.HandlerFunc(GossipHandler(t.Context(), t)), isAnyNode)).Methods("POST")
+	nodeRouter.HandleFunc("/peers", t.requireNodeCertificate(http.HandlerFunc(PeersHandler(t.Context(), t)), t.isKnownNode)).Methods("GET")
+	nodeRouter.HandleFunc("/metrics", t.requireNodeCertificate(promhttp.Handler().ServeHTTP, t.isKnownNode))
 
 	t.server.Handler = handlers.CombinedLoggingHandler(t.config.HTTP2LogOutput, t.router)
 
@@ -187,7 +318,7 @@ func (t *HTTP2Network) Ready() error {
 }
 
 func (t *HTTP2Network) IsReady() bool {
-	client, err := sebakcommon.NewHTTP2Client(50*time.Millisecond, 50*time.Millisecond, false)
+	client, err := t.newHTTP2Client(t.Endpoint(), 50*time.Millisecond, 50*time.Millisecond, false)
 	if err != nil {
 		return false
 	}
@@ -1,19 +1,29 @@
 package sebaknetwork
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
+	"github.com/stellar/go/keypair"
+
 	"boscoin.io/sebak/lib/common"
-	"boscoin.io/sebak/lib/node"
 )
 
 type HTTP2NetworkClient struct {
 	endpoint       *sebakcommon.Endpoint
 	client         *sebakcommon.HTTP2Client
 	defaultHeaders http.Header
+
+	// keypair and networkID sign the `Envelope` of the node messages
+	keypair   *keypair.Full
+	networkID []byte
 }
 
 var (
@@ -21,6 +31,10 @@ var (
 	defaultIdleTimeout = 3 * time.Second
 )
 
+// DefaultBallotsCompressSize is the size of body in `SendBallots`; the larger
+// body is compressed by gzip.
+var DefaultBallotsCompressSize int = 4096
+
 func NewHTTP2NetworkClient(endpoint *sebakcommon.Endpoint, client *sebakcommon.HTTP2Client) *HTTP2NetworkClient {
 	if client == nil {
 		client, _ = sebakcommon.NewHTTP2Client(
@@ -56,6 +70,27 @@ func (c *HTTP2NetworkClient) DefaultHeaders() http.Header {
 	return headers
 }
 
+// SetEnvelopeKeypair sets the keypair of local node; without it, the node
+// messages like `Connect` and `SendBallot` are rejected by the other nodes.
+func (c *HTTP2NetworkClient) SetEnvelopeKeypair(kp *keypair.Full, networkID []byte) {
+	c.keypair = kp
+	c.networkID = networkID
+}
+
+// seal wraps the body of node message with the signed `Envelope`.
+func (c *HTTP2NetworkClient) seal(body []byte) ([]byte, error) {
+	if c.keypair == nil {
+		return body, nil
+	}
+
+	e, err := NewEnvelope(c.keypair, c.networkID, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Serialize()
+}
+
 func (c *HTTP2NetworkClient) resolvePath(path string) (u *url.URL) {
 	u = (*url.URL)(c.endpoint).ResolveReference(&url.URL{Path: path})
 	return u
@@ -79,24 +114,42 @@ func (c *HTTP2NetworkClient) GetNodeInfo() (body []byte, err error) {
 	return
 }
 
-func (c *HTTP2NetworkClient) Connect(node sebaknode.Node) (body []byte, err error) {
+// Ping checks the node is alive.
+func (c *HTTP2NetworkClient) Ping() error {
+	return sebakcommon.HTTP2ClientPingOnce(c.client, c.resolvePath(UrlPathPrefixNode+"/").String(), c.DefaultHeaders())
+}
+
+// Connect sends the `Handshake` of local node and returns the one of the
+// other node; the incompatible node refuses it with error.
+func (c *HTTP2NetworkClient) Connect(h Handshake) (body []byte, err error) {
+	return c.postNodeMessage("/connect", h)
+}
+
+func (c *HTTP2NetworkClient) SendMessage(message sebakcommon.Serializable) (retBody []byte, err error) {
 	headers := c.DefaultHeaders()
 	headers.Set("Content-Type", "application/json")
 
-	n, _ := node.Serialize()
+	var body []byte
+	if body, err = message.Serialize(); err != nil {
+		return
+	}
+
+	u := c.resolvePath(UrlPathPrefixNode + "/message")
+
 	var response *http.Response
-	response, err = c.client.Post(c.resolvePath(UrlPathPrefixNode+"/connect").String(), n, headers)
+	response, err = c.client.Post(u.String(), body, headers)
 	if err != nil {
 		return
 	}
 	defer response.Body.Close()
 	if response.StatusCode == http.StatusOK {
-		body, err = ioutil.ReadAll(response.Body)
+		retBody, err = ioutil.ReadAll(response.Body)
 	}
+
 	return
 }
 
-func (c *HTTP2NetworkClient) SendMessage(message sebakcommon.Serializable) (retBody []byte, err error) {
+func (c *HTTP2NetworkClient) SendBallot(message sebakcommon.Serializable) (retBody []byte, err error) {
 	headers := c.DefaultHeaders()
 	headers.Set("Content-Type", "application/json")
 
@@ -105,7 +158,11 @@ func (c *HTTP2NetworkClient) SendMessage(message sebakcommon.Serializable) (retB
 		return
 	}
 
-	u := c.resolvePath(UrlPathPrefixNode + "/message")
+	if body, err = c.seal(body); err != nil {
+		return
+	}
+
+	u := c.resolvePath(UrlPathPrefixNode + "/ballot")
 
 	var response *http.Response
 	response, err = c.client.Post(u.String(), body, headers)
@@ -120,25 +177,95 @@ func (c *HTTP2NetworkClient) SendMessage(message sebakcommon.Serializable) (retB
 	return
 }
 
-func (c *HTTP2NetworkClient) SendBallot(message sebakcommon.Serializable) (retBody []byte, err error) {
+// SendBallots sends the ballots in one request; the ballots are sent as the
+// JSON array in one `Envelope`.
+func (c *HTTP2NetworkClient) SendBallots(messages []sebakcommon.Serializable) (retBody []byte, err error) {
 	headers := c.DefaultHeaders()
 	headers.Set("Content-Type", "application/json")
 
+	raws := []json.RawMessage{}
+	for _, message := range messages {
+		var b []byte
+		if b, err = message.Serialize(); err != nil {
+			return
+		}
+		raws = append(raws, b)
+	}
+
 	var body []byte
-	if body, err = message.Serialize(); err != nil {
+	if body, err = json.Marshal(raws); err != nil {
+		return
+	}
+	if body, err = c.seal(body); err != nil {
 		return
 	}
 
-	u := c.resolvePath(UrlPathPrefixNode + "/ballot")
+	if len(body) > DefaultBallotsCompressSize {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err = gz.Write(body); err != nil {
+			return
+		}
+		if err = gz.Close(); err != nil {
+			return
+		}
+		body = buf.Bytes()
+		headers.Set("Content-Encoding", "gzip")
+	}
 
 	var response *http.Response
-	response, err = c.client.Post(u.String(), body, headers)
+	response, err = c.client.Post(c.resolvePath(UrlPathPrefixNode+"/ballots").String(), body, headers)
 	if err != nil {
 		return
 	}
 	defer response.Body.Close()
-	if response.StatusCode == http.StatusOK {
-		retBody, err = ioutil.ReadAll(response.Body)
+
+	if retBody, err = ioutil.ReadAll(response.Body); err != nil {
+		return
+	}
+	if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed to send ballots: %s", response.Status)
+		return
+	}
+
+	return
+}
+
+func (c *HTTP2NetworkClient) ExchangePeers(message sebakcommon.Serializable) (retBody []byte, err error) {
+	return c.postNodeMessage("/discover", message)
+}
+
+func (c *HTTP2NetworkClient) SendGossip(message sebakcommon.Serializable) (retBody []byte, err error) {
+	return c.postNodeMessage("/gossip", message)
+}
+
+// postNodeMessage posts the message in signed `Envelope` to the node router.
+// Unlike the other methods, the failed response is returned as error.
+func (c *HTTP2NetworkClient) postNodeMessage(path string, message sebakcommon.Serializable) (retBody []byte, err error) {
+	headers := c.DefaultHeaders()
+	headers.Set("Content-Type", "application/json")
+
+	var body []byte
+	if body, err = message.Serialize(); err != nil {
+		return
+	}
+	if body, err = c.seal(body); err != nil {
+		return
+	}
+
+	var response *http.Response
+	response, err = c.client.Post(c.resolvePath(UrlPathPrefixNode+path).String(), body, headers)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if retBody, err = ioutil.ReadAll(response.Body); err != nil {
+		return
+	}
+	if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed to request '%s': %s: %s", path, response.Status, strings.TrimSpace(string(retBody)))
+		return
 	}
 
 	return
@@ -21,6 +21,10 @@ type HTTP2NetworkConfig struct {
 	TLSCertFile,
 	TLSKeyFile string
 
+	// TLSCAFile is the local CA certificate; if it is given, the node router
+	// requires the client certificate from the known validators.
+	TLSCAFile string
+
 	HTTP2LogOutput io.Writer
 }
 
@@ -32,7 +36,7 @@ func NewHTTP2NetworkConfigFromEndpoint(endpoint *sebakcommon.Endpoint) (config H
 	var ReadHeaderTimeout time.Duration = 0
 	var WriteTimeout time.Duration = 0
 	var IdleTimeout time.Duration = 5
-	var TLSCertFile, TLSKeyFile string
+	var TLSCertFile, TLSKeyFile, TLSCAFile string
 	var HTTP2LogOutput io.Writer
 
 	if ReadTimeout, err = time.ParseDuration(sebakcommon.GetUrlQuery(query, "ReadTimeout", "0s")); err != nil {
@@ -70,6 +74,12 @@ func NewHTTP2NetworkConfigFromEndpoint(endpoint *sebakcommon.Endpoint) (config H
 	TLSCertFile = query.Get("TLSCertFile")
 	TLSKeyFile = query.Get("TLSKeyFile")
 
+	if TLSCAFile = query.Get("TLSCAFile"); len(TLSCAFile) > 0 {
+		if _, err = LoadCertPool(TLSCAFile); err != nil {
+			return
+		}
+	}
+
 	if v := query.Get("NodeName"); len(v) < 1 {
 		err = errors.New("`NodeName` must be given")
 		return
@@ -95,6 +105,7 @@ func NewHTTP2NetworkConfigFromEndpoint(endpoint *sebakcommon.Endpoint) (config H
 		IdleTimeout:       IdleTimeout,
 		TLSCertFile:       TLSCertFile,
 		TLSKeyFile:        TLSKeyFile,
+		TLSCAFile:         TLSCAFile,
 		HTTP2LogOutput:    HTTP2LogOutput,
 	}
 
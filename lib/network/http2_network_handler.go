@@ -1,14 +1,73 @@
 package sebaknetwork
 
 import (
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	"boscoin.io/sebak/lib/common"
 )
 
+// MaxBallotsBodySize limits the body of `BallotsHandler`; the gzip body is
+// limited before and after it is decompressed.
+var MaxBallotsBodySize int64 = 32 * 1024 * 1024
+
+// MaxDiscoverBodySize limits the body of `DiscoverHandler`, which has the
+// peers of `PeerTable`.
+var MaxDiscoverBodySize int64 = 256 * 1024
+
+// MaxGossipBodySize limits the body of `GossipHandler`, which has one
+// transaction.
+var MaxGossipBodySize int64 = 2 * 1024 * 1024
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// readLimitedBody reads the body up to `limit` bytes. The gzip body of
+// 'Content-Encoding' is decompressed, and the decompressed one is limited
+// too. The larger body returns `errBodyTooLarge`.
+func readLimitedBody(r *http.Request, limit int64) (body []byte, err error) {
+	raw := &io.LimitedReader{R: r.Body, N: limit + 1}
+
+	var reader io.Reader = raw
+	if strings.ToLower(r.Header.Get("Content-Encoding")) == "gzip" {
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(raw); err != nil {
+			if raw.N < 1 {
+				err = errBodyTooLarge
+			}
+			return
+		}
+		defer gz.Close()
+
+		reader = gz
+	}
+
+	body, err = ioutil.ReadAll(io.LimitReader(reader, limit+1))
+	if raw.N < 1 || int64(len(body)) > limit {
+		err = errBodyTooLarge
+		return
+	}
+
+	return
+}
+
+// openEnvelope parses and verifies the `Envelope` of node message.
+func openEnvelope(v *EnvelopeVerifier, body []byte) (e Envelope, err error) {
+	if e, err = NewEnvelopeFromJSON(body); err != nil {
+		return
+	}
+
+	err = v.Verify(e, time.Now())
+
+	return
+}
+
 func NodeInfoHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
 	var localNode sebakcommon.Serializable
 
@@ -22,29 +81,38 @@ func NodeInfoHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
 	}
 }
 
+// ConnectHandler exchanges the `Handshake`; the node, which is not compatible
+// with local node is refused.
 func ConnectHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
-	var localNode sebakcommon.Serializable
-
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
 
-		if localNode == nil {
-			localNode = ctx.Value("localNode").(sebakcommon.Serializable)
-		}
-
 		if r.Method != "POST" {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			WriteProblem(w, http.StatusMethodNotAllowed, nil)
 			return
 		}
 
 		body, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			http.Error(w, "Error reading request body", http.StatusInternalServerError)
+			WriteProblem(w, http.StatusInternalServerError, err)
+			return
 		}
 
-		t.messageBroker.ReceiveMessage(t, Message{Type: ConnectMessage, Data: body})
-		o, _ := localNode.Serialize()
-		t.messageBroker.ResponseMessage(w, string(o))
+		e, err := openEnvelope(t.envelopes, body)
+		if err != nil {
+			WriteProblem(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		// the connecting node must be the sender of envelope
+		h, local, err := acceptHandshake(ctx, e.H.Sender, e.B)
+		if err != nil {
+			WriteProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		t.messageBroker.ReceiveMessage(t, Message{Type: ConnectMessage, Data: h.Node})
+		t.messageBroker.ResponseMessage(w, string(local))
 	}
 }
 
@@ -53,18 +121,18 @@ func MessageHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
 		defer r.Body.Close()
 
 		if r.Method != "POST" {
-			// TODO use http-problem spec
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			WriteProblem(w, http.StatusMethodNotAllowed, nil)
 			return
 		}
 		if ct := r.Header.Get("Content-Type"); strings.ToLower(ct) != "application/json" {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			WriteProblem(w, http.StatusBadRequest, nil)
 			return
 		}
 
 		body, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			http.Error(w, "Error reading request body", http.StatusInternalServerError)
+			WriteProblem(w, http.StatusInternalServerError, err)
+			return
 		}
 
 		t.messageBroker.ReceiveMessage(t, Message{Type: MessageFromClient, Data: body})
@@ -77,22 +145,161 @@ func MessageHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
 func BallotHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			WriteProblem(w, http.StatusMethodNotAllowed, nil)
 			return
 		}
 		if ct := r.Header.Get("Content-Type"); strings.ToLower(ct) != "application/json" {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			WriteProblem(w, http.StatusBadRequest, nil)
 			return
 		}
 
 		body, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			http.Error(w, "Error reading request body", http.StatusInternalServerError)
+			WriteProblem(w, http.StatusInternalServerError, err)
+			return
 		}
 
+		e, err := openEnvelope(t.envelopes, body)
+		if err != nil {
+			WriteProblem(w, http.StatusUnauthorized, err)
+			return
+		}
+		body = e.B
+
 		t.messageBroker.ReceiveMessage(t, Message{Type: BallotMessage, Data: body})
 		t.messageBroker.ResponseMessage(w, string(body))
 
 		return
 	}
 }
+
+// BallotsHandler receives the ballots, which are sent together by
+// `SendBallots`; each ballot is handled like the one from `BallotHandler`.
+func BallotsHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		if r.Method != "POST" {
+			WriteProblem(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); strings.ToLower(ct) != "application/json" {
+			WriteProblem(w, http.StatusBadRequest, nil)
+			return
+		}
+
+		body, err := readLimitedBody(r, MaxBallotsBodySize)
+		if err == errBodyTooLarge {
+			WriteProblem(w, http.StatusRequestEntityTooLarge, err)
+			return
+		} else if err != nil {
+			WriteProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		e, err := openEnvelope(t.envelopes, body)
+		if err != nil {
+			WriteProblem(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		var ballots []json.RawMessage
+		if err = json.Unmarshal(e.B, &ballots); err != nil {
+			WriteProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		for _, ballot := range ballots {
+			t.messageBroker.ReceiveMessage(t, Message{Type: BallotMessage, Data: []byte(ballot)})
+		}
+		t.messageBroker.ResponseMessage(w, "")
+
+		return
+	}
+}
+
+// DiscoverHandler exchanges the known peers with the other node.
+func DiscoverHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		pm, ok := ctx.Value("peerManager").(*PeerManager)
+		if !ok {
+			WriteProblem(w, http.StatusServiceUnavailable, nil)
+			return
+		}
+
+		body, err := readLimitedBody(r, MaxDiscoverBodySize)
+		if err == errBodyTooLarge {
+			WriteProblem(w, http.StatusRequestEntityTooLarge, err)
+			return
+		} else if err != nil {
+			WriteProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		e, err := openEnvelope(t.peerEnvelopes, body)
+		if err != nil {
+			WriteProblem(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		o, err := pm.Exchange(e.B)
+		if err != nil {
+			WriteProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		t.messageBroker.ResponseMessage(w, string(o))
+	}
+}
+
+// PeersHandler shows the liveness of validators and the known peers.
+func PeersHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		validators := []ValidatorHealth{}
+		if cm, ok := ctx.Value("connectionManager").(*ConnectionManager); ok {
+			validators = append(validators, cm.Health()...)
+		}
+
+		peers := []Peer{}
+		if pm, ok := ctx.Value("peerManager").(*PeerManager); ok {
+			peers = append(peers, pm.Table().Peers()...)
+		}
+
+		o, err := json.Marshal(map[string]interface{}{
+			"validators": validators,
+			"peers":      peers,
+		})
+		if err != nil {
+			WriteProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		t.messageBroker.ResponseMessage(w, string(o))
+	}
+}
+
+func GossipHandler(ctx context.Context, t *HTTP2Network) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		body, err := readLimitedBody(r, MaxGossipBodySize)
+		if err == errBodyTooLarge {
+			WriteProblem(w, http.StatusRequestEntityTooLarge, err)
+			return
+		} else if err != nil {
+			WriteProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		e, err := openEnvelope(t.peerEnvelopes, body)
+		if err != nil {
+			WriteProblem(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		t.messageBroker.ReceiveMessage(t, Message{Type: GossipMessage, Data: e.B})
+	}
+}
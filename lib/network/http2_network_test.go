@@ -2,6 +2,7 @@ package sebaknetwork
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
 	"boscoin.io/sebak/lib/node"
 
 	"github.com/stellar/go/keypair"
@@ -60,10 +62,9 @@ func pingAndWait(t *testing.T, c0 NetworkClient) {
 	}
 }
 
-func createNewHTTP2Network(t *testing.T) (kp *keypair.Full, mn *HTTP2Network, localNode *sebaknode.LocalNode) {
+func newTestHTTP2NetworkConfig(t *testing.T) (config HTTP2NetworkConfig) {
 	g := NewKeyGenerator(dirPath, certPath, keyPath)
 
-	var config HTTP2NetworkConfig
 	endpoint, err := sebakcommon.NewEndpointFromString(fmt.Sprintf("https://localhost:%s?NodeName=n1", getPort()))
 	if err != nil {
 		t.Error(err)
@@ -80,7 +81,12 @@ func createNewHTTP2Network(t *testing.T) (kp *keypair.Full, mn *HTTP2Network, lo
 		t.Error(err)
 		return
 	}
-	mn = NewHTTP2Network(config)
+
+	return
+}
+
+func createNewHTTP2Network(t *testing.T) (kp *keypair.Full, mn *HTTP2Network, localNode *sebaknode.LocalNode) {
+	mn = NewHTTP2Network(newTestHTTP2NetworkConfig(t))
 
 	kp, _ = keypair.Random()
 	localNode, _ = sebaknode.NewLocalNode(kp, mn.Endpoint(), "")
@@ -157,7 +163,8 @@ func TestHTTP2NetworkMessageBrokerResponseMessage(t *testing.T) {
 	c0 := s0.GetClient(s0.Endpoint())
 	pingAndWait(t, c0)
 
-	returnMsg, _ := c0.Connect(localNode)
+	h, _ := NewHandshake(localNode, nil)
+	returnMsg, _ := c0.Connect(h)
 
 	require.Equal(t, string(returnMsg), "ResponseMessage", "The connectNode and the return should be the same.")
 }
@@ -176,12 +183,44 @@ func TestHTTP2NetworkConnect(t *testing.T) {
 	o, _ := localNode.Serialize()
 	nodeStr := removeWhiteSpaces(string(o))
 
-	returnMsg, _ := c0.Connect(localNode)
-	returnStr := removeWhiteSpaces(string(returnMsg))
+	h, _ := NewHandshake(localNode, nil)
+	returnMsg, err := c0.Connect(h)
+	require.Nil(t, err)
+
+	returned, err := NewHandshakeFromJSON(returnMsg)
+	require.Nil(t, err)
+	require.Equal(t, ProtocolVersion, returned.ProtocolVersion)
+	require.Equal(t, Capabilities, returned.Capabilities)
 
+	returnStr := removeWhiteSpaces(string(returned.Node))
 	require.Equal(t, returnStr, nodeStr, "The connectNode and the return should be the same.")
 }
 
+func TestHTTP2NetworkConnectIncompatible(t *testing.T) {
+	_, s0, localNode := createNewHTTP2Network(t)
+	s0.SetMessageBroker(TestMessageBroker{})
+	s0.Ready()
+
+	go s0.Start()
+	defer s0.Stop()
+
+	c0 := s0.GetClient(s0.Endpoint())
+	pingAndWait(t, c0)
+
+	// different network
+	h, _ := NewHandshake(localNode, []byte("other-network"))
+	_, err := c0.Connect(h)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), sebakerror.ErrorHandshakeNetworkIDMismatch.Message)
+
+	// too old protocol
+	h, _ = NewHandshake(localNode, nil)
+	h.ProtocolVersion = MinProtocolVersion - 1
+	_, err = c0.Connect(h)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), sebakerror.ErrorHandshakeIncompatibleProtocol.Message)
+}
+
 func TestHTTP2NetworkSendMessage(t *testing.T) {
 	_, s0, _ := createNewHTTP2Network(t)
 	s0.SetMessageBroker(TestMessageBroker{})
@@ -220,3 +259,148 @@ func TestHTTP2NetworkSendBallot(t *testing.T) {
 
 	require.Equal(t, returnStr, sendMsg, "The sendBallot and the return should be the same.")
 }
+
+func TestHTTP2NetworkBallotWithoutEnvelope(t *testing.T) {
+	_, s0, _ := createNewHTTP2Network(t)
+	s0.SetMessageBroker(TestMessageBroker{})
+	s0.Ready()
+	go s0.Start()
+	defer s0.Stop()
+
+	c0 := s0.GetClient(s0.Endpoint())
+	pingAndWait(t, c0)
+
+	// client without keypair does not sign the envelope
+	unsigned := NewHTTP2NetworkClient(s0.Endpoint(), nil)
+
+	returnMsg, err := unsigned.SendBallot(NewDummyMessage("findme"))
+	require.Nil(t, err)
+	require.Empty(t, returnMsg)
+
+	// the node, which is not in validators
+	kpUnknown, _ := keypair.Random()
+	unsigned.SetEnvelopeKeypair(kpUnknown, nil)
+
+	returnMsg, err = unsigned.SendBallot(NewDummyMessage("findme"))
+	require.Nil(t, err)
+	require.Empty(t, returnMsg)
+}
+
+func TestHTTP2NetworkPeers(t *testing.T) {
+	_, s0, _ := createNewHTTP2Network(t)
+	s0.SetMessageBroker(Http2MessageBroker{})
+	s0.Ready()
+	go s0.Start()
+	defer s0.Stop()
+
+	c0 := s0.GetClient(s0.Endpoint())
+	pingAndWait(t, c0)
+
+	require.Nil(t, c0.Ping())
+
+	b, err := c0.(*HTTP2NetworkClient).Get(UrlPathPrefixNode + "/peers")
+	require.Nil(t, err)
+
+	var peers map[string][]interface{}
+	require.Nil(t, json.Unmarshal(b, &peers))
+	require.Equal(t, 0, len(peers["validators"]))
+	require.Equal(t, 0, len(peers["peers"]))
+}
+
+type ReceivingMessageBroker struct {
+	received chan Message
+}
+
+func (r ReceivingMessageBroker) ResponseMessage(w http.ResponseWriter, o string) {
+	fmt.Fprint(w, o)
+}
+
+func (r ReceivingMessageBroker) ReceiveMessage(_ *HTTP2Network, message Message) {
+	r.received <- message
+}
+
+func TestHTTP2NetworkSendBallots(t *testing.T) {
+	_, s0, _ := createNewHTTP2Network(t)
+	broker := ReceivingMessageBroker{received: make(chan Message, 100)}
+	s0.SetMessageBroker(broker)
+	s0.Ready()
+	go s0.Start()
+	defer s0.Stop()
+
+	c0 := s0.GetClient(s0.Endpoint())
+	pingAndWait(t, c0)
+
+	defer func(size int) {
+		DefaultBallotsCompressSize = size
+	}(DefaultBallotsCompressSize)
+
+	for _, size := range []int{DefaultBallotsCompressSize, 0} {
+		// with 0, the body is compressed
+		DefaultBallotsCompressSize = size
+
+		messages := []sebakcommon.Serializable{NewDummyMessage("0"), NewDummyMessage("1")}
+		_, err := c0.SendBallots(messages)
+		require.Nil(t, err)
+
+		for _, m := range messages {
+			received := <-broker.received
+			require.Equal(t, MessageType(BallotMessage), received.Type)
+
+			b, _ := m.Serialize()
+			require.Equal(t, string(b), string(received.Data))
+		}
+	}
+
+	// the node, which is not in validators
+	unknown := NewHTTP2NetworkClient(s0.Endpoint(), nil)
+	kpUnknown, _ := keypair.Random()
+	unknown.SetEnvelopeKeypair(kpUnknown, nil)
+
+	_, err := unknown.SendBallots([]sebakcommon.Serializable{NewDummyMessage("0")})
+	require.NotNil(t, err)
+
+	// the body over `MaxBallotsBodySize`, plain and compressed
+	defer func(size int64) {
+		MaxBallotsBodySize = size
+	}(MaxBallotsBodySize)
+	MaxBallotsBodySize = 100
+
+	large := NewDummyMessage(strings.Repeat("a", 1000))
+	for _, size := range []int{DefaultBallotsCompressSize, 0} {
+		DefaultBallotsCompressSize = size
+
+		_, err = c0.SendBallots([]sebakcommon.Serializable{large})
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "413")
+	}
+}
+
+func TestHTTP2NetworkPeerMessageTooLarge(t *testing.T) {
+	_, s0, localNode := createNewHTTP2Network(t)
+	pm := NewPeerManager(localNode, s0, testPeerNetworkID)
+	s0.SetContext(context.WithValue(s0.Context(), "peerManager", pm))
+	s0.SetMessageBroker(ReceivingMessageBroker{received: make(chan Message, 100)})
+	s0.Ready()
+	go s0.Start()
+	defer s0.Stop()
+
+	c0 := s0.GetClient(s0.Endpoint())
+	pingAndWait(t, c0)
+
+	defer func(discover, gossip int64) {
+		MaxDiscoverBodySize = discover
+		MaxGossipBodySize = gossip
+	}(MaxDiscoverBodySize, MaxGossipBodySize)
+	MaxDiscoverBodySize = 100
+	MaxGossipBodySize = 100
+
+	large := NewDummyMessage(strings.Repeat("a", 1000))
+
+	_, err := c0.ExchangePeers(large)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "413")
+
+	_, err = c0.SendGossip(large)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "413")
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"net"
 	"net/http"
+	"sync"
 
 	"boscoin.io/sebak/lib/common"
 	"github.com/google/uuid"
 )
 
-var memoryNetworks map[ /* endpoint */ string]*MemoryNetwork
+var (
+	memoryNetworks     map[ /* endpoint */ string]*MemoryNetwork
+	memoryNetworksLock sync.RWMutex
+)
 
 func init() {
 	memoryNetworks = map[string]*MemoryNetwork{}
@@ -18,13 +22,28 @@ func init() {
 func CleanUpMemoryNetwork() {
 	// BUG(osx): `CleanUpMemoryNetwork` causes 'runtime error: invalid memory address or nil pointer dereference'
 	//MemoryNetworks = map[string]*MemoryNetworks{}
+
+	faults.reset()
 }
 
 func addMemoryNetwork(m *MemoryNetwork) {
+	memoryNetworksLock.Lock()
+	defer memoryNetworksLock.Unlock()
+
 	memoryNetworks[m.Endpoint().String()] = m
 }
 
+func removeMemoryNetwork(m *MemoryNetwork) {
+	memoryNetworksLock.Lock()
+	defer memoryNetworksLock.Unlock()
+
+	delete(memoryNetworks, m.Endpoint().String())
+}
+
 func getMemoryNetwork(endpoint *sebakcommon.Endpoint) *MemoryNetwork {
+	memoryNetworksLock.RLock()
+	defer memoryNetworksLock.RUnlock()
+
 	n, _ := memoryNetworks[endpoint.String()]
 	return n
 }
@@ -47,12 +66,15 @@ func (t *MemoryNetwork) SetContext(ctx context.Context) {
 }
 
 func (t *MemoryNetwork) GetClient(endpoint *sebakcommon.Endpoint) NetworkClient {
-	n, ok := memoryNetworks[endpoint.String()]
-	if !ok {
+	n := getMemoryNetwork(endpoint)
+	if n == nil {
 		return nil
 	}
 
-	return NewMemoryNetworkClient(endpoint, n)
+	client := NewMemoryNetworkClient(endpoint, n)
+	client.from = t.endpoint
+
+	return client
 }
 
 func (p *MemoryNetwork) AddWatcher(f func(Network, net.Conn, http.ConnState)) {
@@ -1,13 +1,17 @@
 package sebaknetwork
 
 import (
+	"errors"
+
 	"boscoin.io/sebak/lib/common"
-	"boscoin.io/sebak/lib/node"
 )
 
 type MemoryTransportClient struct {
 	endpoint *sebakcommon.Endpoint
 
+	// from is the endpoint of the network, which created the client; the
+	// faults of the link from it are applied.
+	from   *sebakcommon.Endpoint
 	server *MemoryNetwork
 }
 
@@ -22,12 +26,48 @@ func (m *MemoryTransportClient) Endpoint() *sebakcommon.Endpoint {
 	return m.endpoint
 }
 
-func (m *MemoryTransportClient) Connect(node sebaknode.Node) (b []byte, err error) {
-	b = m.server.GetNodeInfo()
+// send delivers the message to server through the faults of link.
+func (m *MemoryTransportClient) send(mt MessageType, b []byte) (err error) {
+	message, delivered, err := faults.message(m.from, m.endpoint, NewMessage(mt, b))
+	if err != nil || !delivered {
+		return
+	}
+
+	return m.server.Send(message.Type, message.Data)
+}
+
+func (m *MemoryTransportClient) Connect(h Handshake) (b []byte, err error) {
+	if err = faults.request(m.from, m.endpoint); err != nil {
+		return
+	}
+
+	var s []byte
+	if s, err = h.Serialize(); err != nil {
+		return
+	}
+
+	_, b, err = acceptHandshake(m.server.Context(), "", s)
+
+	return
+}
+
+func (m *MemoryTransportClient) Ping() (err error) {
+	if err = faults.request(m.from, m.endpoint); err != nil {
+		return
+	}
+
+	if getMemoryNetwork(m.endpoint) == nil {
+		err = errors.New("peer is not found")
+	}
+
 	return
 }
 
 func (m *MemoryTransportClient) GetNodeInfo() (b []byte, err error) {
+	if err = faults.request(m.from, m.endpoint); err != nil {
+		return
+	}
+
 	b = m.server.GetNodeInfo()
 	return
 }
@@ -37,7 +77,7 @@ func (m *MemoryTransportClient) SendMessage(message sebakcommon.Serializable) (b
 	if s, err = message.Serialize(); err != nil {
 		return
 	}
-	m.server.Send(MessageFromClient, s)
+	err = m.send(MessageFromClient, s)
 
 	return
 }
@@ -47,7 +87,52 @@ func (m *MemoryTransportClient) SendBallot(message sebakcommon.Serializable) (bo
 	if s, err = message.Serialize(); err != nil {
 		return
 	}
-	m.server.Send(BallotMessage, s)
+	err = m.send(BallotMessage, s)
+
+	return
+}
+
+func (m *MemoryTransportClient) SendBallots(messages []sebakcommon.Serializable) (body []byte, err error) {
+	for _, message := range messages {
+		if _, err = m.SendBallot(message); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (m *MemoryTransportClient) ExchangePeers(message sebakcommon.Serializable) (body []byte, err error) {
+	if err = faults.request(m.from, m.endpoint); err != nil {
+		return
+	}
+
+	var s []byte
+	if s, err = message.Serialize(); err != nil {
+		return
+	}
+
+	ctx := m.server.Context()
+	if ctx == nil {
+		err = errors.New("peer is not ready")
+		return
+	}
+
+	pm, ok := ctx.Value("peerManager").(*PeerManager)
+	if !ok {
+		err = errors.New("peer is not ready")
+		return
+	}
+
+	return pm.Exchange(s)
+}
+
+func (m *MemoryTransportClient) SendGossip(message sebakcommon.Serializable) (body []byte, err error) {
+	var s []byte
+	if s, err = message.Serialize(); err != nil {
+		return
+	}
+	err = m.send(GossipMessage, s)
 
 	return
 }
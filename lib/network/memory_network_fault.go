@@ -0,0 +1,160 @@
+package sebaknetwork
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+// The faults of `MemoryNetwork` are set by link, which is the one-way
+// connection from one endpoint to the other. They are for the tests, which
+// reproduce the slow, lossy or partitioned network; `CleanUpMemoryNetwork`
+// removes all of them.
+
+// MemoryLink is the condition of one-way link.
+type MemoryLink struct {
+	Latency     time.Duration // delay of every request
+	DropRate    float64       // probability of losing message; 0 ~ 1
+	Partitioned bool          // the requests fail like unreachable node
+}
+
+// MemoryInterceptor is called with the message before it is delivered; it
+// can inspect or replace the message, and the message is dropped when it
+// returns `false`.
+type MemoryInterceptor func(from, to *sebakcommon.Endpoint, message Message) (Message, bool)
+
+var errMemoryLinkPartitioned = errors.New("link is partitioned")
+
+type memoryLinkKey struct {
+	from string
+	to   string
+}
+
+func newMemoryLinkKey(from, to *sebakcommon.Endpoint) memoryLinkKey {
+	var key memoryLinkKey
+	if from != nil {
+		key.from = from.String()
+	}
+	if to != nil {
+		key.to = to.String()
+	}
+
+	return key
+}
+
+type memoryFaults struct {
+	sync.RWMutex
+
+	links        map[memoryLinkKey]MemoryLink
+	interceptors []MemoryInterceptor
+}
+
+var faults = &memoryFaults{links: map[memoryLinkKey]MemoryLink{}}
+
+func (f *memoryFaults) reset() {
+	f.Lock()
+	defer f.Unlock()
+
+	f.links = map[memoryLinkKey]MemoryLink{}
+	f.interceptors = nil
+}
+
+func (f *memoryFaults) link(from, to *sebakcommon.Endpoint) MemoryLink {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.links[newMemoryLinkKey(from, to)]
+}
+
+// request applies the latency and partition of link to the request, which
+// is not message like `Connect` and `Ping`.
+func (f *memoryFaults) request(from, to *sebakcommon.Endpoint) error {
+	link := f.link(from, to)
+	if link.Latency > 0 {
+		time.Sleep(link.Latency)
+	}
+	if link.Partitioned {
+		return errMemoryLinkPartitioned
+	}
+
+	return nil
+}
+
+// message applies the link and the interceptors to the message; it returns
+// `false` when the message is lost.
+func (f *memoryFaults) message(from, to *sebakcommon.Endpoint, message Message) (Message, bool, error) {
+	if err := f.request(from, to); err != nil {
+		return message, false, err
+	}
+
+	if link := f.link(from, to); link.DropRate > 0 && rand.Float64() < link.DropRate {
+		return message, false, nil
+	}
+
+	f.RLock()
+	interceptors := f.interceptors
+	f.RUnlock()
+
+	for _, intercept := range interceptors {
+		var delivered bool
+		if message, delivered = intercept(from, to, message); !delivered {
+			return message, false, nil
+		}
+	}
+
+	return message, true, nil
+}
+
+// SetMemoryLink sets the condition of the link from `from` to `to`; the
+// other direction is not changed.
+func SetMemoryLink(from, to *sebakcommon.Endpoint, link MemoryLink) {
+	faults.Lock()
+	defer faults.Unlock()
+
+	faults.links[newMemoryLinkKey(from, to)] = link
+}
+
+// PartitionMemoryNetwork cuts the links between the groups in both
+// directions; the links in the same group are not changed.
+func PartitionMemoryNetwork(groups ...[]*sebakcommon.Endpoint) {
+	faults.Lock()
+	defer faults.Unlock()
+
+	for i, group := range groups {
+		for j, other := range groups {
+			if i == j {
+				continue
+			}
+			for _, from := range group {
+				for _, to := range other {
+					key := newMemoryLinkKey(from, to)
+					link := faults.links[key]
+					link.Partitioned = true
+					faults.links[key] = link
+				}
+			}
+		}
+	}
+}
+
+// HealMemoryNetwork removes the partitions; the latency and drop rate are
+// kept.
+func HealMemoryNetwork() {
+	faults.Lock()
+	defer faults.Unlock()
+
+	for key, link := range faults.links {
+		link.Partitioned = false
+		faults.links[key] = link
+	}
+}
+
+func AddMemoryInterceptor(intercept MemoryInterceptor) {
+	faults.Lock()
+	defer faults.Unlock()
+
+	faults.interceptors = append(faults.interceptors, intercept)
+}
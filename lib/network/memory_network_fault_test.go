@@ -0,0 +1,137 @@
+package sebaknetwork
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+func createStartedMemoryNetworks(n int) (mns []*MemoryNetwork) {
+	for i := 0; i < n; i++ {
+		_, mn, _ := createNewMemoryNetwork()
+		go mn.Start()
+		mns = append(mns, mn)
+	}
+
+	return
+}
+
+// receiveMemoryMessage returns `false` when no message is received in
+// `timeout`.
+func receiveMemoryMessage(mn *MemoryNetwork, timeout time.Duration) (Message, bool) {
+	select {
+	case message := <-mn.ReceiveMessage():
+		return message, true
+	case <-time.After(timeout):
+		return Message{}, false
+	}
+}
+
+func TestMemoryNetworkFaultLatency(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	mns := createStartedMemoryNetworks(2)
+	SetMemoryLink(mns[0].Endpoint(), mns[1].Endpoint(), MemoryLink{Latency: 200 * time.Millisecond})
+
+	started := time.Now()
+	go mns[0].GetClient(mns[1].Endpoint()).SendBallot(NewDummyMessage("0"))
+
+	_, received := receiveMemoryMessage(mns[1], time.Second)
+	require.True(t, received)
+	require.True(t, time.Since(started) >= 200*time.Millisecond)
+
+	// the other direction is not delayed
+	require.Nil(t, mns[1].GetClient(mns[0].Endpoint()).Ping())
+
+	started = time.Now()
+	require.Nil(t, mns[0].GetClient(mns[1].Endpoint()).Ping())
+	require.True(t, time.Since(started) >= 200*time.Millisecond)
+}
+
+func TestMemoryNetworkFaultDrop(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	mns := createStartedMemoryNetworks(2)
+	SetMemoryLink(mns[0].Endpoint(), mns[1].Endpoint(), MemoryLink{DropRate: 1})
+
+	// the lost message is not an error for sender
+	_, err := mns[0].GetClient(mns[1].Endpoint()).SendBallot(NewDummyMessage("0"))
+	require.Nil(t, err)
+
+	_, received := receiveMemoryMessage(mns[1], 100*time.Millisecond)
+	require.False(t, received)
+}
+
+func TestMemoryNetworkFaultPartition(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	mns := createStartedMemoryNetworks(3)
+
+	// one-way partition
+	SetMemoryLink(mns[0].Endpoint(), mns[1].Endpoint(), MemoryLink{Partitioned: true})
+	require.NotNil(t, mns[0].GetClient(mns[1].Endpoint()).Ping())
+	require.Nil(t, mns[1].GetClient(mns[0].Endpoint()).Ping())
+
+	HealMemoryNetwork()
+	require.Nil(t, mns[0].GetClient(mns[1].Endpoint()).Ping())
+
+	// mns[2] is isolated from the others
+	PartitionMemoryNetwork(
+		[]*sebakcommon.Endpoint{mns[0].Endpoint(), mns[1].Endpoint()},
+		[]*sebakcommon.Endpoint{mns[2].Endpoint()},
+	)
+
+	_, err := mns[0].GetClient(mns[2].Endpoint()).SendBallot(NewDummyMessage("0"))
+	require.NotNil(t, err)
+	_, err = mns[2].GetClient(mns[1].Endpoint()).SendGossip(NewDummyMessage("0"))
+	require.NotNil(t, err)
+
+	go mns[0].GetClient(mns[1].Endpoint()).SendBallot(NewDummyMessage("1"))
+	message, received := receiveMemoryMessage(mns[1], time.Second)
+	require.True(t, received)
+	require.Equal(t, MessageType(BallotMessage), message.Type)
+
+	HealMemoryNetwork()
+	go mns[0].GetClient(mns[2].Endpoint()).SendBallot(NewDummyMessage("2"))
+	_, received = receiveMemoryMessage(mns[2], time.Second)
+	require.True(t, received)
+}
+
+func TestMemoryNetworkFaultInterceptor(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	mns := createStartedMemoryNetworks(2)
+
+	var inspected []string
+	AddMemoryInterceptor(func(from, to *sebakcommon.Endpoint, message Message) (Message, bool) {
+		inspected = append(inspected, from.String()+">"+to.String())
+
+		if message.Type == GossipMessage {
+			return message, false
+		}
+		message.Data = []byte("replaced")
+		return message, true
+	})
+
+	client := mns[0].GetClient(mns[1].Endpoint())
+	_, err := client.SendGossip(NewDummyMessage("0"))
+	require.Nil(t, err)
+
+	go client.SendBallot(NewDummyMessage("1"))
+	message, received := receiveMemoryMessage(mns[1], time.Second)
+	require.True(t, received)
+	require.Equal(t, "replaced", string(message.Data))
+
+	link := mns[0].Endpoint().String() + ">" + mns[1].Endpoint().String()
+	require.Equal(t, []string{link, link}, inspected)
+
+	// `CleanUpMemoryNetwork` removes the faults
+	CleanUpMemoryNetwork()
+	go client.SendBallot(NewDummyMessage("2"))
+	message, received = receiveMemoryMessage(mns[1], time.Second)
+	require.True(t, received)
+	require.NotEqual(t, "replaced", string(message.Data))
+}
@@ -122,53 +122,3 @@ func TestMemoryNetworkGetClient(t *testing.T) {
 		t.Error("failed to get message")
 	}
 }
-
-func TestMemoryNetworkGetNodeInfo(t *testing.T) {
-	defer CleanUpMemoryNetwork()
-
-	_, s0, localNode := createNewMemoryNetwork()
-
-	c0 := s0.GetClient(s0.Endpoint())
-	b, err := c0.GetNodeInfo()
-	if err != nil {
-		t.Error(err)
-		return
-	}
-	v, err := sebaknode.NewValidatorFromString(b)
-	if err != nil {
-		t.Error(err)
-		return
-	}
-	if localNode.Endpoint().String() != v.Endpoint().String() {
-		t.Errorf("received node info mismatch; '%s' != '%s'", localNode.Endpoint().String(), v.Endpoint().String())
-	}
-	if localNode.Address() != v.Address() {
-		t.Errorf("received node info mismatch; '%s' != '%s'", localNode.Address(), v.Address())
-		return
-	}
-}
-
-func TestMemoryNetworkConnect(t *testing.T) {
-	defer CleanUpMemoryNetwork()
-
-	_, s0, localNode := createNewMemoryNetwork()
-
-	c0 := s0.GetClient(s0.Endpoint())
-	b, err := c0.Connect(localNode)
-	if err != nil {
-		t.Error(err)
-		return
-	}
-	v, err := sebaknode.NewValidatorFromString(b)
-	if err != nil {
-		t.Error(err)
-		return
-	}
-	if localNode.Endpoint().String() != v.Endpoint().String() {
-		t.Errorf("received node info mismatch; '%s' != '%s'", localNode.Endpoint().String(), v.Endpoint().String())
-	}
-	if localNode.Address() != v.Address() {
-		t.Errorf("received node info mismatch; '%s' != '%s'", localNode.Address(), v.Address())
-		return
-	}
-}
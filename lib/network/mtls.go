@@ -0,0 +1,251 @@
+package sebaknetwork
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"time"
+
+	"github.com/stellar/go/keypair"
+)
+
+// The certificates for mutual TLS between validators are issued by the
+// local CA. The node certificate is bound to the public address of node by
+// it's common name, so the node can check which validator is on the other
+// side of connection.
+
+const (
+	CAValidFor   = time.Hour * 24 * 365 * 10
+	NodeValidFor = time.Hour * 24 * 365
+)
+
+var (
+	caOrganization   = "BOScoin Sebak Local CA"
+	nodeOrganization = "BOScoin Sebak Node"
+)
+
+func newSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func writeCertificate(certPath, keyPath string, der []byte, priv *ecdsa.PrivateKey) (err error) {
+	var certOut *os.File
+	if certOut, err = os.Create(certPath); err != nil {
+		return
+	}
+	defer certOut.Close()
+
+	if err = pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		return
+	}
+
+	var b []byte
+	if b, err = x509.MarshalECPrivateKey(priv); err != nil {
+		return
+	}
+
+	var keyOut *os.File
+	if keyOut, err = os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600); err != nil {
+		return
+	}
+	defer keyOut.Close()
+
+	return pem.Encode(keyOut, &pem.Block{Type: "EC PRIVATE KEY", Bytes: b})
+}
+
+// GenerateCA creates the local CA certificate and it's key.
+func GenerateCA(certPath, keyPath string) (err error) {
+	var priv *ecdsa.PrivateKey
+	if priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		return
+	}
+
+	var serialNumber *big.Int
+	if serialNumber, err = newSerialNumber(); err != nil {
+		return
+	}
+
+	notBefore := time.Now()
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{caOrganization},
+			CommonName:   caOrganization,
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(CAValidFor),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	var der []byte
+	if der, err = x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv); err != nil {
+		return
+	}
+
+	return writeCertificate(certPath, keyPath, der, priv)
+}
+
+// IssueNodeCertificate issues the certificate of node, which has the public
+// address, `address`. `hosts` are the host names or ip addresses of node.
+// The certificate can be used for both of server and client.
+func IssueNodeCertificate(caCertPath, caKeyPath, address string, hosts []string, certPath, keyPath string) (err error) {
+	if _, err = keypair.Parse(address); err != nil {
+		return
+	}
+
+	var ca tls.Certificate
+	if ca, err = tls.LoadX509KeyPair(caCertPath, caKeyPath); err != nil {
+		return
+	}
+
+	var caCert *x509.Certificate
+	if caCert, err = x509.ParseCertificate(ca.Certificate[0]); err != nil {
+		return
+	}
+	if !caCert.IsCA {
+		return errors.New("not CA certificate")
+	}
+
+	var priv *ecdsa.PrivateKey
+	if priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		return
+	}
+
+	var serialNumber *big.Int
+	if serialNumber, err = newSerialNumber(); err != nil {
+		return
+	}
+
+	notBefore := time.Now()
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{nodeOrganization},
+			CommonName:   address,
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(NodeValidFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	var der []byte
+	if der, err = x509.CreateCertificate(rand.Reader, &template, caCert, &priv.PublicKey, ca.PrivateKey); err != nil {
+		return
+	}
+
+	return writeCertificate(certPath, keyPath, der, priv)
+}
+
+// GetAddressFromCertificate returns the public address of node certificate.
+func GetAddressFromCertificate(cert *x509.Certificate) (address string, err error) {
+	address = cert.Subject.CommonName
+	if _, err = keypair.Parse(address); err != nil {
+		err = fmt.Errorf("certificate is not bound to node address: %v", err)
+		return
+	}
+
+	return
+}
+
+func LoadCertPool(caPath string) (pool *x509.CertPool, err error) {
+	var b []byte
+	if b, err = ioutil.ReadFile(caPath); err != nil {
+		return
+	}
+
+	pool = x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		err = fmt.Errorf("failed to load CA certificate from '%s'", caPath)
+		return
+	}
+
+	return
+}
+
+// NewNodeClientTLSConfig makes the `tls.Config` for the client of mutual TLS.
+// The client presents the node certificate and verifies the server
+// certificate by the local CA. The identity of validator is it's address, not
+// the host name, so the host name is not checked; instead the server
+// certificate must be bound to `address`, and without `address`, every server
+// is refused.
+func NewNodeClientTLSConfig(certFile, keyFile string, pool *x509.CertPool, address string) (config *tls.Config, err error) {
+	var cert tls.Certificate
+	if cert, err = tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		return
+	}
+
+	config = &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyNodeCertificate(rawCerts, pool, address)
+		},
+	}
+
+	return
+}
+
+func verifyNodeCertificate(rawCerts [][]byte, pool *x509.CertPool, address string) (err error) {
+	if len(rawCerts) < 1 {
+		return errors.New("empty server certificate")
+	}
+
+	var certs []*x509.Certificate
+	for _, raw := range rawCerts {
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(raw); err != nil {
+			return
+		}
+		certs = append(certs, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		return
+	}
+
+	if len(address) < 1 {
+		return errors.New("server is not the known validator")
+	}
+
+	var found string
+	if found, err = GetAddressFromCertificate(certs[0]); err != nil {
+		return
+	}
+	if found != address {
+		return fmt.Errorf("server certificate is for '%s', not '%s'", found, address)
+	}
+
+	return
+}
@@ -0,0 +1,174 @@
+package sebaknetwork
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/node"
+)
+
+type testMutualTLS struct {
+	dir    string
+	caCert string
+	caKey  string
+}
+
+func newTestMutualTLS(t *testing.T) *testMutualTLS {
+	dir, err := ioutil.TempDir("", "sebak-mtls")
+	require.Nil(t, err)
+
+	m := &testMutualTLS{
+		dir:    dir,
+		caCert: filepath.Join(dir, "ca.crt"),
+		caKey:  filepath.Join(dir, "ca.key"),
+	}
+	require.Nil(t, GenerateCA(m.caCert, m.caKey))
+
+	return m
+}
+
+func (m *testMutualTLS) issue(t *testing.T, kp keypair.KP) (certPath, keyPath string) {
+	certPath = filepath.Join(m.dir, kp.Address()+".crt")
+	keyPath = filepath.Join(m.dir, kp.Address()+".key")
+	require.Nil(t, IssueNodeCertificate(m.caCert, m.caKey, kp.Address(), []string{"localhost"}, certPath, keyPath))
+
+	return
+}
+
+func (m *testMutualTLS) pool(t *testing.T) *x509.CertPool {
+	pool, err := LoadCertPool(m.caCert)
+	require.Nil(t, err)
+
+	return pool
+}
+
+func (m *testMutualTLS) Close() {
+	os.RemoveAll(m.dir)
+}
+
+func (m *testMutualTLS) createNewHTTP2Network(t *testing.T, kp *keypair.Full) (*HTTP2Network, *sebaknode.LocalNode) {
+	certPath, keyPath := m.issue(t, kp)
+
+	endpoint, err := sebakcommon.NewEndpointFromString(fmt.Sprintf("https://localhost:%s?NodeName=n1", getPort()))
+	require.Nil(t, err)
+
+	queries := endpoint.Query()
+	queries.Add("TLSCertFile", certPath)
+	queries.Add("TLSKeyFile", keyPath)
+	queries.Add("TLSCAFile", m.caCert)
+	endpoint.RawQuery = queries.Encode()
+
+	config, err := NewHTTP2NetworkConfigFromEndpoint(endpoint)
+	require.Nil(t, err)
+
+	n := NewHTTP2Network(config)
+	localNode, _ := sebaknode.NewLocalNode(kp, n.Endpoint(), "")
+	n.SetContext(context.WithValue(context.Background(), "localNode", localNode))
+
+	return n, localNode
+}
+
+func TestIssueNodeCertificate(t *testing.T) {
+	m := newTestMutualTLS(t)
+	defer m.Close()
+
+	kp, _ := keypair.Random()
+	certPath, keyPath := m.issue(t, kp)
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	require.Nil(t, err)
+
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.Nil(t, err)
+
+	address, err := GetAddressFromCertificate(parsed)
+	require.Nil(t, err)
+	require.Equal(t, kp.Address(), address)
+
+	require.Nil(t, verifyNodeCertificate(cert.Certificate, m.pool(t), kp.Address()))
+
+	// bound to the other address
+	kpOther, _ := keypair.Random()
+	require.NotNil(t, verifyNodeCertificate(cert.Certificate, m.pool(t), kpOther.Address()))
+
+	// issued by the other CA
+	other := newTestMutualTLS(t)
+	defer other.Close()
+	require.NotNil(t, verifyNodeCertificate(cert.Certificate, other.pool(t), kp.Address()))
+
+	// without address, not pinned
+	require.NotNil(t, verifyNodeCertificate(cert.Certificate, m.pool(t), ""))
+
+	// invalid address
+	require.NotNil(t, IssueNodeCertificate(m.caCert, m.caKey, "findme", nil, certPath, keyPath))
+}
+
+func TestHTTP2NetworkMutualTLS(t *testing.T) {
+	m := newTestMutualTLS(t)
+	defer m.Close()
+
+	kp, _ := keypair.Random()
+	kpValidator, _ := keypair.Random()
+	kpUnknown, _ := keypair.Random()
+
+	s0, localNode := m.createNewHTTP2Network(t, kp)
+	validator, _ := sebaknode.NewValidator(kpValidator.Address(), &sebakcommon.Endpoint{Scheme: "https", Host: "localhost:1"}, "")
+	localNode.AddValidators(validator)
+
+	s0.SetMessageBroker(TestMessageBroker{})
+	s0.Ready()
+	go s0.Start()
+	defer s0.Stop()
+
+	require.True(t, s0.IsMutualTLS())
+
+	c0 := s0.GetClient(s0.Endpoint())
+	pingAndWait(t, c0)
+
+	newClient := func(kp keypair.KP, address string) *HTTP2NetworkClient {
+		certPath, keyPath := m.issue(t, kp)
+		tlsConfig, err := NewNodeClientTLSConfig(certPath, keyPath, m.pool(t), address)
+		require.Nil(t, err)
+		rawClient, err := sebakcommon.NewHTTP2ClientWithTLS(defaultTimeout, 0, false, tlsConfig)
+		require.Nil(t, err)
+
+		return NewHTTP2NetworkClient(s0.Endpoint(), rawClient)
+	}
+
+	{ // validator
+		b, err := newClient(kpValidator, kp.Address()).GetNodeInfo()
+		require.Nil(t, err)
+		require.NotEmpty(t, b)
+	}
+
+	{ // unknown node
+		b, err := newClient(kpUnknown, kp.Address()).GetNodeInfo()
+		require.Nil(t, err)
+		require.Empty(t, b)
+	}
+
+	{ // without client certificate
+		b, err := NewHTTP2NetworkClient(s0.Endpoint(), nil).GetNodeInfo()
+		require.Nil(t, err)
+		require.Empty(t, b)
+	}
+
+	{ // server is not the expected validator
+		_, err := newClient(kpValidator, kpUnknown.Address()).GetNodeInfo()
+		require.NotNil(t, err)
+	}
+
+	{ // the endpoint of unknown node
+		require.Nil(t, s0.GetClient(&sebakcommon.Endpoint{Scheme: "https", Host: "localhost:2"}))
+	}
+}
@@ -0,0 +1,195 @@
+package sebaknetwork
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/node"
+)
+
+// testNetwork creates the started network of one kind; the same tests run
+// over all the kinds of `Network`.
+type testNetwork struct {
+	name   string
+	create func(t *testing.T) (n Network, localNode *sebaknode.LocalNode, stop func())
+}
+
+var testNetworks = []testNetwork{
+	{name: "memory", create: startTestMemoryNetwork},
+	{name: "http2", create: startTestHTTP2Network},
+	{name: "stream", create: startTestStreamNetwork},
+}
+
+func startTestMemoryNetwork(t *testing.T) (Network, *sebaknode.LocalNode, func()) {
+	_, mn, localNode := createNewMemoryNetwork()
+	go mn.Start()
+
+	return mn, localNode, func() { removeMemoryNetwork(mn) }
+}
+
+func startTestHTTP2Network(t *testing.T) (Network, *sebaknode.LocalNode, func()) {
+	_, hn, localNode := createNewHTTP2Network(t)
+	hn.Ready()
+	go hn.Start()
+
+	pingAndWait(t, hn.GetClient(hn.Endpoint()))
+
+	return hn, localNode, hn.Stop
+}
+
+func startTestStreamNetwork(t *testing.T) (Network, *sebaknode.LocalNode, func()) {
+	sn, localNode := createNewStreamNetwork(t)
+	sn.Ready()
+	go sn.Start()
+
+	pingAndWait(t, sn.GetClient(sn.Endpoint()))
+
+	return sn, localNode, sn.Stop
+}
+
+// collectMessages relays the received messages of network to the buffered
+// channel, so the handlers of network do not block.
+func collectMessages(n Network) chan Message {
+	received := make(chan Message, 100)
+	go func() {
+		for message := range n.ReceiveMessage() {
+			received <- message
+		}
+	}()
+
+	return received
+}
+
+// waitMessage returns the next received message except `ConnectMessage`,
+// which only some networks deliver.
+func waitMessage(t *testing.T, received chan Message) Message {
+	for {
+		select {
+		case message := <-received:
+			if message.Type == ConnectMessage {
+				continue
+			}
+			return message
+		case <-time.After(3 * time.Second):
+			require.Fail(t, "failed to receive message")
+			return Message{}
+		}
+	}
+}
+
+func TestNetworkSuite(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	for _, tn := range testNetworks {
+		tn := tn
+		t.Run(tn.name, func(t *testing.T) {
+			t.Run("GetNodeInfo", func(t *testing.T) { testNetworkGetNodeInfo(t, tn) })
+			t.Run("Connect", func(t *testing.T) { testNetworkConnect(t, tn) })
+			t.Run("Send", func(t *testing.T) { testNetworkSend(t, tn) })
+			t.Run("ConnectionManager", func(t *testing.T) { testNetworkConnectionManager(t, tn) })
+		})
+	}
+}
+
+func testNetworkGetNodeInfo(t *testing.T, tn testNetwork) {
+	n, localNode, stop := tn.create(t)
+	defer stop()
+
+	b, err := n.GetClient(n.Endpoint()).GetNodeInfo()
+	require.Nil(t, err)
+
+	v, err := sebaknode.NewValidatorFromString(b)
+	require.Nil(t, err)
+	require.Equal(t, localNode.Address(), v.Address())
+	require.Equal(t, localNode.Endpoint().String(), v.Endpoint().String())
+}
+
+func testNetworkConnect(t *testing.T, tn testNetwork) {
+	n, localNode, stop := tn.create(t)
+	defer stop()
+	collectMessages(n)
+
+	h, _ := NewHandshake(localNode, nil)
+	b, err := n.GetClient(n.Endpoint()).Connect(h)
+	require.Nil(t, err)
+
+	returned, err := NewHandshakeFromJSON(b)
+	require.Nil(t, err)
+	require.Equal(t, ProtocolVersion, returned.ProtocolVersion)
+	require.Equal(t, Capabilities, returned.Capabilities)
+
+	v, err := returned.Validator()
+	require.Nil(t, err)
+	require.Equal(t, localNode.Address(), v.Address())
+	require.Equal(t, localNode.Endpoint().String(), v.Endpoint().String())
+}
+
+// testNetworkSend checks the messages are received in the sent order.
+func testNetworkSend(t *testing.T, tn testNetwork) {
+	n, _, stop := tn.create(t)
+	defer stop()
+
+	received := collectMessages(n)
+	c0 := n.GetClient(n.Endpoint())
+
+	_, err := c0.SendBallot(NewDummyMessage("0"))
+	require.Nil(t, err)
+	_, err = c0.SendBallots([]sebakcommon.Serializable{NewDummyMessage("1"), NewDummyMessage("2")})
+	require.Nil(t, err)
+	_, err = c0.SendGossip(NewDummyMessage("3"))
+	require.Nil(t, err)
+	_, err = c0.SendMessage(NewDummyMessage("4"))
+	require.Nil(t, err)
+
+	expected := []MessageType{BallotMessage, BallotMessage, BallotMessage, GossipMessage, MessageFromClient}
+	for i, mt := range expected {
+		message := waitMessage(t, received)
+		require.Equal(t, mt, message.Type)
+
+		b, _ := NewDummyMessage(strconv.Itoa(i)).Serialize()
+		require.Equal(t, string(b), string(message.Data))
+	}
+}
+
+// testNetworkConnectionManager checks `ConnectionManager` connects to the
+// validator, broadcasts to it and finds it is down.
+func testNetworkConnectionManager(t *testing.T, tn testNetwork) {
+	n0, node0, stop0 := tn.create(t)
+	defer stop0()
+	collectMessages(n0)
+	n1, node1, stop1 := tn.create(t)
+
+	node0.AddValidators(node1.ConvertToValidator())
+	node1.AddValidators(node0.ConvertToValidator())
+	received := collectMessages(n1)
+
+	policy := &testPolicy{}
+	cm := NewConnectionManager(node0, n0, policy, node0.GetValidators())
+	cm.pingInterval = 10 * time.Millisecond
+	cm.reconnectMin = 10 * time.Millisecond
+	cm.reconnectMax = 20 * time.Millisecond
+	cm.Start()
+	defer cm.Stop()
+
+	require.True(t, waitConnected(cm, node1.Address(), true))
+	require.Equal(t, 1, policy.connected)
+	require.True(t, cm.SupportedByAll(CapabilityBallots))
+
+	cm.Broadcast(NewDummyMessage("findme"))
+
+	message := waitMessage(t, received)
+	require.Equal(t, MessageType(BallotMessage), message.Type)
+	b, _ := NewDummyMessage("findme").Serialize()
+	require.Equal(t, string(b), string(message.Data))
+
+	// node1 goes down
+	stop1()
+
+	require.True(t, waitConnected(cm, node1.Address(), false))
+	require.Equal(t, 0, policy.connected)
+	require.Equal(t, ValidatorStateDisconnected, cm.Health()[0].State)
+}
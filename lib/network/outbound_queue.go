@@ -0,0 +1,296 @@
+package sebaknetwork
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"boscoin.io/sebak/lib/common"
+	logging "github.com/inconshreveable/log15"
+)
+
+var (
+	// DefaultOutboundQueueSize is the maximum number of messages waiting for
+	// one peer; when it is full, the oldest one is dropped.
+	DefaultOutboundQueueSize int = 1000
+
+	// DefaultOutboundRetryMin and DefaultOutboundRetryMax bound the backoff
+	// between the retries of one message.
+	DefaultOutboundRetryMin time.Duration = 100 * time.Millisecond
+	DefaultOutboundRetryMax time.Duration = 5 * time.Second
+
+	// DefaultOutboundMaxRetries is the number of retries before the message
+	// is dropped.
+	DefaultOutboundMaxRetries int = 10
+
+	// DefaultOutboundBatchSize is the maximum number of messages in one
+	// request, and the messages queued within DefaultOutboundBatchWindow are
+	// sent together.
+	DefaultOutboundBatchSize   int           = 100
+	DefaultOutboundBatchWindow time.Duration = 5 * time.Millisecond
+)
+
+var (
+	outboundSentCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "sebak",
+			Subsystem: "network",
+			Name:      "outbound_sent_total",
+			Help:      "Number of the messages sent to peer.",
+		},
+		[]string{"peer"},
+	)
+	outboundRetriesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "sebak",
+			Subsystem: "network",
+			Name:      "outbound_retries_total",
+			Help:      "Number of the failed sendings, which will be retried.",
+		},
+		[]string{"peer"},
+	)
+	outboundDroppedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "sebak",
+			Subsystem: "network",
+			Name:      "outbound_dropped_total",
+			Help:      "Number of the messages dropped before sent to peer.",
+		},
+		[]string{"peer", "reason"},
+	)
+	outboundQueueGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "sebak",
+			Subsystem: "network",
+			Name:      "outbound_queue_length",
+			Help:      "Number of the messages waiting in the queue of peer.",
+		},
+		[]string{"peer"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		outboundSentCounter,
+		outboundRetriesCounter,
+		outboundDroppedCounter,
+		outboundQueueGauge,
+	)
+}
+
+const (
+	outboundDroppedFull       string = "full"
+	outboundDroppedSuperseded string = "superseded"
+	outboundDroppedRetries    string = "retries"
+)
+
+// outboundQueue delivers the messages to one peer in order; the messages
+// queued in short time are sent in one request. The failed messages are
+// retried with backoff, and the message, which waits in the queue is
+// replaced by the newer one for the same message; for example, the ballot of
+// `SIGN` state is superseded by the ballot of `ACCEPT` state.
+type outboundQueue struct {
+	sync.Mutex
+
+	peer  string
+	size  int
+	send  func([]sebakcommon.Message) error
+	items *list.List
+	index map[ /* outboundKey() */ string]*list.Element
+
+	retryMin   time.Duration
+	retryMax   time.Duration
+	maxRetries int
+
+	batchSize   int
+	batchWindow time.Duration
+
+	notify   chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	log      logging.Logger
+}
+
+func newOutboundQueue(peer string, size int, send func([]sebakcommon.Message) error) *outboundQueue {
+	return &outboundQueue{
+		peer:        peer,
+		size:        size,
+		send:        send,
+		items:       list.New(),
+		index:       map[string]*list.Element{},
+		retryMin:    DefaultOutboundRetryMin,
+		retryMax:    DefaultOutboundRetryMax,
+		maxRetries:  DefaultOutboundMaxRetries,
+		batchSize:   DefaultOutboundBatchSize,
+		batchWindow: DefaultOutboundBatchWindow,
+		notify:      make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		log:         log.New(logging.Ctx{"module": "outbound", "peer": peer}),
+	}
+}
+
+// outboundKey is the key of message for superseding. The ballots for the same
+// message share `MessageHash()`.
+func outboundKey(m sebakcommon.Message) string {
+	if b, ok := m.(interface {
+		MessageHash() string
+	}); ok {
+		return b.MessageHash()
+	}
+
+	return m.GetHash()
+}
+
+func (q *outboundQueue) Len() int {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.items.Len()
+}
+
+func (q *outboundQueue) Push(m sebakcommon.Message) {
+	key := outboundKey(m)
+
+	q.Lock()
+	if e, found := q.index[key]; found {
+		e.Value = m
+		q.Unlock()
+
+		outboundDroppedCounter.WithLabelValues(q.peer, outboundDroppedSuperseded).Inc()
+		return
+	}
+
+	if q.items.Len() >= q.size {
+		oldest := q.items.Front()
+		q.items.Remove(oldest)
+		delete(q.index, outboundKey(oldest.Value.(sebakcommon.Message)))
+		outboundDroppedCounter.WithLabelValues(q.peer, outboundDroppedFull).Inc()
+	}
+
+	q.index[key] = q.items.PushBack(m)
+	outboundQueueGauge.WithLabelValues(q.peer).Set(float64(q.items.Len()))
+	q.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop takes at most `n` messages from the front of queue.
+func (q *outboundQueue) pop(n int) (ms []sebakcommon.Message) {
+	q.Lock()
+	defer q.Unlock()
+
+	for len(ms) < n {
+		e := q.items.Front()
+		if e == nil {
+			break
+		}
+
+		q.items.Remove(e)
+		m := e.Value.(sebakcommon.Message)
+		delete(q.index, outboundKey(m))
+		ms = append(ms, m)
+	}
+	outboundQueueGauge.WithLabelValues(q.peer).Set(float64(q.items.Len()))
+
+	return
+}
+
+// isSuperseded checks the newer message for the same key is waiting.
+func (q *outboundQueue) isSuperseded(m sebakcommon.Message) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	_, found := q.index[outboundKey(m)]
+	return found
+}
+
+func (q *outboundQueue) Start() {
+	go q.run()
+}
+
+func (q *outboundQueue) Stop() {
+	q.stopOnce.Do(func() {
+		close(q.stop)
+	})
+}
+
+func (q *outboundQueue) run() {
+	for {
+		if q.Len() < 1 {
+			select {
+			case <-q.notify:
+			case <-q.stop:
+				return
+			}
+		}
+
+		// the messages, which are queued within `batchWindow` are sent
+		// together
+		if q.batchWindow > 0 && q.Len() < q.batchSize {
+			select {
+			case <-time.After(q.batchWindow):
+			case <-q.stop:
+				return
+			}
+		}
+
+		ms := q.pop(q.batchSize)
+		if len(ms) < 1 {
+			continue
+		}
+
+		if !q.deliver(ms) {
+			return
+		}
+	}
+}
+
+// deliver sends the messages until it succeeds or it is given up. It returns
+// `false` when the queue is stopped.
+func (q *outboundQueue) deliver(ms []sebakcommon.Message) bool {
+	backoff := q.retryMin
+	for retries := 0; ; retries++ {
+		err := q.send(ms)
+		if err == nil {
+			outboundSentCounter.WithLabelValues(q.peer).Add(float64(len(ms)))
+			return true
+		}
+
+		if retries >= q.maxRetries {
+			q.log.Error("failed to send messages; dropped", "messages", len(ms), "error", err)
+			outboundDroppedCounter.WithLabelValues(q.peer, outboundDroppedRetries).Add(float64(len(ms)))
+			return true
+		}
+
+		q.log.Debug("failed to send messages; will retry", "messages", len(ms), "error", err, "after", backoff)
+		outboundRetriesCounter.WithLabelValues(q.peer).Inc()
+
+		select {
+		case <-time.After(backoff):
+		case <-q.stop:
+			return false
+		}
+
+		// the superseded messages are not retried
+		var left []sebakcommon.Message
+		for _, m := range ms {
+			if q.isSuperseded(m) {
+				outboundDroppedCounter.WithLabelValues(q.peer, outboundDroppedSuperseded).Inc()
+				continue
+			}
+			left = append(left, m)
+		}
+		if ms = left; len(ms) < 1 {
+			return true
+		}
+
+		if backoff *= 2; backoff > q.retryMax {
+			backoff = q.retryMax
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package sebaknetwork
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+// testBallotMessage has the same message hash with the other states of it.
+type testBallotMessage struct {
+	DummyMessage
+	messageHash string
+}
+
+func (m testBallotMessage) MessageHash() string {
+	return m.messageHash
+}
+
+type testOutboundPeer struct {
+	sync.Mutex
+
+	fails    int
+	received []string
+	requests int
+	sent     chan struct{}
+}
+
+func newTestOutboundPeer(fails int) *testOutboundPeer {
+	return &testOutboundPeer{fails: fails, sent: make(chan struct{}, 100)}
+}
+
+func (p *testOutboundPeer) send(ms []sebakcommon.Message) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.fails > 0 {
+		p.fails--
+		return errors.New("peer is not available")
+	}
+
+	p.requests++
+	for _, m := range ms {
+		if b, ok := m.(testBallotMessage); ok {
+			m = b.DummyMessage
+		}
+		p.received = append(p.received, m.(DummyMessage).Data)
+		p.sent <- struct{}{}
+	}
+
+	return nil
+}
+
+func (p *testOutboundPeer) wait(t *testing.T, n int) []string {
+	for i := 0; i < n; i++ {
+		select {
+		case <-p.sent:
+		case <-time.After(time.Second):
+			require.Fail(t, "failed to receive messages")
+		}
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	return p.received
+}
+
+func newTestOutboundQueue(peer *testOutboundPeer, size int) *outboundQueue {
+	q := newOutboundQueue("peer", size, peer.send)
+	q.retryMin = time.Millisecond
+	q.retryMax = 10 * time.Millisecond
+
+	return q
+}
+
+func TestOutboundQueueOrdered(t *testing.T) {
+	peer := newTestOutboundPeer(3)
+	q := newTestOutboundQueue(peer, 10)
+	defer q.Stop()
+
+	q.Push(NewDummyMessage("0"))
+	q.Push(NewDummyMessage("1"))
+	q.Push(NewDummyMessage("2"))
+	q.Start()
+
+	// the failed message is retried before the next ones
+	require.Equal(t, []string{"0", "1", "2"}, peer.wait(t, 3))
+	require.Equal(t, 0, q.Len())
+}
+
+func TestOutboundQueueSuperseded(t *testing.T) {
+	peer := newTestOutboundPeer(0)
+	q := newTestOutboundQueue(peer, 10)
+	defer q.Stop()
+
+	q.Push(testBallotMessage{DummyMessage: NewDummyMessage("sign"), messageHash: "m0"})
+	q.Push(NewDummyMessage("other"))
+	q.Push(testBallotMessage{DummyMessage: NewDummyMessage("accept"), messageHash: "m0"})
+	require.Equal(t, 2, q.Len())
+
+	q.Start()
+
+	require.Equal(t, []string{"accept", "other"}, peer.wait(t, 2))
+}
+
+func TestOutboundQueueFull(t *testing.T) {
+	peer := newTestOutboundPeer(0)
+	q := newTestOutboundQueue(peer, 2)
+	defer q.Stop()
+
+	q.Push(NewDummyMessage("0"))
+	q.Push(NewDummyMessage("1"))
+	q.Push(NewDummyMessage("2"))
+	require.Equal(t, 2, q.Len())
+
+	q.Start()
+
+	// the oldest one is dropped
+	require.Equal(t, []string{"1", "2"}, peer.wait(t, 2))
+}
+
+func TestOutboundQueueMaxRetries(t *testing.T) {
+	peer := newTestOutboundPeer(3)
+	q := newTestOutboundQueue(peer, 10)
+	q.maxRetries = 2
+	q.batchSize = 1
+	defer q.Stop()
+
+	q.Push(NewDummyMessage("0"))
+	q.Push(NewDummyMessage("1"))
+	q.Start()
+
+	// "0" is given up after 3 tries, and "1" is sent
+	require.Equal(t, []string{"1"}, peer.wait(t, 1))
+}
+
+func TestOutboundQueueBatch(t *testing.T) {
+	peer := newTestOutboundPeer(0)
+	q := newTestOutboundQueue(peer, 10)
+	q.batchSize = 2
+	defer q.Stop()
+
+	q.Push(NewDummyMessage("0"))
+	q.Push(NewDummyMessage("1"))
+	q.Push(NewDummyMessage("2"))
+	q.Start()
+
+	require.Equal(t, []string{"0", "1", "2"}, peer.wait(t, 3))
+
+	peer.Lock()
+	defer peer.Unlock()
+	require.Equal(t, 2, peer.requests)
+}
@@ -0,0 +1,221 @@
+package sebaknetwork
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+var (
+	// DefaultMaxPeers is the maximum number of peers in `PeerTable`.
+	DefaultMaxPeers int = 50
+
+	// MaxPeerScore and MinPeerScore limit the score of peer; the peer, which
+	// reaches `MinPeerScore` is removed from `PeerTable` unless it is pinned.
+	MaxPeerScore int = 100
+	MinPeerScore int = -20
+
+	PeerScoreSuccess int = 1
+	PeerScoreFailure int = -5
+)
+
+// PeerInfo is the public information of peer, which is exchanged between
+// nodes.
+type PeerInfo struct {
+	Address  string `json:"address"`
+	Endpoint string `json:"endpoint"`
+}
+
+type Peer struct {
+	Address  string
+	Endpoint *sebakcommon.Endpoint
+	Score    int
+	LastSeen time.Time
+	// Pinned peer like the validators from the command line is never removed
+	Pinned bool
+}
+
+func (p Peer) Info() PeerInfo {
+	return PeerInfo{Address: p.Address, Endpoint: p.Endpoint.String()}
+}
+
+func (p Peer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"address":   p.Address,
+		"endpoint":  p.Endpoint.String(),
+		"score":     p.Score,
+		"last_seen": p.LastSeen,
+		"pinned":    p.Pinned,
+	})
+}
+
+// PeerTable keeps the known peers with their scores. The table is bounded by
+// `maxSize`; when it is full, the new peer replaces the lowest scored peer
+// only when that score is under the initial score.
+type PeerTable struct {
+	sync.RWMutex
+
+	localAddress string
+	maxSize      int
+	peers        map[ /* Peer.Address */ string]*Peer
+}
+
+func NewPeerTable(localAddress string, maxSize int) *PeerTable {
+	return &PeerTable{
+		localAddress: localAddress,
+		maxSize:      maxSize,
+		peers:        map[string]*Peer{},
+	}
+}
+
+func (t *PeerTable) Len() int {
+	t.RLock()
+	defer t.RUnlock()
+
+	return len(t.peers)
+}
+
+// Add adds the new peer and returns `true` if it is added. The existing peer
+// is updated only for it's endpoint and `pinned`.
+func (t *PeerTable) Add(address string, endpoint *sebakcommon.Endpoint, pinned bool) bool {
+	if address == t.localAddress || endpoint == nil {
+		return false
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	if p, found := t.peers[address]; found {
+		p.Endpoint = endpoint
+		p.Pinned = p.Pinned || pinned
+		return false
+	}
+
+	if len(t.peers) >= t.maxSize && !pinned {
+		lowest := t.lowest()
+		if lowest == nil || lowest.Score >= 0 {
+			return false
+		}
+		delete(t.peers, lowest.Address)
+	}
+
+	t.peers[address] = &Peer{Address: address, Endpoint: endpoint, Pinned: pinned}
+
+	return true
+}
+
+func (t *PeerTable) lowest() (lowest *Peer) {
+	for _, p := range t.peers {
+		if p.Pinned {
+			continue
+		}
+		if lowest == nil || p.Score < lowest.Score {
+			lowest = p
+		}
+	}
+
+	return
+}
+
+func (t *PeerTable) Get(address string) (p Peer, found bool) {
+	t.RLock()
+	defer t.RUnlock()
+
+	var peer *Peer
+	if peer, found = t.peers[address]; found {
+		p = *peer
+	}
+
+	return
+}
+
+func (t *PeerTable) Remove(address string) {
+	t.Lock()
+	defer t.Unlock()
+
+	delete(t.peers, address)
+}
+
+func (t *PeerTable) Succeed(address string) {
+	t.updateScore(address, PeerScoreSuccess)
+}
+
+func (t *PeerTable) Fail(address string) {
+	t.updateScore(address, PeerScoreFailure)
+}
+
+func (t *PeerTable) updateScore(address string, delta int) {
+	t.Lock()
+	defer t.Unlock()
+
+	p, found := t.peers[address]
+	if !found {
+		return
+	}
+
+	p.Score += delta
+	if p.Score > MaxPeerScore {
+		p.Score = MaxPeerScore
+	}
+	if delta > 0 {
+		p.LastSeen = time.Now()
+	}
+
+	if p.Score <= MinPeerScore {
+		if !p.Pinned {
+			delete(t.peers, address)
+			return
+		}
+		p.Score = MinPeerScore
+	}
+}
+
+// Peers returns the peers ordered by score.
+func (t *PeerTable) Peers() []Peer {
+	t.RLock()
+	defer t.RUnlock()
+
+	peers := make([]Peer, 0, len(t.peers))
+	for _, p := range t.peers {
+		peers = append(peers, *p)
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].Score == peers[j].Score {
+			return peers[i].Address < peers[j].Address
+		}
+		return peers[i].Score > peers[j].Score
+	})
+
+	return peers
+}
+
+// Select returns the `n` random peers except `excludes`; the peers under the
+// initial score are selected only when there are not enough peers.
+func (t *PeerTable) Select(n int, excludes ...string) []Peer {
+	var good, bad []Peer
+	for _, p := range t.Peers() {
+		if _, found := sebakcommon.InStringArray(excludes, p.Address); found {
+			continue
+		}
+		if p.Score < 0 {
+			bad = append(bad, p)
+		} else {
+			good = append(good, p)
+		}
+	}
+
+	rand.Shuffle(len(good), func(i, j int) { good[i], good[j] = good[j], good[i] })
+	rand.Shuffle(len(bad), func(i, j int) { bad[i], bad[j] = bad[j], bad[i] })
+
+	selected := append(good, bad...)
+	if len(selected) > n {
+		selected = selected[:n]
+	}
+
+	return selected
+}
@@ -0,0 +1,332 @@
+package sebaknetwork
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/node"
+	logging "github.com/inconshreveable/log15"
+)
+
+// DefaultPeerExchangeInterval is the period of exchanging the known peers.
+var DefaultPeerExchangeInterval time.Duration = 30 * time.Second
+
+// PeerManager keeps the `PeerTable` current by exchanging the known peers
+// with the other nodes, and relays `Gossip` to the peers. With it, the node,
+// which is not listed in the validators of the others can join the network.
+//
+// The peers from the other nodes are only the candidates; the candidate is
+// added to `PeerTable` after it answers the exchange with the envelope, which
+// is signed by the advertised address and has the challenge of request.
+type PeerManager struct {
+	localNode *sebaknode.LocalNode
+	network   Network
+	networkID []byte
+	table     *PeerTable
+	seen      *gossipSeen
+
+	candidatesLock sync.Mutex
+	candidates     map[ /* address */ string]*sebakcommon.Endpoint
+	verifying      int32
+
+	interval time.Duration
+	fanout   int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	log      logging.Logger
+}
+
+func NewPeerManager(localNode *sebaknode.LocalNode, network Network, networkID []byte) *PeerManager {
+	pm := &PeerManager{
+		localNode:  localNode,
+		network:    network,
+		networkID:  networkID,
+		table:      NewPeerTable(localNode.Address(), DefaultMaxPeers),
+		seen:       newGossipSeen(DefaultGossipSeenSize),
+		candidates: map[string]*sebakcommon.Endpoint{},
+		interval:   DefaultPeerExchangeInterval,
+		fanout:     DefaultGossipFanout,
+		stop:       make(chan struct{}),
+		log:        log.New(logging.Ctx{"node": localNode.Alias(), "module": "peer"}),
+	}
+
+	// validators are always the peers
+	for _, v := range localNode.GetValidators() {
+		pm.table.Add(v.Address(), v.Endpoint(), true)
+	}
+
+	return pm
+}
+
+func (pm *PeerManager) Table() *PeerTable {
+	return pm.table
+}
+
+func (pm *PeerManager) Start() {
+	go func() {
+		pm.exchange()
+
+		ticker := time.NewTicker(pm.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				pm.exchange()
+			case <-pm.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (pm *PeerManager) Stop() {
+	pm.stopOnce.Do(func() {
+		close(pm.stop)
+	})
+}
+
+// PeerInfos returns the local node and the known peers, which are not under
+// the initial score.
+func (pm *PeerManager) PeerInfos() []PeerInfo {
+	infos := []PeerInfo{
+		{Address: pm.localNode.Address(), Endpoint: pm.localNode.Endpoint().String()},
+	}
+	for _, p := range pm.table.Peers() {
+		if p.Score < 0 {
+			continue
+		}
+		infos = append(infos, p.Info())
+	}
+
+	return infos
+}
+
+// MergePeerInfos adds the unknown peers to the candidates, which will be
+// verified by the next exchange. It returns the number of new candidates.
+func (pm *PeerManager) MergePeerInfos(infos []PeerInfo) (added int) {
+	pm.candidatesLock.Lock()
+	defer pm.candidatesLock.Unlock()
+
+	for _, info := range infos {
+		if len(pm.candidates) >= DefaultMaxPeers {
+			break
+		}
+		if info.Address == pm.localNode.Address() {
+			continue
+		}
+		if _, found := pm.table.Get(info.Address); found {
+			continue
+		}
+		if _, found := pm.candidates[info.Address]; found {
+			continue
+		}
+
+		endpoint, err := sebakcommon.NewEndpointFromString(info.Endpoint)
+		if err != nil {
+			continue
+		}
+		pm.candidates[info.Address] = endpoint
+		added++
+	}
+
+	return
+}
+
+// popCandidates takes out the `n` candidates at most.
+func (pm *PeerManager) popCandidates(n int) map[string]*sebakcommon.Endpoint {
+	pm.candidatesLock.Lock()
+	defer pm.candidatesLock.Unlock()
+
+	popped := map[string]*sebakcommon.Endpoint{}
+	for address, endpoint := range pm.candidates {
+		if len(popped) >= n {
+			break
+		}
+		popped[address] = endpoint
+		delete(pm.candidates, address)
+	}
+
+	return popped
+}
+
+// peerExchange is the message of exchange; the response has the challenge
+// of request and it is signed by the responding node in `Envelope`.
+type peerExchange struct {
+	Challenge string     `json:"challenge"`
+	Peers     []PeerInfo `json:"peers"`
+}
+
+func (p peerExchange) Serialize() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// Exchange merges the peers from the other node and returns the known peers
+// of local node in the signed `Envelope`. It is called by the handler of
+// network.
+func (pm *PeerManager) Exchange(b []byte) (o []byte, err error) {
+	var request peerExchange
+	if err = json.Unmarshal(b, &request); err != nil {
+		return
+	}
+	if pm.MergePeerInfos(request.Peers) > 0 {
+		go pm.verifyCandidates()
+	}
+
+	var body []byte
+	if body, err = (peerExchange{Challenge: request.Challenge, Peers: pm.PeerInfos()}).Serialize(); err != nil {
+		return
+	}
+
+	var e Envelope
+	if e, err = NewEnvelope(pm.localNode.Keypair(), pm.networkID, body); err != nil {
+		return
+	}
+
+	return e.Serialize()
+}
+
+// exchangeWith exchanges the peers with the node of `endpoint` and checks the
+// response is signed by `address`.
+func (pm *PeerManager) exchangeWith(address string, endpoint *sebakcommon.Endpoint) (peers []PeerInfo, err error) {
+	client := pm.network.GetClient(endpoint)
+	if client == nil {
+		err = sebakerror.ErrorInvalidState
+		return
+	}
+
+	request := peerExchange{Challenge: sebakcommon.GenerateUUID(), Peers: pm.PeerInfos()}
+
+	var b []byte
+	if b, err = client.ExchangePeers(request); err != nil {
+		return
+	}
+
+	var e Envelope
+	if e, err = NewEnvelopeFromJSON(b); err != nil {
+		return
+	}
+	if e.H.NetworkID != string(pm.networkID) {
+		err = sebakerror.ErrorEnvelopeNetworkIDMismatch
+		return
+	}
+	if e.H.Sender != address {
+		err = sebakerror.ErrorHandshakeSenderMismatch
+		return
+	}
+	if err = e.VerifySignature(); err != nil {
+		return
+	}
+
+	var response peerExchange
+	if err = json.Unmarshal(e.B, &response); err != nil {
+		return
+	}
+	if response.Challenge != request.Challenge {
+		err = sebakerror.ErrorEnvelopeReplayed
+		return
+	}
+
+	peers = response.Peers
+	return
+}
+
+func (pm *PeerManager) exchange() {
+	for _, p := range pm.table.Select(pm.fanout) {
+		received, err := pm.exchangeWith(p.Address, p.Endpoint)
+		if err != nil {
+			pm.log.Debug("failed to exchange peers", "peer", p.Address, "error", err)
+			pm.table.Fail(p.Address)
+			continue
+		}
+		pm.table.Succeed(p.Address)
+
+		if added := pm.MergePeerInfos(received); added > 0 {
+			pm.log.Debug("new candidates found", "peer", p.Address, "added", added)
+		}
+	}
+
+	pm.verifyCandidates()
+}
+
+// verifyCandidates exchanges with the candidates and the candidate, which
+// proves it's address becomes peer. Only one verification runs at once.
+func (pm *PeerManager) verifyCandidates() {
+	if !atomic.CompareAndSwapInt32(&pm.verifying, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&pm.verifying, 0)
+
+	for address, endpoint := range pm.popCandidates(pm.fanout) {
+		received, err := pm.exchangeWith(address, endpoint)
+		if err != nil {
+			pm.log.Debug("candidate is refused", "peer", address, "error", err)
+			continue
+		}
+		if pm.table.Add(address, endpoint, false) {
+			pm.table.Succeed(address)
+			pm.log.Debug("new peer found", "peer", address)
+		}
+		pm.MergePeerInfos(received)
+	}
+}
+
+// Gossip starts to relay new `Gossip` from local node.
+func (pm *PeerManager) Gossip(gossipType, id string, data []byte) {
+	g, err := NewGossip(gossipType, id, pm.localNode.Keypair(), pm.networkID, data)
+	if err != nil {
+		pm.log.Error("failed to make gossip", "error", err)
+		return
+	}
+	if !pm.seen.add(g.key()) {
+		return
+	}
+
+	pm.relay(g)
+}
+
+// Receive checks the received `Gossip` and relays it to the other peers. It
+// returns `false` when the `Gossip` was already seen. The `Gossip`, which is
+// not signed by it's origin is refused.
+func (pm *PeerManager) Receive(b []byte) (g Gossip, isNew bool, err error) {
+	if g, err = NewGossipFromJSON(b); err != nil {
+		return
+	}
+	if err = g.VerifySignature(pm.networkID); err != nil {
+		return
+	}
+
+	if isNew = pm.seen.add(g.key()); !isNew {
+		return
+	}
+
+	if g.TTL--; g.TTL > 0 {
+		pm.relay(g)
+	}
+
+	return
+}
+
+func (pm *PeerManager) relay(g Gossip) {
+	for _, p := range pm.table.Select(pm.fanout, g.Origin) {
+		go func(p Peer) {
+			client := pm.network.GetClient(p.Endpoint)
+			if client == nil {
+				pm.table.Fail(p.Address)
+				return
+			}
+
+			if _, err := client.SendGossip(g); err != nil {
+				pm.log.Debug("failed to send gossip", "peer", p.Address, "error", err)
+				pm.table.Fail(p.Address)
+				return
+			}
+			pm.table.Succeed(p.Address)
+		}(p)
+	}
+}
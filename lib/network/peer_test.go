@@ -0,0 +1,278 @@
+package sebaknetwork
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/node"
+)
+
+func newTestPeerAddress() string {
+	kp, _ := keypair.Random()
+	return kp.Address()
+}
+
+func TestPeerTableAdd(t *testing.T) {
+	local := newTestPeerAddress()
+	table := NewPeerTable(local, 2)
+
+	// local node is not a peer
+	require.False(t, table.Add(local, CreateNewMemoryEndpoint(), false))
+	require.False(t, table.Add(newTestPeerAddress(), nil, false))
+
+	a0, a1 := newTestPeerAddress(), newTestPeerAddress()
+	require.True(t, table.Add(a0, CreateNewMemoryEndpoint(), false))
+	require.False(t, table.Add(a0, CreateNewMemoryEndpoint(), true))
+	require.True(t, table.Add(a1, CreateNewMemoryEndpoint(), false))
+	require.Equal(t, 2, table.Len())
+
+	p0, found := table.Get(a0)
+	require.True(t, found)
+	require.True(t, p0.Pinned)
+
+	// the table is full and no peer is under the initial score
+	a2 := newTestPeerAddress()
+	require.False(t, table.Add(a2, CreateNewMemoryEndpoint(), false))
+
+	// the bad peer is replaced
+	table.Fail(a1)
+	require.True(t, table.Add(a2, CreateNewMemoryEndpoint(), false))
+	_, found = table.Get(a1)
+	require.False(t, found)
+	require.Equal(t, 2, table.Len())
+}
+
+func TestPeerTableScore(t *testing.T) {
+	table := NewPeerTable(newTestPeerAddress(), DefaultMaxPeers)
+
+	pinned, other := newTestPeerAddress(), newTestPeerAddress()
+	table.Add(pinned, CreateNewMemoryEndpoint(), true)
+	table.Add(other, CreateNewMemoryEndpoint(), false)
+
+	table.Succeed(other)
+	p, _ := table.Get(other)
+	require.Equal(t, PeerScoreSuccess, p.Score)
+	require.False(t, p.LastSeen.IsZero())
+
+	peers := table.Peers()
+	require.Equal(t, other, peers[0].Address)
+	require.Equal(t, pinned, peers[1].Address)
+
+	for i := 0; i < (MaxPeerScore-MinPeerScore)/-PeerScoreFailure+1; i++ {
+		table.Fail(pinned)
+		table.Fail(other)
+	}
+
+	// the pinned peer is never removed
+	p, found := table.Get(pinned)
+	require.True(t, found)
+	require.Equal(t, MinPeerScore, p.Score)
+
+	_, found = table.Get(other)
+	require.False(t, found)
+}
+
+func TestPeerTableSelect(t *testing.T) {
+	table := NewPeerTable(newTestPeerAddress(), DefaultMaxPeers)
+
+	var addresses []string
+	for i := 0; i < 5; i++ {
+		address := newTestPeerAddress()
+		table.Add(address, CreateNewMemoryEndpoint(), false)
+		addresses = append(addresses, address)
+	}
+	table.Fail(addresses[0])
+
+	selected := table.Select(10, addresses[1])
+	require.Equal(t, 4, len(selected))
+	for _, p := range selected {
+		require.NotEqual(t, addresses[1], p.Address)
+	}
+	// the bad peer comes last
+	require.Equal(t, addresses[0], selected[3].Address)
+
+	require.Equal(t, 2, len(table.Select(2)))
+}
+
+var testPeerNetworkID = []byte("sebak-test-network")
+
+func createNewPeerManager() (*MemoryNetwork, *PeerManager) {
+	_, mn, localNode := createNewMemoryNetwork()
+	pm := NewPeerManager(localNode, mn, testPeerNetworkID)
+
+	ctx := context.WithValue(context.Background(), "localNode", localNode)
+	ctx = context.WithValue(ctx, "peerManager", pm)
+	mn.SetContext(ctx)
+
+	go mn.Start()
+
+	return mn, pm
+}
+
+func TestPeerManagerExchange(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	_, pm0 := createNewPeerManager()
+	_, pm1 := createNewPeerManager()
+	_, pm2 := createNewPeerManager()
+
+	// pm0 knows only pm1 and pm1 knows only pm2
+	pm0.Table().Add(pm1.localNode.Address(), pm1.localNode.Endpoint(), false)
+	pm1.Table().Add(pm2.localNode.Address(), pm2.localNode.Endpoint(), false)
+
+	pm0.exchange()
+
+	// pm2 is verified by pm0
+	_, found := pm0.Table().Get(pm2.localNode.Address())
+	require.True(t, found)
+
+	// pm0 is verified by pm1 after the exchange
+	for i := 0; i < 10; i++ {
+		if _, found = pm1.Table().Get(pm0.localNode.Address()); found {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	require.True(t, found)
+
+	p, _ := pm0.Table().Get(pm1.localNode.Address())
+	require.Equal(t, PeerScoreSuccess, p.Score)
+}
+
+func TestPeerManagerExchangeForgedPeer(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	_, pm0 := createNewPeerManager()
+	_, pm1 := createNewPeerManager()
+
+	// the endpoint of pm1 is advertised with the other address
+	forged := newTestPeerAddress()
+	added := pm0.MergePeerInfos([]PeerInfo{{Address: forged, Endpoint: pm1.localNode.Endpoint().String()}})
+	require.Equal(t, 1, added)
+
+	pm0.exchange()
+
+	_, found := pm0.Table().Get(forged)
+	require.False(t, found)
+	_, found = pm0.Table().Get(pm1.localNode.Address())
+	require.False(t, found)
+}
+
+func TestPeerManagerExchangeWithValidators(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	_, mn, localNode := createNewMemoryNetwork()
+	_, pm1 := createNewPeerManager()
+
+	validator, _ := sebaknode.NewValidator(pm1.localNode.Address(), pm1.localNode.Endpoint(), "")
+	localNode.AddValidators(validator)
+
+	pm := NewPeerManager(localNode, mn, testPeerNetworkID)
+	p, found := pm.Table().Get(pm1.localNode.Address())
+	require.True(t, found)
+	require.True(t, p.Pinned)
+}
+
+func TestPeerManagerGossip(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	_, pm0 := createNewPeerManager()
+	mn1, pm1 := createNewPeerManager()
+	mn2, pm2 := createNewPeerManager()
+
+	pm0.Table().Add(pm1.localNode.Address(), pm1.localNode.Endpoint(), false)
+	pm1.Table().Add(pm0.localNode.Address(), pm0.localNode.Endpoint(), false)
+	pm1.Table().Add(pm2.localNode.Address(), pm2.localNode.Endpoint(), false)
+
+	receive := func(mn *MemoryNetwork) Message {
+		select {
+		case message := <-mn.ReceiveMessage():
+			return message
+		case <-time.After(time.Second):
+			require.Fail(t, "failed to get gossip")
+		}
+		return Message{}
+	}
+
+	pm0.Gossip(GossipTransaction, "tx-hash", []byte(`{"a":1}`))
+
+	message := receive(mn1)
+	require.Equal(t, MessageType(GossipMessage), message.Type)
+
+	g, isNew, err := pm1.Receive(message.Data)
+	require.Nil(t, err)
+	require.True(t, isNew)
+	require.Equal(t, pm0.localNode.Address(), g.Origin)
+	require.Equal(t, DefaultGossipTTL-1, g.TTL)
+	require.Equal(t, `{"a":1}`, string(g.Data))
+
+	// relayed to pm2, but not back to the origin
+	message = receive(mn2)
+	g, isNew, err = pm2.Receive(message.Data)
+	require.Nil(t, err)
+	require.True(t, isNew)
+	require.Equal(t, DefaultGossipTTL-2, g.TTL)
+
+	// duplicated gossip is dropped
+	_, isNew, err = pm1.Receive(message.Data)
+	require.Nil(t, err)
+	require.False(t, isNew)
+}
+
+func TestPeerManagerGossipForgedOrigin(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	_, pm0 := createNewPeerManager()
+
+	kp, _ := keypair.Random()
+	g, err := NewGossip(GossipTransaction, "tx-hash", kp, testPeerNetworkID, []byte(`{"a": 1}`))
+	require.Nil(t, err)
+
+	// the origin is replaced
+	g.Origin = newTestPeerAddress()
+	b, _ := g.Serialize()
+	_, _, err = pm0.Receive(b)
+	require.Equal(t, sebakerror.ErrorSignatureVerificationFailed, err)
+
+	// the other network
+	g, _ = NewGossip(GossipTransaction, "tx-hash", kp, []byte("other-network"), []byte(`{}`))
+	b, _ = g.Serialize()
+	_, _, err = pm0.Receive(b)
+	require.Equal(t, sebakerror.ErrorSignatureVerificationFailed, err)
+
+	// the forged gossip is not seen, so the valid one is received
+	g, _ = NewGossip(GossipTransaction, "tx-hash", kp, testPeerNetworkID, []byte(`{"a": 1}`))
+	b, _ = g.Serialize()
+	_, isNew, err := pm0.Receive(b)
+	require.Nil(t, err)
+	require.True(t, isNew)
+}
+
+func TestPeerManagerGossipTTL(t *testing.T) {
+	defer CleanUpMemoryNetwork()
+
+	mn0, pm0 := createNewPeerManager()
+	_, pm1 := createNewPeerManager()
+	pm1.Table().Add(pm0.localNode.Address(), pm0.localNode.Endpoint(), false)
+
+	kp, _ := keypair.Random()
+	g, _ := NewGossip(GossipTransaction, "tx-hash", kp, testPeerNetworkID, []byte(`{}`))
+	g.TTL = 1
+	b, _ := g.Serialize()
+
+	_, isNew, err := pm1.Receive(b)
+	require.Nil(t, err)
+	require.True(t, isNew)
+
+	// gossip, which runs out of TTL is not relayed
+	select {
+	case <-mn0.ReceiveMessage():
+		require.Fail(t, "gossip must not be relayed")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,143 @@
+package sebaknetwork
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is the error response of handlers by the 'Problem Details for HTTP
+// APIs'(RFC 7807). `Code` is the code of `sebakerror.Error` and `Data` has
+// the context of error, like the address of missing account.
+type Problem struct {
+	Type   string                 `json:"type"`
+	Title  string                 `json:"title"`
+	Status int                    `json:"status"`
+	Detail string                 `json:"detail,omitempty"`
+	Code   uint                   `json:"code,omitempty"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+}
+
+// problemStatuses maps the code of `sebakerror.Error` to the http status; the
+// code, which is not in here uses the status of handler.
+var problemStatuses = map[uint]int{
+	sebakerror.ErrorBlockAlreadyExists.Code:            http.StatusConflict,
+	sebakerror.ErrorHashDoesNotMatch.Code:              http.StatusBadRequest,
+	sebakerror.ErrorSignatureVerificationFailed.Code:   http.StatusBadRequest,
+	sebakerror.ErrorBadPublicAddress.Code:              http.StatusBadRequest,
+	sebakerror.ErrorInvalidFee.Code:                    http.StatusBadRequest,
+	sebakerror.ErrorInvalidOperation.Code:              http.StatusBadRequest,
+	sebakerror.ErrorNewButKnownMessage.Code:            http.StatusConflict,
+	sebakerror.ErrorInvalidHash.Code:                   http.StatusBadRequest,
+	sebakerror.ErrorInvalidMessage.Code:                http.StatusBadRequest,
+	sebakerror.ErrorVotingResultNotFound.Code:          http.StatusNotFound,
+	sebakerror.ErrorTransactionEmptyOperations.Code:    http.StatusBadRequest,
+	sebakerror.ErrorAlreadySaved.Code:                  http.StatusConflict,
+	sebakerror.ErrorDuplicatedOperation.Code:           http.StatusBadRequest,
+	sebakerror.ErrorUnknownOperationType.Code:          http.StatusBadRequest,
+	sebakerror.ErrorTypeOperationBodyNotMatched.Code:   http.StatusBadRequest,
+	sebakerror.ErrorBlockAccountDoesNotExists.Code:     http.StatusNotFound,
+	sebakerror.ErrorBlockAccountAlreadyExists.Code:     http.StatusConflict,
+	sebakerror.ErrorAccountBalanceUnderZero.Code:       http.StatusBadRequest,
+	sebakerror.ErrorMaximumBalanceReached.Code:         http.StatusBadRequest,
+	sebakerror.ErrorStorageRecordDoesNotExist.Code:     http.StatusNotFound,
+	sebakerror.ErrorTransactionInvalidCheckpoint.Code:  http.StatusBadRequest,
+	sebakerror.ErrorBlockTransactionDoesNotExists.Code: http.StatusNotFound,
+	sebakerror.ErrorBlockOperationDoesNotExists.Code:   http.StatusNotFound,
+	sebakerror.ErrorStorageRecordAlreadyExists.Code:    http.StatusConflict,
+	sebakerror.ErrorReindexMessageNotFound.Code:        http.StatusNotFound,
+	sebakerror.ErrorEnvelopeUnknownSender.Code:         http.StatusForbidden,
+	sebakerror.ErrorEnvelopeNetworkIDMismatch.Code:     http.StatusUnauthorized,
+	sebakerror.ErrorEnvelopeExpired.Code:               http.StatusUnauthorized,
+	sebakerror.ErrorEnvelopeReplayed.Code:              http.StatusUnauthorized,
+	sebakerror.ErrorHandshakeNetworkIDMismatch.Code:    http.StatusBadRequest,
+	sebakerror.ErrorHandshakeIncompatibleProtocol.Code: http.StatusBadRequest,
+	sebakerror.ErrorHandshakeSenderMismatch.Code:       http.StatusForbidden,
+	sebakerror.ErrorTransactionRejected.Code:           http.StatusBadRequest,
+	sebakerror.ErrorInvalidSubscription.Code:           http.StatusBadRequest,
+	sebakerror.ErrorTooManySubscriptions.Code:          http.StatusBadRequest,
+	sebakerror.ErrorSubscriptionNotFound.Code:          http.StatusNotFound,
+	sebakerror.ErrorNodeStopped.Code:                   http.StatusServiceUnavailable,
+}
+
+// ProblemStatus returns the http status of `err`; `status` is used for the
+// error, which is not `sebakerror.Error` or is not mapped.
+func ProblemStatus(err error, status int) int {
+	if e, ok := err.(*sebakerror.Error); ok {
+		if s, found := problemStatuses[e.Code]; found {
+			return s
+		}
+	}
+
+	return status
+}
+
+// problemInternalDetail is the `Detail` of the server error, which is not
+// `sebakerror.Error`; the error itself is only logged.
+const problemInternalDetail = "internal error occurred; see the log of node"
+
+// NewProblem makes `Problem` of `err`; `err` can be nil for the plain http
+// error, like `405 Method Not Allowed`. The text of the unknown server error
+// is not sent to the client.
+func NewProblem(status int, err error) Problem {
+	p := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+	}
+
+	switch e := err.(type) {
+	case nil:
+	case *sebakerror.Error:
+		p.Code = e.Code
+		p.Detail = e.Message
+	default:
+		if status >= http.StatusInternalServerError {
+			log.Error("internal error in handler", "status", status, "error", err)
+			p.Detail = problemInternalDetail
+			break
+		}
+		p.Detail = err.Error()
+	}
+
+	return p
+}
+
+// With returns the copied `Problem`, which has the context value.
+func (p Problem) With(key string, value interface{}) Problem {
+	data := map[string]interface{}{key: value}
+	for k, v := range p.Data {
+		if _, found := data[k]; !found {
+			data[k] = v
+		}
+	}
+	p.Data = data
+
+	return p
+}
+
+func (p Problem) Serialize() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func (p Problem) Write(w http.ResponseWriter) {
+	b, err := p.Serialize()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(b)
+}
+
+// WriteProblem writes the `Problem` of `err`; see `ProblemStatus` for
+// `status`.
+func WriteProblem(w http.ResponseWriter, status int, err error) {
+	NewProblem(ProblemStatus(err, status), err).Write(w)
+}
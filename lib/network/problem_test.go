@@ -0,0 +1,84 @@
+package sebaknetwork
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+func TestProblemStatus(t *testing.T) {
+	require.Equal(t, http.StatusNotFound, ProblemStatus(sebakerror.ErrorBlockAccountDoesNotExists, http.StatusInternalServerError))
+	require.Equal(t, http.StatusForbidden, ProblemStatus(sebakerror.ErrorEnvelopeUnknownSender, http.StatusUnauthorized))
+	require.Equal(t, http.StatusUnauthorized, ProblemStatus(sebakerror.ErrorEnvelopeExpired, http.StatusUnauthorized))
+
+	// not mapped
+	require.Equal(t, http.StatusInternalServerError, ProblemStatus(sebakerror.ErrorVotingResultFailedToClose, http.StatusInternalServerError))
+	require.Equal(t, http.StatusBadRequest, ProblemStatus(errors.New("showme"), http.StatusBadRequest))
+	require.Equal(t, http.StatusMethodNotAllowed, ProblemStatus(nil, http.StatusMethodNotAllowed))
+}
+
+func TestWriteProblem(t *testing.T) {
+	read := func(w *httptest.ResponseRecorder) (p Problem) {
+		require.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &p))
+		require.Equal(t, w.Code, p.Status)
+		return
+	}
+
+	{ // `sebakerror.Error`
+		w := httptest.NewRecorder()
+		WriteProblem(w, http.StatusInternalServerError, sebakerror.ErrorBlockAccountDoesNotExists)
+
+		p := read(w)
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Equal(t, "about:blank", p.Type)
+		require.Equal(t, http.StatusText(http.StatusNotFound), p.Title)
+		require.Equal(t, sebakerror.ErrorBlockAccountDoesNotExists.Code, p.Code)
+		require.Equal(t, sebakerror.ErrorBlockAccountDoesNotExists.Message, p.Detail)
+	}
+
+	{ // plain http error
+		w := httptest.NewRecorder()
+		WriteProblem(w, http.StatusMethodNotAllowed, nil)
+
+		p := read(w)
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		require.Equal(t, uint(0), p.Code)
+		require.Empty(t, p.Detail)
+	}
+
+	{ // the unknown server error is not sent
+		w := httptest.NewRecorder()
+		WriteProblem(w, http.StatusInternalServerError, errors.New("open /secret/db: permission denied"))
+
+		p := read(w)
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Equal(t, problemInternalDetail, p.Detail)
+	}
+
+	{ // the unknown client error is sent
+		w := httptest.NewRecorder()
+		WriteProblem(w, http.StatusBadRequest, errors.New("showme"))
+
+		p := read(w)
+		require.Equal(t, "showme", p.Detail)
+	}
+
+	{ // with context
+		w := httptest.NewRecorder()
+		problem := NewProblem(http.StatusNotFound, sebakerror.ErrorBlockTransactionDoesNotExists)
+		problem.With("hash", "showme").With("source", "findme").Write(w)
+
+		p := read(w)
+		require.Equal(t, map[string]interface{}{"hash": "showme", "source": "findme"}, p.Data)
+
+		// `With` does not change the original
+		require.Nil(t, problem.Data)
+	}
+}
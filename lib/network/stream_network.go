@@ -0,0 +1,249 @@
+package sebaknetwork
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+// StreamNetworkScheme is the endpoint scheme of `StreamNetwork`, like
+// `https+stream://localhost:12345`.
+const StreamNetworkScheme string = "https+stream"
+
+const streamFrameHello MessageType = "hello"
+
+// MaxStreamFrameSize limits the size of one frame in stream.
+var MaxStreamFrameSize uint32 = 32 * 1024 * 1024
+
+// streamFrame is the unit of messages in stream; in the wire, it is prefixed
+// by it's length in 4 bytes.
+type streamFrame struct {
+	Type MessageType     `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+func writeStreamFrame(w io.Writer, f streamFrame) (err error) {
+	var b []byte
+	if b, err = json.Marshal(f); err != nil {
+		return
+	}
+
+	head := make([]byte, 4)
+	binary.BigEndian.PutUint32(head, uint32(len(b)))
+	if _, err = w.Write(append(head, b...)); err != nil {
+		return
+	}
+
+	return
+}
+
+func readStreamFrame(r io.Reader) (f streamFrame, err error) {
+	head := make([]byte, 4)
+	if _, err = io.ReadFull(r, head); err != nil {
+		return
+	}
+
+	size := binary.BigEndian.Uint32(head)
+	if size > MaxStreamFrameSize {
+		err = fmt.Errorf("too large frame: %d", size)
+		return
+	}
+
+	b := make([]byte, size)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return
+	}
+	err = json.Unmarshal(b, &f)
+
+	return
+}
+
+// StreamNetwork is the `HTTP2Network`, which keeps one long-lived HTTP2
+// stream to each validator; the ballots and messages are sent by the frames
+// in the stream instead of the requests. The node router and api router are
+// same with `HTTP2Network`, so the validators of `HTTP2Network` still can
+// talk with it.
+type StreamNetwork struct {
+	*HTTP2Network
+
+	sync.Mutex
+	clients map[ /* Endpoint.String() */ string]*StreamNetworkClient
+
+	// streams are the bodies of the opened streams from the other nodes;
+	// they are closed by `Stop`.
+	streams  map[io.Closer]struct{}
+	handlers sync.WaitGroup
+	stopped  bool
+}
+
+func NewStreamNetwork(config HTTP2NetworkConfig) *StreamNetwork {
+	return &StreamNetwork{
+		HTTP2Network: NewHTTP2Network(config),
+		clients:      map[string]*StreamNetworkClient{},
+		streams:      map[io.Closer]struct{}{},
+	}
+}
+
+func (t *StreamNetwork) Endpoint() *sebakcommon.Endpoint {
+	endpoint := t.HTTP2Network.Endpoint()
+	endpoint.Scheme = StreamNetworkScheme
+
+	return endpoint
+}
+
+// GetClient returns the client, which shares the stream to `endpoint`. The
+// endpoint, which is not `StreamNetworkScheme` gets the `HTTP2NetworkClient`.
+func (t *StreamNetwork) GetClient(endpoint *sebakcommon.Endpoint) NetworkClient {
+	if endpoint.Scheme != StreamNetworkScheme {
+		return t.HTTP2Network.GetClient(endpoint)
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	if client, found := t.clients[endpoint.String()]; found {
+		return client
+	}
+
+	httpEndpoint := &sebakcommon.Endpoint{}
+	*httpEndpoint = *endpoint
+	httpEndpoint.Scheme = "https"
+
+	client, ok := t.HTTP2Network.GetClient(httpEndpoint).(*HTTP2NetworkClient)
+	if !ok || client == nil {
+		return nil
+	}
+
+	// the stream is not closed by timeout
+	rawClient, err := t.newHTTP2Client(httpEndpoint, 0, 0, true)
+	if err != nil {
+		log.Error("failed to create client", "endpoint", endpoint, "error", err)
+		return nil
+	}
+
+	c := NewStreamNetworkClient(endpoint, client, rawClient)
+	t.clients[endpoint.String()] = c
+
+	return c
+}
+
+func (t *StreamNetwork) Ready() (err error) {
+	if err = t.HTTP2Network.Ready(); err != nil {
+		return
+	}
+
+	nodeRouter := t.routers[RouterNameNode]
+	nodeRouter.HandleFunc("/stream", t.requireNodeCertificate(http.HandlerFunc(StreamHandler(t.Context(), t)), t.isKnownNode)).Methods("POST")
+
+	return
+}
+
+// Stop closes the streams from both sides and waits until the handlers of
+// the streams return, so no message is received after it.
+func (t *StreamNetwork) Stop() {
+	t.Lock()
+	t.stopped = true
+	for _, c := range t.clients {
+		c.Close()
+	}
+	t.clients = map[string]*StreamNetworkClient{}
+	for body := range t.streams {
+		body.Close()
+	}
+	t.Unlock()
+
+	t.handlers.Wait()
+	t.HTTP2Network.Stop()
+}
+
+// openStream registers the body of new stream; after `Stop`, it returns
+// `false`.
+func (t *StreamNetwork) openStream(body io.Closer) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.stopped {
+		return false
+	}
+	t.streams[body] = struct{}{}
+	t.handlers.Add(1)
+
+	return true
+}
+
+func (t *StreamNetwork) closeStream(body io.Closer) {
+	t.Lock()
+	delete(t.streams, body)
+	t.Unlock()
+
+	t.handlers.Done()
+}
+
+// StreamHandler receives the frames from the stream of the other node. The
+// first frame must be the `Envelope` from the known validator; the following
+// frames are handled like the messages from the requests.
+func StreamHandler(ctx context.Context, sn *StreamNetwork) HandlerFunc {
+	t := sn.HTTP2Network
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		if !sn.openStream(r.Body) {
+			WriteProblem(w, http.StatusServiceUnavailable, nil)
+			return
+		}
+		defer sn.closeStream(r.Body)
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			WriteProblem(w, http.StatusInternalServerError, errors.New("stream is not supported"))
+			return
+		}
+
+		reader := bufio.NewReader(r.Body)
+
+		hello, err := readStreamFrame(reader)
+		if err != nil || hello.Type != streamFrameHello {
+			WriteProblem(w, http.StatusBadRequest, nil)
+			return
+		}
+		e, err := openEnvelope(t.envelopes, hello.Data)
+		if err != nil {
+			WriteProblem(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		// the client waits for the response header before sending frames
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		log.Debug("stream is opened", "node", e.H.Sender)
+		defer log.Debug("stream is closed", "node", e.H.Sender)
+
+		for {
+			f, err := readStreamFrame(reader)
+			if err != nil {
+				if err != io.EOF {
+					log.Debug("failed to read frame", "node", e.H.Sender, "error", err)
+				}
+				return
+			}
+
+			switch f.Type {
+			case BallotMessage, GossipMessage, MessageFromClient:
+				t.messageBroker.ReceiveMessage(t, Message{Type: f.Type, Data: []byte(f.Data)})
+			default:
+				log.Debug("unknown frame", "node", e.H.Sender, "type", f.Type)
+				return
+			}
+		}
+	}
+}
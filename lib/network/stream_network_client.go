@@ -0,0 +1,254 @@
+package sebaknetwork
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+)
+
+var (
+	// DefaultStreamWriteTimeout is the maximum time to write one frame; the
+	// stream, which is stuck is closed and opened again.
+	DefaultStreamWriteTimeout time.Duration = 3 * time.Second
+
+	// DefaultStreamRetryInterval is the interval of opening the stream
+	// again, after it failed; meanwhile the messages are sent by requests.
+	DefaultStreamRetryInterval time.Duration = 1 * time.Second
+)
+
+var errStreamClosed = errors.New("stream is closed")
+
+// stream is the writing side of one opened stream.
+type stream struct {
+	sync.Mutex
+
+	writer *io.PipeWriter
+	closed bool
+}
+
+func (s *stream) write(f streamFrame, timeout time.Duration) (err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return errStreamClosed
+	}
+
+	written := make(chan error, 1)
+	go func() {
+		written <- writeStreamFrame(s.writer, f)
+	}()
+
+	select {
+	case err = <-written:
+	case <-time.After(timeout):
+		err = errors.New("timeout to write frame")
+		s.writer.CloseWithError(err)
+		<-written
+	}
+
+	if err != nil {
+		s.closed = true
+		s.writer.CloseWithError(err)
+	}
+
+	return
+}
+
+func (s *stream) close(err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.closed = true
+	s.writer.CloseWithError(err)
+}
+
+func (s *stream) isClosed() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.closed
+}
+
+// StreamNetworkClient sends the ballots and messages through the stream to
+// `StreamNetwork`; the other requests like `Connect` are same with
+// `HTTP2NetworkClient`. When the stream is not available, it falls back to
+// the requests of `HTTP2NetworkClient`.
+type StreamNetworkClient struct {
+	*HTTP2NetworkClient
+
+	sync.Mutex
+	endpoint   *sebakcommon.Endpoint
+	rawClient  *sebakcommon.HTTP2Client
+	stream     *stream
+	failedAt   time.Time
+	retryAfter time.Duration
+	timeout    time.Duration
+}
+
+func NewStreamNetworkClient(endpoint *sebakcommon.Endpoint, client *HTTP2NetworkClient, rawClient *sebakcommon.HTTP2Client) *StreamNetworkClient {
+	return &StreamNetworkClient{
+		HTTP2NetworkClient: client,
+		endpoint:           endpoint,
+		rawClient:          rawClient,
+		retryAfter:         DefaultStreamRetryInterval,
+		timeout:            DefaultStreamWriteTimeout,
+	}
+}
+
+func (c *StreamNetworkClient) Endpoint() *sebakcommon.Endpoint {
+	return c.endpoint
+}
+
+// open opens new stream. The first frame is the signed `Envelope` of local
+// node, so the stream is accepted only from the known validators.
+func (c *StreamNetworkClient) open() (s *stream, err error) {
+	if c.keypair == nil {
+		err = errors.New("keypair is not set")
+		return
+	}
+
+	var e Envelope
+	if e, err = NewEnvelope(c.keypair, c.networkID, []byte("{}")); err != nil {
+		return
+	}
+
+	var hello []byte
+	if hello, err = e.Serialize(); err != nil {
+		return
+	}
+
+	reader, writer := io.Pipe()
+	s = &stream{writer: writer}
+
+	go func() {
+		if err := writeStreamFrame(writer, streamFrame{Type: streamFrameHello, Data: hello}); err != nil {
+			writer.CloseWithError(err)
+		}
+	}()
+
+	headers := c.DefaultHeaders()
+	headers.Set("Content-Type", "application/octet-stream")
+
+	var response *http.Response
+	response, err = c.rawClient.PostStream(c.resolvePath(UrlPathPrefixNode+"/stream").String(), reader, headers)
+	if err != nil {
+		writer.CloseWithError(err)
+		return
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		err = errors.New("failed to open stream: " + response.Status)
+		writer.CloseWithError(err)
+		return
+	}
+
+	// the stream is closed when the other node closes the response
+	go func() {
+		io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+		s.close(errStreamClosed)
+	}()
+
+	return
+}
+
+// getStream returns the opened stream; when it is closed, the new one is
+// opened, but not before `retryAfter` from the last failure.
+func (c *StreamNetworkClient) getStream() (s *stream, err error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.stream != nil && !c.stream.isClosed() {
+		return c.stream, nil
+	}
+	c.stream = nil
+
+	if !c.failedAt.IsZero() && time.Since(c.failedAt) < c.retryAfter {
+		err = errStreamClosed
+		return
+	}
+
+	if s, err = c.open(); err != nil {
+		c.failedAt = time.Now()
+		log.Debug("failed to open stream", "endpoint", c.endpoint, "error", err)
+		return
+	}
+	c.stream = s
+	c.failedAt = time.Time{}
+
+	return
+}
+
+// send writes the messages into the stream and returns the number of the
+// written messages; the messages after the failed one are not written.
+func (c *StreamNetworkClient) send(mt MessageType, messages ...sebakcommon.Serializable) (sent int, err error) {
+	var s *stream
+	if s, err = c.getStream(); err != nil {
+		return
+	}
+
+	for _, message := range messages {
+		var b []byte
+		if b, err = message.Serialize(); err != nil {
+			return
+		}
+		if err = s.write(streamFrame{Type: mt, Data: b}, c.timeout); err != nil {
+			return
+		}
+		sent++
+	}
+
+	return
+}
+
+func (c *StreamNetworkClient) SendMessage(message sebakcommon.Serializable) (body []byte, err error) {
+	if _, err = c.send(MessageFromClient, message); err == nil {
+		return
+	}
+
+	return c.HTTP2NetworkClient.SendMessage(message)
+}
+
+func (c *StreamNetworkClient) SendBallot(message sebakcommon.Serializable) (body []byte, err error) {
+	if _, err = c.send(BallotMessage, message); err == nil {
+		return
+	}
+
+	return c.HTTP2NetworkClient.SendBallot(message)
+}
+
+func (c *StreamNetworkClient) SendBallots(messages []sebakcommon.Serializable) (body []byte, err error) {
+	var sent int
+	if sent, err = c.send(BallotMessage, messages...); err == nil {
+		return
+	}
+
+	// the ballots, which were already written are not sent again
+	return c.HTTP2NetworkClient.SendBallots(messages[sent:])
+}
+
+func (c *StreamNetworkClient) SendGossip(message sebakcommon.Serializable) (body []byte, err error) {
+	if _, err = c.send(GossipMessage, message); err == nil {
+		return
+	}
+
+	return c.HTTP2NetworkClient.SendGossip(message)
+}
+
+// Close closes the stream.
+func (c *StreamNetworkClient) Close() {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.stream != nil {
+		c.stream.close(errStreamClosed)
+		c.stream = nil
+	}
+	c.rawClient.Close()
+}
@@ -0,0 +1,164 @@
+package sebaknetwork
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/node"
+)
+
+func createNewStreamNetwork(t *testing.T) (sn *StreamNetwork, localNode *sebaknode.LocalNode) {
+	sn = NewStreamNetwork(newTestHTTP2NetworkConfig(t))
+
+	kp, _ := keypair.Random()
+	localNode, _ = sebaknode.NewLocalNode(kp, sn.Endpoint(), "")
+
+	sn.SetContext(context.WithValue(context.Background(), "localNode", localNode))
+
+	return
+}
+
+func startStreamNetwork(t *testing.T, broker MessageBroker) (*StreamNetwork, *sebaknode.LocalNode, *StreamNetworkClient) {
+	sn, localNode := createNewStreamNetwork(t)
+	sn.SetMessageBroker(broker)
+	sn.Ready()
+	go sn.Start()
+
+	c0 := sn.GetClient(sn.Endpoint())
+	pingAndWait(t, c0)
+
+	return sn, localNode, c0.(*StreamNetworkClient)
+}
+
+func receiveMessage(t *testing.T, broker ReceivingMessageBroker) Message {
+	select {
+	case message := <-broker.received:
+		return message
+	case <-time.After(3 * time.Second):
+		require.Fail(t, "failed to receive message")
+	}
+
+	return Message{}
+}
+
+func TestStreamNetworkEndpoint(t *testing.T) {
+	sn, _ := createNewStreamNetwork(t)
+	require.Equal(t, StreamNetworkScheme, sn.Endpoint().Scheme)
+
+	endpoint, err := sebakcommon.NewEndpointFromString(sn.Endpoint().String() + "?NodeName=n1")
+	require.Nil(t, err)
+
+	n, err := NewNetwork(endpoint)
+	require.Nil(t, err)
+	require.IsType(t, &StreamNetwork{}, n)
+
+	// the plain `https` endpoint gets the `HTTP2NetworkClient`
+	httpEndpoint := &sebakcommon.Endpoint{}
+	*httpEndpoint = *sn.Endpoint()
+	httpEndpoint.Scheme = "https"
+	require.IsType(t, &HTTP2NetworkClient{}, sn.GetClient(httpEndpoint))
+
+	// the clients to the same endpoint share the stream
+	require.True(t, sn.GetClient(sn.Endpoint()) == sn.GetClient(sn.Endpoint()))
+}
+
+// TestStreamNetworkSendBallotsResend checks only the ballots, which were not
+// written into the stream are sent again by the request.
+func TestStreamNetworkSendBallotsResend(t *testing.T) {
+	broker := ReceivingMessageBroker{received: make(chan Message, 100)}
+	sn, _, c0 := startStreamNetwork(t, broker)
+	defer sn.Stop()
+
+	_, err := c0.SendBallot(NewDummyMessage("0"))
+	require.Nil(t, err)
+	require.NotNil(t, c0.stream)
+	receiveMessage(t, broker)
+
+	// the stream, which is broken after the first frame
+	c0.stream.close(errStreamClosed)
+	reader, writer := io.Pipe()
+	broken := &stream{writer: writer}
+	c0.stream = broken
+
+	written := make(chan streamFrame, 1)
+	go func() {
+		f, _ := readStreamFrame(reader)
+		written <- f
+		reader.CloseWithError(errStreamClosed)
+	}()
+
+	messages := []sebakcommon.Serializable{NewDummyMessage("1"), NewDummyMessage("2"), NewDummyMessage("3")}
+	_, err = c0.SendBallots(messages)
+	require.Nil(t, err)
+	require.True(t, broken.isClosed())
+
+	f := <-written
+	b, _ := messages[0].Serialize()
+	require.Equal(t, string(b), string(f.Data))
+
+	for _, m := range messages[1:] {
+		message := receiveMessage(t, broker)
+		require.Equal(t, MessageType(BallotMessage), message.Type)
+
+		b, _ := m.Serialize()
+		require.Equal(t, string(b), string(message.Data))
+	}
+
+	select {
+	case message := <-broker.received:
+		require.Fail(t, "written ballot must not be sent again", string(message.Data))
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestStreamNetworkReopen(t *testing.T) {
+	broker := ReceivingMessageBroker{received: make(chan Message, 100)}
+	sn, _, c0 := startStreamNetwork(t, broker)
+	defer sn.Stop()
+
+	_, err := c0.SendBallot(NewDummyMessage("0"))
+	require.Nil(t, err)
+	receiveMessage(t, broker)
+
+	opened := c0.stream
+	opened.close(errStreamClosed)
+
+	// the closed stream is opened again
+	_, err = c0.SendBallot(NewDummyMessage("1"))
+	require.Nil(t, err)
+	require.Equal(t, MessageType(BallotMessage), receiveMessage(t, broker).Type)
+	require.False(t, opened == c0.stream)
+}
+
+func TestStreamNetworkUnknownNode(t *testing.T) {
+	broker := ReceivingMessageBroker{received: make(chan Message, 100)}
+	sn, _, _ := startStreamNetwork(t, broker)
+	defer sn.Stop()
+
+	rawClient, _ := sebakcommon.NewHTTP2Client(0, 0, true)
+	httpEndpoint := &sebakcommon.Endpoint{}
+	*httpEndpoint = *sn.Endpoint()
+	httpEndpoint.Scheme = "https"
+
+	kpUnknown, _ := keypair.Random()
+	client := NewHTTP2NetworkClient(httpEndpoint, nil)
+	client.SetEnvelopeKeypair(kpUnknown, nil)
+	unknown := NewStreamNetworkClient(sn.Endpoint(), client, rawClient)
+
+	// the stream and the request are refused
+	_, err := unknown.SendBallots([]sebakcommon.Serializable{NewDummyMessage("0")})
+	require.NotNil(t, err)
+	require.Nil(t, unknown.stream)
+
+	select {
+	case <-broker.received:
+		require.Fail(t, "message from unknown node must not be received")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,87 @@
+package sebaknetwork
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ValidatorState string
+
+const (
+	ValidatorStateConnected    ValidatorState = "CONNECTED"
+	ValidatorStateDisconnected ValidatorState = "DISCONNECTED"
+)
+
+// ValidatorHealth is the liveness of validator, which is checked by
+// `ConnectionManager`.
+type ValidatorHealth struct {
+	Address   string
+	Endpoint  string
+	State     ValidatorState
+	Latency   time.Duration // latency of the last successful ping
+	LastSeen  time.Time
+	Failures  int // the number of the continuous failures
+	LastError string
+
+	// ProtocolVersion and Version are from the last handshake
+	ProtocolVersion int
+	Version         string
+}
+
+func (h ValidatorHealth) MarshalJSON() ([]byte, error) {
+	var lastSeen interface{}
+	if !h.LastSeen.IsZero() {
+		lastSeen = h.LastSeen
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"address":    h.Address,
+		"endpoint":   h.Endpoint,
+		"state":      h.State,
+		"latency":    h.Latency.String(),
+		"last_seen":  lastSeen,
+		"failures":   h.Failures,
+		"last_error": h.LastError,
+		"protocol":   h.ProtocolVersion,
+		"version":    h.Version,
+	})
+}
+
+// UnmarshalJSON decodes the output of `MarshalJSON`; the client of node api
+// reads the health of validators by it.
+func (h *ValidatorHealth) UnmarshalJSON(b []byte) (err error) {
+	var v struct {
+		Address         string         `json:"address"`
+		Endpoint        string         `json:"endpoint"`
+		State           ValidatorState `json:"state"`
+		Latency         string         `json:"latency"`
+		LastSeen        *time.Time     `json:"last_seen"`
+		Failures        int            `json:"failures"`
+		LastError       string         `json:"last_error"`
+		ProtocolVersion int            `json:"protocol"`
+		Version         string         `json:"version"`
+	}
+	if err = json.Unmarshal(b, &v); err != nil {
+		return
+	}
+
+	*h = ValidatorHealth{
+		Address:         v.Address,
+		Endpoint:        v.Endpoint,
+		State:           v.State,
+		Failures:        v.Failures,
+		LastError:       v.LastError,
+		ProtocolVersion: v.ProtocolVersion,
+		Version:         v.Version,
+	}
+	if len(v.Latency) > 0 {
+		if h.Latency, err = time.ParseDuration(v.Latency); err != nil {
+			return
+		}
+	}
+	if v.LastSeen != nil {
+		h.LastSeen = *v.LastSeen
+	}
+
+	return
+}
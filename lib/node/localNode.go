@@ -24,6 +24,7 @@ type LocalNode struct {
 	keypair *keypair.Full
 
 	state      NodeState
+	mode       NodeMode
 	alias      string
 	endpoint   *sebakcommon.Endpoint
 	validators map[ /* Node.Address() */ string]*Validator
@@ -37,6 +38,7 @@ func NewLocalNode(kp *keypair.Full, endpoint *sebakcommon.Endpoint, alias string
 	n = &LocalNode{
 		keypair:    kp,
 		state:      NodeStateNONE,
+		mode:       NodeModeValidator,
 		alias:      alias,
 		endpoint:   endpoint,
 		validators: map[string]*Validator{},
@@ -88,6 +90,18 @@ func (n *LocalNode) SetTerminating() {
 	n.state = NodeStateTERMINATING
 }
 
+func (n *LocalNode) Mode() NodeMode {
+	return n.mode
+}
+
+func (n *LocalNode) SetMode(mode NodeMode) {
+	n.mode = mode
+}
+
+func (n *LocalNode) IsWatcher() bool {
+	return n.mode == NodeModeWatcher
+}
+
 func (n *LocalNode) Address() string {
 	return n.keypair.Address()
 }
@@ -0,0 +1,29 @@
+package sebaknode
+
+import (
+	"fmt"
+)
+
+// NodeMode decides whether the node takes part in consensus. The watcher node
+// never votes; it follows the confirmed results of validators.
+type NodeMode string
+
+const (
+	NodeModeValidator NodeMode = "validator"
+	NodeModeWatcher   NodeMode = "watcher"
+)
+
+func (m NodeMode) String() string {
+	return string(m)
+}
+
+func NodeModeFromString(s string) (m NodeMode, err error) {
+	switch NodeMode(s) {
+	case NodeModeValidator, NodeModeWatcher:
+		m = NodeMode(s)
+	default:
+		err = fmt.Errorf("unknown node mode: '%s'", s)
+	}
+
+	return
+}
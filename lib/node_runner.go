@@ -9,11 +9,15 @@ package sebak
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
 	"boscoin.io/sebak/lib/network"
 	"boscoin.io/sebak/lib/node"
+	"boscoin.io/sebak/lib/observer"
 	"boscoin.io/sebak/lib/storage"
 	logging "github.com/inconshreveable/log15"
 )
@@ -25,7 +29,11 @@ type NodeRunner struct {
 	network           sebaknetwork.Network
 	consensus         Consensus
 	connectionManager *sebaknetwork.ConnectionManager
-	storage           *sebakstorage.LevelDBBackend
+	peerManager       *sebaknetwork.PeerManager
+	storage           sebakstorage.DBBackend
+	confirmed         *confirmedBallots
+	webSocketHub      *WebSocketHub
+	allowedOrigins    []string
 
 	handleMessageFromClientCheckerFuncs []sebakcommon.CheckerFunc
 	handleBallotCheckerFuncs            []sebakcommon.CheckerFunc
@@ -33,6 +41,10 @@ type NodeRunner struct {
 	handleMessageFromClientCheckerDeferFunc sebakcommon.CheckerDeferFunc
 	handleBallotCheckerDeferFunc            sebakcommon.CheckerDeferFunc
 
+	requests chan messageRequest
+	stop     chan struct{}
+	stopOnce sync.Once
+
 	ctx context.Context
 	log logging.Logger
 }
@@ -43,7 +55,7 @@ func NewNodeRunner(
 	policy sebakcommon.VotingThresholdPolicy,
 	network sebaknetwork.Network,
 	consensus Consensus,
-	storage *sebakstorage.LevelDBBackend,
+	storage sebakstorage.DBBackend,
 ) *NodeRunner {
 	nr := &NodeRunner{
 		networkID: []byte(networkID),
@@ -52,11 +64,18 @@ func NewNodeRunner(
 		network:   network,
 		consensus: consensus,
 		storage:   storage,
+		confirmed: newConfirmedBallots(),
+		requests:  make(chan messageRequest),
+		stop:      make(chan struct{}),
 		log:       log.New(logging.Ctx{"node": localNode.Alias()}),
 	}
+	nr.peerManager = sebaknetwork.NewPeerManager(nr.localNode, nr.network, nr.networkID)
+	nr.webSocketHub = NewWebSocketHub()
+
 	nr.ctx = context.WithValue(context.Background(), "localNode", localNode)
 	nr.ctx = context.WithValue(nr.ctx, "networkID", nr.networkID)
 	nr.ctx = context.WithValue(nr.ctx, "storage", nr.storage)
+	nr.ctx = context.WithValue(nr.ctx, "peerManager", nr.peerManager)
 
 	nr.connectionManager = sebaknetwork.NewConnectionManager(
 		nr.localNode,
@@ -65,8 +84,13 @@ func NewNodeRunner(
 		nr.localNode.GetValidators(),
 	)
 	nr.network.AddWatcher(nr.connectionManager.ConnectionWatcher)
+	nr.ctx = context.WithValue(nr.ctx, "connectionManager", nr.connectionManager)
 
-	nr.SetHandleMessageFromClientCheckerFuncs(nil, DefaultHandleMessageFromClientCheckerFuncs...)
+	if localNode.IsWatcher() {
+		nr.SetHandleMessageFromClientCheckerFuncs(nil, DefaultWatcherHandleMessageFromClientCheckerFuncs...)
+	} else {
+		nr.SetHandleMessageFromClientCheckerFuncs(nil, DefaultHandleMessageFromClientCheckerFuncs...)
+	}
 	nr.SetHandleBallotCheckerFuncs(nil, DefaultHandleBallotCheckerFuncs...)
 
 	return nr
@@ -75,10 +99,12 @@ func NewNodeRunner(
 func (nr *NodeRunner) Ready() {
 	nr.network.SetContext(nr.ctx)
 	nr.network.AddHandler(nr.ctx, AddAPIHandlers(nr.storage))
+	nr.network.AddHandler(nr.ctx, AddNodeRunnerAPIHandlers(nr))
 	nr.network.Ready()
 }
 
 func (nr *NodeRunner) Start() (err error) {
+	nr.localNode.SetBooting()
 	nr.Ready()
 
 	go nr.handleMessage()
@@ -92,7 +118,14 @@ func (nr *NodeRunner) Start() (err error) {
 }
 
 func (nr *NodeRunner) Stop() {
+	nr.stopOnce.Do(func() {
+		close(nr.stop)
+	})
+	nr.localNode.SetTerminating()
+	nr.peerManager.Stop()
+	nr.connectionManager.Stop()
 	nr.network.Stop()
+	nr.webSocketHub.Close()
 }
 
 func (nr *NodeRunner) Node() *sebaknode.LocalNode {
@@ -115,10 +148,18 @@ func (nr *NodeRunner) ConnectionManager() *sebaknetwork.ConnectionManager {
 	return nr.connectionManager
 }
 
-func (nr *NodeRunner) Storage() *sebakstorage.LevelDBBackend {
+func (nr *NodeRunner) PeerManager() *sebaknetwork.PeerManager {
+	return nr.peerManager
+}
+
+func (nr *NodeRunner) Storage() sebakstorage.DBBackend {
 	return nr.storage
 }
 
+func (nr *NodeRunner) WebSocketHub() *WebSocketHub {
+	return nr.webSocketHub
+}
+
 func (nr *NodeRunner) Policy() sebakcommon.VotingThresholdPolicy {
 	return nr.policy
 }
@@ -141,8 +182,17 @@ func (nr *NodeRunner) ConnectValidators() {
 	nr.log.Debug("current node is ready")
 	nr.log.Debug("trying to connect to the validators", "validators", nr.localNode.GetValidators())
 
-	nr.log.Debug("initializing connectionManager for validators")
-	nr.connectionManager.Start()
+	// watcher is not connected to the validators; it only sends the
+	// transactions to them
+	if !nr.localNode.IsWatcher() {
+		nr.log.Debug("initializing connectionManager for validators")
+		nr.connectionManager.Start()
+	}
+
+	nr.log.Debug("starting to exchange peers", "peers", nr.peerManager.Table().Len())
+	nr.peerManager.Start()
+
+	nr.localNode.SetConsensus()
 }
 
 var DefaultHandleMessageFromClientCheckerFuncs = []sebakcommon.CheckerFunc{
@@ -152,6 +202,7 @@ var DefaultHandleMessageFromClientCheckerFuncs = []sebakcommon.CheckerFunc{
 	CheckNodeRunnerHandleMessageISAACReceiveMessage,
 	CheckNodeRunnerHandleMessageSignBallot,
 	CheckNodeRunnerHandleMessageBroadcast,
+	CheckNodeRunnerHandleMessageGossip,
 }
 
 var DefaultHandleBallotCheckerFuncs = []sebakcommon.CheckerFunc{
@@ -166,6 +217,12 @@ var DefaultHandleBallotCheckerFuncs = []sebakcommon.CheckerFunc{
 	CheckNodeRunnerHandleBallotBroadcast,
 }
 
+// SetAllowedOrigins sets the origins, from which the browser can connect to
+// the websocket api; it should be set before `Ready`.
+func (nr *NodeRunner) SetAllowedOrigins(origins []string) {
+	nr.allowedOrigins = origins
+}
+
 func (nr *NodeRunner) SetHandleMessageFromClientCheckerFuncs(
 	deferFunc sebakcommon.CheckerDeferFunc,
 	f ...sebakcommon.CheckerFunc,
@@ -204,9 +261,58 @@ func (nr *NodeRunner) SetHandleBallotCheckerDeferFuncs(deferFunc sebakcommon.Che
 	nr.handleBallotCheckerDeferFunc = deferFunc
 }
 
+// messageRequest is the message from client, which is submitted by
+// `HandleMessageFromClient`; the result of checkers is sent to `result`.
+type messageRequest struct {
+	message sebaknetwork.Message
+	result  chan error
+}
+
+// HandleMessageFromClient runs the checkers of message from client with the
+// other messages in `handleMessage` and returns the result. The message,
+// which is accepted, goes into the consensus. It does not wait after `ctx`
+// is done or `NodeRunner` is stopped.
+func (nr *NodeRunner) HandleMessageFromClient(ctx context.Context, message sebaknetwork.Message) (err error) {
+	if message.IsEmpty() {
+		return sebakerror.ErrorInvalidMessage
+	}
+
+	r := messageRequest{message: message, result: make(chan error, 1)}
+	select {
+	case nr.requests <- r:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-nr.stop:
+		return sebakerror.ErrorNodeStopped
+	}
+
+	select {
+	case err = <-r.result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-nr.stop:
+		err = sebakerror.ErrorNodeStopped
+	}
+
+	return
+}
+
 func (nr *NodeRunner) handleMessage() {
 	var err error
-	for message := range nr.network.ReceiveMessage() {
+	for {
+		var message sebaknetwork.Message
+		select {
+		case r := <-nr.requests:
+			nr.log.Debug("got message from api", "message", r.message.Head(50))
+			r.result <- nr.handleMessageFromClient(r.message)
+			continue
+		case m, ok := <-nr.network.ReceiveMessage():
+			if !ok {
+				return
+			}
+			message = m
+		}
+
 		switch message.Type {
 		case sebaknetwork.ConnectMessage:
 			nr.log.Debug("got connect", "message", message.Head(50))
@@ -221,23 +327,12 @@ func (nr *NodeRunner) handleMessage() {
 			}
 
 			nr.log.Debug("got message from client`", "message", message.Head(50))
-
-			checker := &NodeRunnerHandleMessageChecker{
-				DefaultChecker: sebakcommon.DefaultChecker{Funcs: nr.handleMessageFromClientCheckerFuncs},
-				NodeRunner:     nr,
-				LocalNode:      nr.localNode,
-				NetworkID:      nr.networkID,
-				Message:        message,
-			}
-
-			if err = sebakcommon.RunChecker(checker, nr.handleMessageFromClientCheckerDeferFunc); err != nil {
-				if _, ok := err.(sebakcommon.CheckerErrorStop); ok {
-					continue
-				}
-				nr.log.Error("failed to handle message from client", "error", err)
+			nr.handleMessageFromClient(message)
+		case sebaknetwork.BallotMessage:
+			if nr.localNode.IsWatcher() {
+				nr.log.Debug("watcher does not vote; ballot is ignored", "message", message.Head(50))
 				continue
 			}
-		case sebaknetwork.BallotMessage:
 			if message.IsEmpty() {
 				nr.log.Error("got empty ballot message`")
 				continue
@@ -258,12 +353,67 @@ func (nr *NodeRunner) handleMessage() {
 				}
 			}
 			nr.closeConsensus(checker)
+		case sebaknetwork.GossipMessage:
+			nr.handleGossip(message)
 		default:
 			nr.log.Error("got unknown", "message", message.Head(50))
 		}
 	}
 }
 
+func (nr *NodeRunner) handleMessageFromClient(message sebaknetwork.Message) (err error) {
+	checker := &NodeRunnerHandleMessageChecker{
+		DefaultChecker: sebakcommon.DefaultChecker{Funcs: nr.handleMessageFromClientCheckerFuncs},
+		NodeRunner:     nr,
+		LocalNode:      nr.localNode,
+		NetworkID:      nr.networkID,
+		Message:        message,
+	}
+
+	if err = sebakcommon.RunChecker(checker, nr.handleMessageFromClientCheckerDeferFunc); err != nil {
+		if _, ok := err.(sebakcommon.CheckerErrorStop); ok {
+			return
+		}
+		nr.log.Error("failed to handle message from client", "error", err)
+	}
+
+	return
+}
+
+// handleGossip relays the new `Gossip` to the peers. The transaction, which
+// was started from the node out of validators, is handled like the message
+// from client; the one from validators was already proposed by them.
+func (nr *NodeRunner) handleGossip(message sebaknetwork.Message) {
+	g, isNew, err := nr.peerManager.Receive(message.Data)
+	if err != nil {
+		nr.log.Error("invalid gossip was received", "error", err, "message", message.Head(50))
+		return
+	}
+	if !isNew {
+		return
+	}
+
+	nr.log.Debug("got gossip", "type", g.Type, "id", g.ID, "origin", g.Origin)
+
+	switch g.Type {
+	case sebaknetwork.GossipTransaction:
+		// `Origin` is verified by the signature of gossip
+		if nr.localNode.HasValidators(g.Origin) {
+			return
+		}
+		nr.handleMessageFromClient(sebaknetwork.NewMessage(sebaknetwork.MessageFromClient, g.Data))
+	case sebaknetwork.GossipConfirmed:
+		if !nr.localNode.IsWatcher() {
+			return
+		}
+		if err = nr.handleConfirmed(g); err != nil {
+			nr.log.Error("failed to handle confirmed gossip", "error", err, "id", g.ID)
+		}
+	default:
+		nr.log.Error("got unknown gossip", "type", g.Type)
+	}
+}
+
 func (nr *NodeRunner) closeConsensus(c sebakcommon.Checker) (err error) {
 	checker := c.(*NodeRunnerHandleBallotChecker)
 
@@ -274,6 +424,17 @@ func (nr *NodeRunner) closeConsensus(c sebakcommon.Checker) (err error) {
 		return
 	}
 
+	if !checker.VotingStateStaging.IsStorable() {
+		bte := NewBlockTransactionErrorFromStaging(checker.VotingStateStaging)
+		if err := bte.Save(nr.storage); err != nil {
+			nr.log.Error("failed to save transaction error", "error", err, "hash", bte.Hash)
+		}
+		observer.TransactionObserver.Trigger(
+			fmt.Sprintf("failed hash-%s", checker.VotingStateStaging.MessageHash),
+			checker.VotingStateStaging,
+		)
+	}
+
 	if err = nr.Consensus().CloseConsensus(checker.Ballot); err != nil {
 		nr.Log().Error("new failed to close consensus", "error", err)
 		return
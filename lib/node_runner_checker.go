@@ -97,6 +97,20 @@ func CheckNodeRunnerHandleMessageBroadcast(c sebakcommon.Checker, args ...interf
 	return
 }
 
+// CheckNodeRunnerHandleMessageGossip relays the transaction to the peers,
+// which are not validators.
+func CheckNodeRunnerHandleMessageGossip(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*NodeRunnerHandleMessageChecker)
+
+	checker.NodeRunner.PeerManager().Gossip(
+		sebaknetwork.GossipTransaction,
+		checker.Transaction.GetHash(),
+		checker.Message.Data,
+	)
+
+	return
+}
+
 type NodeRunnerHandleBallotChecker struct {
 	sebakcommon.DefaultChecker
 
@@ -204,6 +218,12 @@ func CheckNodeRunnerHandleBallotStore(c sebakcommon.Checker, args ...interface{}
 		return
 	}
 
+	// the confirmed result is relayed to the watchers
+	confirmed := newConfirmedBallot(checker.NodeRunner, checker.Ballot)
+	if b, err := confirmed.Serialize(); err == nil {
+		checker.NodeRunner.PeerManager().Gossip(sebaknetwork.GossipConfirmed, confirmed.GetHash(), b)
+	}
+
 	checker.NodeRunner.Log().Debug(
 		"got consensus",
 		"ballot", checker.Ballot.MessageHash(),
@@ -0,0 +1,63 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/network"
+)
+
+// TestNodeRunnerPartitionHealed checks the consensus stalls while one
+// validator is partitioned, and it is finished after the partition is healed
+// by the retried ballots.
+func TestNodeRunnerPartitionHealed(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunnersWithReady(3)
+	for _, nr := range nodeRunners {
+		defer nr.Stop()
+
+		// every validator must agree
+		nr.Policy().Reset(sebakcommon.BallotStateSIGN, 100)
+		nr.Policy().Reset(sebakcommon.BallotStateACCEPT, 100)
+	}
+
+	kp, _ := keypair.Random()
+	kpNewAccount, _ := keypair.Random()
+
+	checkpoint := sebakcommon.MakeGenesisCheckpoint(networkID)
+	account := block.NewBlockAccount(kp.Address(), BaseFee.MustAdd(1), checkpoint)
+	for _, nr := range nodeRunners {
+		account.Save(nr.Storage())
+	}
+
+	sebaknetwork.PartitionMemoryNetwork(
+		[]*sebakcommon.Endpoint{nodeRunners[0].Node().Endpoint()},
+		[]*sebakcommon.Endpoint{nodeRunners[1].Node().Endpoint(), nodeRunners[2].Node().Endpoint()},
+	)
+
+	tx := makeTransactionCreateAccount(kp, kpNewAccount.Address(), sebakcommon.Amount(1))
+	tx.B.Checkpoint = account.Checkpoint
+	tx.Sign(kp, networkID)
+
+	client := nodeRunners[0].Network().GetClient(nodeRunners[0].Node().Endpoint())
+	_, err := client.SendMessage(tx)
+	require.Nil(t, err)
+
+	time.Sleep(500 * time.Millisecond)
+	for _, nr := range nodeRunners {
+		found, _ := ExistBlockTransaction(nr.Storage(), tx.GetHash())
+		require.False(t, found)
+	}
+
+	sebaknetwork.HealMemoryNetwork()
+
+	for _, nr := range nodeRunners {
+		require.True(t, waitBlockTransaction(nr.Storage(), tx.GetHash()))
+	}
+}
@@ -111,10 +111,18 @@ func makeTransactionCreateAccount(kpSource *keypair.Full, target string, amount
 }
 
 func createNodeRunners(n int) []*NodeRunner {
-	var ns []*sebaknetwork.MemoryNetwork
+	return createNodeRunnersWithNetwork(n, func() (sebaknetwork.Network, *sebaknode.LocalNode) {
+		return createNetMemoryNetwork()
+	})
+}
+
+// createNodeRunnersWithNetwork creates the validators, which know each other,
+// over the networks from `createNetwork`.
+func createNodeRunnersWithNetwork(n int, createNetwork func() (sebaknetwork.Network, *sebaknode.LocalNode)) []*NodeRunner {
+	var ns []sebaknetwork.Network
 	var nodes []*sebaknode.LocalNode
 	for i := 0; i < n; i++ {
-		s, v := createNetMemoryNetwork()
+		s, v := createNetwork()
 		ns = append(ns, s)
 		nodes = append(nodes, v)
 	}
@@ -143,8 +151,13 @@ func createNodeRunners(n int) []*NodeRunner {
 }
 
 func createNodeRunnersWithReady(n int) []*NodeRunner {
-	nodeRunners := createNodeRunners(n)
+	return startNodeRunners(createNodeRunners(n))
+}
 
+// startNodeRunners starts the validators and waits until they are connected
+// to each other.
+func startNodeRunners(nodeRunners []*NodeRunner) []*NodeRunner {
+	n := len(nodeRunners)
 	for _, nr := range nodeRunners {
 		go nr.Start()
 	}
@@ -0,0 +1,90 @@
+package sebak
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/network"
+	"boscoin.io/sebak/lib/node"
+)
+
+func getTestFreePort() int {
+	ln, _ := net.Listen("tcp", "localhost:0")
+	defer ln.Close()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func createNetStreamNetwork(g *sebaknetwork.KeyGenerator) (sebaknetwork.Network, *sebaknode.LocalNode) {
+	query := url.Values{}
+	query.Set("NodeName", "n1")
+	query.Set("TLSCertFile", g.GetCertPath())
+	query.Set("TLSKeyFile", g.GetKeyPath())
+	query.Set("HTTP2LogOutput", os.DevNull)
+
+	endpoint, _ := sebakcommon.NewEndpointFromString(
+		fmt.Sprintf("%s://localhost:%d?%s", sebaknetwork.StreamNetworkScheme, getTestFreePort(), query.Encode()),
+	)
+	n, _ := sebaknetwork.NewNetwork(endpoint)
+
+	kp, _ := keypair.Random()
+	localNode, _ := sebaknode.NewLocalNode(kp, n.Endpoint(), "")
+
+	n.SetContext(context.WithValue(context.Background(), "localNode", localNode))
+
+	return n, localNode
+}
+
+// TestStreamNetworkPayment checks the validators over `StreamNetwork` make
+// consensus of payment.
+func TestStreamNetworkPayment(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "sebak-test-")
+	defer os.RemoveAll(dir)
+	g := sebaknetwork.NewKeyGenerator(dir, "cert.pem", "key.pem")
+
+	nodeRunners := startNodeRunners(createNodeRunnersWithNetwork(3, func() (sebaknetwork.Network, *sebaknode.LocalNode) {
+		return createNetStreamNetwork(g)
+	}))
+	for _, nr := range nodeRunners {
+		defer nr.Stop()
+		require.Equal(t, 2, nr.ConnectionManager().CountConnected())
+	}
+
+	kpSource, _ := keypair.Random()
+	kpTarget, _ := keypair.Random()
+
+	checkpoint := sebakcommon.MakeGenesisCheckpoint(networkID)
+	accountSource := block.NewBlockAccount(kpSource.Address(), BaseFee.MustAdd(1), checkpoint)
+	accountTarget := block.NewBlockAccount(kpTarget.Address(), sebakcommon.Amount(2000), checkpoint)
+	for _, nr := range nodeRunners {
+		accountSource.Save(nr.Storage())
+		accountTarget.Save(nr.Storage())
+	}
+
+	amount := sebakcommon.Amount(1)
+	tx := makeTransactionPayment(kpSource, kpTarget.Address(), amount)
+	tx.B.Checkpoint = accountSource.Checkpoint
+	tx.Sign(kpSource, networkID)
+
+	nr0 := nodeRunners[0]
+	_, err := nr0.Network().GetClient(nr0.Node().Endpoint()).SendMessage(tx)
+	require.Nil(t, err)
+
+	for _, nr := range nodeRunners {
+		require.True(t, waitBlockTransaction(nr.Storage(), tx.GetHash()))
+
+		baTarget, err := block.GetBlockAccount(nr.Storage(), kpTarget.Address())
+		require.Nil(t, err)
+		require.Equal(t, accountTarget.GetBalance().MustAdd(amount), baTarget.GetBalance())
+	}
+}
@@ -0,0 +1,196 @@
+package sebak
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/network"
+)
+
+// The watcher node does not vote. It forwards the transactions from client to
+// the validators and stores the transaction, which enough validators confirmed
+// by `GossipConfirmed`.
+
+var DefaultWatcherHandleMessageFromClientCheckerFuncs = []sebakcommon.CheckerFunc{
+	CheckNodeRunnerHandleMessageTransactionUnmarshal,
+	CheckNodeRunnerHandleMessageNotConfirmed,
+	CheckNodeRunnerHandleMessageHistory,
+	CheckNodeRunnerHandleMessageForward,
+}
+
+func CheckNodeRunnerHandleMessageNotConfirmed(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*NodeRunnerHandleMessageChecker)
+
+	var found bool
+	if found, err = ExistBlockTransaction(checker.NodeRunner.Storage(), checker.Transaction.GetHash()); err != nil {
+		return
+	}
+	if found {
+		err = sebakcommon.CheckerErrorStop{Message: "transaction is already confirmed"}
+		return
+	}
+
+	return
+}
+
+// CheckNodeRunnerHandleMessageForward sends the transaction to one of the
+// validators; the validator, which accepts it, broadcasts it to the others.
+func CheckNodeRunnerHandleMessageForward(c sebakcommon.Checker, args ...interface{}) (err error) {
+	checker := c.(*NodeRunnerHandleMessageChecker)
+
+	for _, v := range checker.LocalNode.GetValidators() {
+		client := checker.NodeRunner.Network().GetClient(v.Endpoint())
+		if client == nil {
+			continue
+		}
+
+		if _, err = client.SendMessage(checker.Transaction); err != nil {
+			checker.NodeRunner.Log().Debug("failed to forward transaction", "validator", v.Address(), "error", err)
+			continue
+		}
+
+		checker.NodeRunner.Log().Debug(
+			"transaction was forwarded",
+			"transaction", checker.Transaction.GetHash(),
+			"validator", v.Address(),
+		)
+		return
+	}
+
+	err = errors.New("no validator accepted the transaction")
+
+	return
+}
+
+// newConfirmedBallot makes the ballot of local node, which says the
+// transaction was stored by consensus. It is relayed to the watchers by
+// `GossipConfirmed`.
+func newConfirmedBallot(nr *NodeRunner, ballot Ballot) Ballot {
+	confirmed := ballot.Clone()
+	confirmed.SetState(sebakcommon.BallotStateALLCONFIRM)
+	confirmed.Vote(VotingYES)
+	confirmed.Sign(nr.Node().Keypair(), nr.NetworkID())
+
+	return confirmed
+}
+
+// DefaultConfirmedBallotsExpire is how long the watcher waits the message
+// reaches the threshold; the ballots of the message, which does not reach it
+// are dropped after it.
+var DefaultConfirmedBallotsExpire time.Duration = 10 * time.Minute
+
+// confirmedBallots collects the confirmed ballots by the message hash until
+// enough validators confirm it.
+type confirmedBallots struct {
+	sync.Mutex
+
+	expire  time.Duration
+	ballots map[ /* Ballot.MessageHash() */ string]map[ /* NodeKey */ string]Ballot
+	added   map[ /* Ballot.MessageHash() */ string]time.Time
+	expired time.Time
+}
+
+func newConfirmedBallots() *confirmedBallots {
+	return &confirmedBallots{
+		expire:  DefaultConfirmedBallotsExpire,
+		ballots: map[string]map[string]Ballot{},
+		added:   map[string]time.Time{},
+	}
+}
+
+// add returns the number of validators, which confirmed the same message.
+func (c *confirmedBallots) add(ballot Ballot, now time.Time) int {
+	c.Lock()
+	defer c.Unlock()
+
+	c.expireBallots(now)
+
+	if _, found := c.ballots[ballot.MessageHash()]; !found {
+		c.ballots[ballot.MessageHash()] = map[string]Ballot{}
+		c.added[ballot.MessageHash()] = now
+	}
+	c.ballots[ballot.MessageHash()][ballot.B.NodeKey] = ballot
+
+	return len(c.ballots[ballot.MessageHash()])
+}
+
+func (c *confirmedBallots) remove(hash string) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.ballots, hash)
+	delete(c.added, hash)
+}
+
+func (c *confirmedBallots) expireBallots(now time.Time) {
+	if now.Sub(c.expired) < time.Second {
+		return
+	}
+	c.expired = now
+
+	for hash, added := range c.added {
+		if now.Sub(added) > c.expire {
+			delete(c.ballots, hash)
+			delete(c.added, hash)
+		}
+	}
+}
+
+// handleConfirmed stores the transaction of confirmed ballot, when the
+// ballots from validators reach the accept threshold.
+func (nr *NodeRunner) handleConfirmed(g sebaknetwork.Gossip) (err error) {
+	var ballot Ballot
+	if ballot, err = NewBallotFromJSON(g.Data); err != nil {
+		return
+	}
+	if err = ballot.IsWellFormed(nr.networkID); err != nil {
+		return
+	}
+	if !nr.localNode.HasValidators(ballot.B.NodeKey) {
+		err = errors.New("confirmed ballot from unknown validator")
+		return
+	}
+	if ballot.State() != sebakcommon.BallotStateALLCONFIRM || ballot.B.VotingHole != VotingYES {
+		err = errors.New("ballot is not confirmed")
+		return
+	}
+
+	tx, ok := ballot.Data().Data.(Transaction)
+	if !ok || tx.GetHash() != ballot.MessageHash() {
+		err = errors.New("confirmed ballot has invalid transaction")
+		return
+	}
+	if err = tx.IsWellFormed(nr.networkID); err != nil {
+		return
+	}
+
+	var found bool
+	if found, err = ExistBlockTransaction(nr.storage, ballot.MessageHash()); err != nil || found {
+		return
+	}
+
+	count := nr.confirmed.add(ballot, time.Now())
+	if count < nr.policy.Threshold(sebakcommon.BallotStateACCEPT) {
+		return
+	}
+	nr.confirmed.remove(ballot.MessageHash())
+
+	var raw []byte
+	if raw, err = ballot.Data().Serialize(); err != nil {
+		return
+	}
+	bh := NewTransactionHistoryFromTransaction(tx, raw)
+	if err = bh.Save(nr.storage); err != nil && err != sebakerror.ErrorBlockAlreadyExists {
+		return
+	}
+	if err = FinishTransaction(nr.storage, ballot, tx); err != nil {
+		return
+	}
+
+	nr.log.Debug("confirmed transaction was stored", "transaction", tx.GetHash(), "validators", count)
+
+	return
+}
@@ -0,0 +1,153 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/network"
+	"boscoin.io/sebak/lib/node"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// createWatcherNodeRunner creates the watcher, which follows the validators of
+// `nodeRunners`.
+func createWatcherNodeRunner(nodeRunners []*NodeRunner) *NodeRunner {
+	mn, localNode := createNetMemoryNetwork()
+	localNode.SetMode(sebaknode.NodeModeWatcher)
+	for _, nr := range nodeRunners {
+		localNode.AddValidators(nr.Node().ConvertToValidator())
+	}
+
+	p, _ := NewDefaultVotingThresholdPolicy(100, 30, 30)
+	p.SetValidators(len(localNode.GetValidators()))
+	is, _ := NewISAAC(networkID, localNode, p)
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+
+	return NewNodeRunner(string(networkID), localNode, p, mn, is, st)
+}
+
+func waitBlockTransaction(st sebakstorage.DBBackend, hash string) bool {
+	for i := 0; i < 50; i++ {
+		if found, _ := ExistBlockTransaction(st, hash); found {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return false
+}
+
+func TestNodeRunnerWatcher(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunnersWithReady(3)
+	for _, nr := range nodeRunners {
+		defer nr.Stop()
+	}
+
+	watcher := createWatcherNodeRunner(nodeRunners)
+	go watcher.Start()
+	defer watcher.Stop()
+
+	kp, _ := keypair.Random()
+	kpNewAccount, _ := keypair.Random()
+
+	checkpoint := sebakcommon.MakeGenesisCheckpoint(networkID)
+	account := block.NewBlockAccount(kp.Address(), BaseFee.MustAdd(1), checkpoint)
+	for _, nr := range append(nodeRunners, watcher) {
+		account.Save(nr.Storage())
+	}
+
+	// wait until the validators know the watcher
+	for i := 0; i < 50; i++ {
+		if _, found := nodeRunners[0].PeerManager().Table().Get(watcher.Node().Address()); found {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	tx := makeTransactionCreateAccount(kp, kpNewAccount.Address(), sebakcommon.Amount(1))
+	tx.B.Checkpoint = account.Checkpoint
+	tx.Sign(kp, networkID)
+
+	// the client sends transaction to the watcher
+	client := watcher.Network().GetClient(watcher.Node().Endpoint())
+	_, err := client.SendMessage(tx)
+	require.Nil(t, err)
+
+	for _, nr := range nodeRunners {
+		require.True(t, waitBlockTransaction(nr.Storage(), tx.GetHash()))
+	}
+	require.True(t, waitBlockTransaction(watcher.Storage(), tx.GetHash()))
+
+	baTarget, err := block.GetBlockAccount(watcher.Storage(), kpNewAccount.Address())
+	require.Nil(t, err)
+	require.Equal(t, sebakcommon.Amount(1), baTarget.GetBalance())
+
+	// watcher never votes
+	require.False(t, watcher.Consensus().HasMessageByHash(tx.GetHash()))
+}
+
+func TestNodeRunnerWatcherConfirmedFromUnknown(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(3)
+	watcher := createWatcherNodeRunner(nodeRunners)
+	watcher.Policy().Reset(sebakcommon.BallotStateACCEPT, 100)
+
+	kp, _ := keypair.Random()
+	tx := makeTransaction(kp)
+	ballot, _ := NewBallotFromMessage(kp.Address(), tx)
+
+	// confirmed by validators
+	confirmed := newConfirmedBallot(nodeRunners[0], ballot)
+	b, _ := confirmed.Serialize()
+	g, _ := sebaknetwork.NewGossip(sebaknetwork.GossipConfirmed, confirmed.GetHash(), nodeRunners[0].Node().Keypair(), networkID, b)
+	require.Nil(t, watcher.handleConfirmed(g))
+	require.Equal(t, 1, len(watcher.confirmed.ballots[tx.GetHash()]))
+
+	// confirmed by unknown node
+	unknown := createNodeRunners(1)[0]
+	confirmed = newConfirmedBallot(unknown, ballot)
+	b, _ = confirmed.Serialize()
+	g, _ = sebaknetwork.NewGossip(sebaknetwork.GossipConfirmed, confirmed.GetHash(), unknown.Node().Keypair(), networkID, b)
+	require.NotNil(t, watcher.handleConfirmed(g))
+	require.Equal(t, 1, len(watcher.confirmed.ballots[tx.GetHash()]))
+
+	// not confirmed ballot
+	ballot.SetState(sebakcommon.BallotStateSIGN)
+	ballot.Vote(VotingYES)
+	ballot.Sign(nodeRunners[1].Node().Keypair(), networkID)
+	b, _ = ballot.Serialize()
+	g, _ = sebaknetwork.NewGossip(sebaknetwork.GossipConfirmed, ballot.GetHash(), nodeRunners[1].Node().Keypair(), networkID, b)
+	require.NotNil(t, watcher.handleConfirmed(g))
+	require.Equal(t, 1, len(watcher.confirmed.ballots[tx.GetHash()]))
+}
+
+func TestConfirmedBallotsExpire(t *testing.T) {
+	defer sebaknetwork.CleanUpMemoryNetwork()
+
+	nodeRunners := createNodeRunners(2)
+
+	kp, _ := keypair.Random()
+	ballot, _ := NewBallotFromMessage(kp.Address(), makeTransaction(kp))
+
+	c := newConfirmedBallots()
+	now := time.Now()
+	require.Equal(t, 1, c.add(newConfirmedBallot(nodeRunners[0], ballot), now))
+
+	// the message, which does not reach the threshold is dropped
+	kpOther, _ := keypair.Random()
+	other, _ := NewBallotFromMessage(kpOther.Address(), makeTransaction(kpOther))
+	require.Equal(t, 1, c.add(newConfirmedBallot(nodeRunners[0], other), now.Add(c.expire+time.Second)))
+
+	_, found := c.ballots[ballot.MessageHash()]
+	require.False(t, found)
+	require.Equal(t, 1, len(c.ballots))
+	require.Equal(t, 1, len(c.added))
+}
@@ -5,3 +5,7 @@ import "github.com/GianlucaGuarini/go-observable"
 var BlockAccountObserver = observable.New()
 var BlockTransactionObserver = observable.New()
 var BlockOperationObserver = observable.New()
+
+// TransactionObserver triggers `failed hash-<transaction hash>`, when the
+// consensus of transaction is closed without storing it.
+var TransactionObserver = observable.New()
@@ -36,7 +36,7 @@ func (o OperationBodyCreateAccount) IsWellFormed([]byte) (err error) {
 	return
 }
 
-func (o OperationBodyCreateAccount) Validate(st sebakstorage.LevelDBBackend) (err error) {
+func (o OperationBodyCreateAccount) Validate(st sebakstorage.DBBackend) (err error) {
 	// TODO check whether `Target` is not in `Block Account`
 
 	return
@@ -50,7 +50,7 @@ func (o OperationBodyCreateAccount) GetAmount() sebakcommon.Amount {
 	return o.Amount
 }
 
-func FinishOperationCreateAccount(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) (err error) {
+func FinishOperationCreateAccount(st sebakstorage.DBBackend, tx Transaction, op Operation) (err error) {
 	var baSource, baTarget *block.BlockAccount
 	if baSource, err = block.GetBlockAccount(st, tx.B.Source); err != nil {
 		err = sebakerror.ErrorBlockAccountDoesNotExists
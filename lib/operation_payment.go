@@ -42,7 +42,7 @@ func (o OperationBodyPayment) IsWellFormed([]byte) (err error) {
 	return
 }
 
-func (o OperationBodyPayment) Validate(st sebakstorage.LevelDBBackend) (err error) {
+func (o OperationBodyPayment) Validate(st sebakstorage.DBBackend) (err error) {
 	// TODO check whether `Target` is in `Block Account`
 	// TODO check over minimum balance
 	return
@@ -56,7 +56,7 @@ func (o OperationBodyPayment) GetAmount() sebakcommon.Amount {
 	return o.Amount
 }
 
-func FinishOperationPayment(st *sebakstorage.LevelDBBackend, tx Transaction, op Operation) (err error) {
+func FinishOperationPayment(st sebakstorage.DBBackend, tx Transaction, op Operation) (err error) {
 	var baSource, baTarget *block.BlockAccount
 	if baSource, err = block.GetBlockAccount(st, tx.B.Source); err != nil {
 		err = sebakerror.ErrorBlockAccountDoesNotExists
@@ -0,0 +1,354 @@
+package sebak
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+	logging "github.com/inconshreveable/log15"
+)
+
+// Pruner removes the old history from storage for the non-archival node. The
+// records, which are older than `PrunePolicy.Window` are pruned,
+//  * `BlockTransactionHistory`
+//  * `BlockAccountCheckpoint`, except the current checkpoint of account
+//  * `BlockAccountHistory`, except the current and the genesis balance
+//  * `BlockTransaction.Message`
+//
+// The current state like `BlockAccount`, `BlockTransaction` and
+// `BlockOperation` are kept. `Pruner` writes in small batches without
+// transaction, so it does not block the consensus. The histories, which were
+// stored by the older node are pruned after `Migrate` indexes them.
+
+// PrunerMarkerMessage keeps the confirmed time, until which the
+// `BlockTransaction.Message` was pruned.
+const PrunerMarkerMessage string = "pruner-marker-message"
+
+// DefaultPruneBatchSize is the number of records, which are removed at once.
+var DefaultPruneBatchSize int = 1000
+
+type PrunePolicy struct {
+	// Window is the retention window; zero means the archival node, which
+	// never prunes.
+	Window time.Duration
+	// Interval is the period of pruning.
+	Interval time.Duration
+}
+
+func (p PrunePolicy) IsArchival() bool {
+	return p.Window < 1
+}
+
+type PruneResult struct {
+	TransactionHistories int
+	TransactionMessages  int
+	AccountCheckpoints   int
+	AccountHistories     int
+}
+
+type Pruner struct {
+	st        sebakstorage.DBBackend
+	policy    PrunePolicy
+	batchSize int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	log      logging.Logger
+}
+
+func NewPruner(st sebakstorage.DBBackend, policy PrunePolicy) *Pruner {
+	return &Pruner{
+		st:        st,
+		policy:    policy,
+		batchSize: DefaultPruneBatchSize,
+		stop:      make(chan struct{}),
+		log:       log.New(logging.Ctx{"module": "pruner"}),
+	}
+}
+
+func (p *Pruner) Policy() PrunePolicy {
+	return p.policy
+}
+
+// Start runs the pruning at every `PrunePolicy.Interval` until `Stop` is
+// called. The archival node does nothing.
+func (p *Pruner) Start() {
+	if p.policy.IsArchival() {
+		p.log.Debug("archival node; pruning is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(p.policy.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				result, err := p.Prune(time.Now())
+				if err != nil {
+					p.log.Error("failed to prune", "error", err)
+					continue
+				}
+				p.log.Debug("pruned", "result", result)
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (p *Pruner) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+// Prune removes the records older than `now - PrunePolicy.Window`.
+func (p *Pruner) Prune(now time.Time) (result PruneResult, err error) {
+	if p.policy.IsArchival() {
+		return
+	}
+
+	cutoff := now.Add(-p.policy.Window)
+
+	if result.TransactionHistories, err = p.pruneTransactionHistories(cutoff); err != nil {
+		return
+	}
+	if result.TransactionMessages, err = p.pruneTransactionMessages(cutoff); err != nil {
+		return
+	}
+	if result.AccountCheckpoints, err = p.pruneAccountCheckpoints(cutoff); err != nil {
+		return
+	}
+	if result.AccountHistories, err = p.pruneAccountHistories(cutoff); err != nil {
+		return
+	}
+
+	return
+}
+
+func (p *Pruner) flush(batch *sebakstorage.Batch, force bool) (*sebakstorage.Batch, error) {
+	if batch.Len() < 1 || (!force && batch.Len() < p.batchSize) {
+		return batch, nil
+	}
+
+	if err := p.st.Write(batch); err != nil {
+		return batch, err
+	}
+
+	return sebakstorage.NewBatch(), nil
+}
+
+func (p *Pruner) pruneTransactionHistories(cutoff time.Time) (n int, err error) {
+	iterFunc, closeFunc := BlockTransactionHistoryModel.GetIteratorRange(
+		p.st,
+		BlockTransactionHistoryIndexConfirmed,
+		"",
+		sebakcommon.FormatISO8601(cutoff),
+		false,
+	)
+	defer closeFunc()
+
+	batch := sebakstorage.NewBatch()
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var bth BlockTransactionHistory
+		if err = json.Unmarshal(item.Value, &bth); err != nil {
+			return
+		}
+		if err = BlockTransactionHistoryModel.Delete(p.st, batch, bth.Hash); err != nil {
+			return
+		}
+		n++
+
+		if batch, err = p.flush(batch, false); err != nil {
+			return
+		}
+	}
+
+	_, err = p.flush(batch, true)
+
+	return
+}
+
+func (p *Pruner) pruneTransactionMessages(cutoff time.Time) (n int, err error) {
+	var start string
+	if err = p.st.Get(PrunerMarkerMessage, &start); err != nil {
+		if err != sebakerror.ErrorStorageRecordDoesNotExist {
+			return
+		}
+		start = ""
+	}
+
+	limit := sebakcommon.FormatISO8601(cutoff)
+	if limit <= start {
+		return 0, nil
+	}
+
+	iterFunc, closeFunc := BlockTransactionModel.GetIteratorRange(
+		p.st,
+		BlockTransactionIndexConfirmed,
+		start,
+		limit,
+		false,
+	)
+	defer closeFunc()
+
+	batch := sebakstorage.NewBatch()
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var bt BlockTransaction
+		if err = json.Unmarshal(item.Value, &bt); err != nil {
+			return
+		}
+		if len(bt.Message) < 1 {
+			continue
+		}
+
+		bt.Message = nil
+		if err = BlockTransactionModel.Update(p.st, batch, &bt); err != nil {
+			return
+		}
+		n++
+
+		if batch, err = p.flush(batch, false); err != nil {
+			return
+		}
+	}
+
+	var encoded []byte
+	if encoded, err = sebakcommon.EncodeJSONValue(limit); err != nil {
+		return
+	}
+	batch.Put(PrunerMarkerMessage, encoded)
+
+	_, err = p.flush(batch, true)
+
+	return
+}
+
+func (p *Pruner) pruneAccountCheckpoints(cutoff time.Time) (n int, err error) {
+	accountIterFunc, accountCloseFunc := block.GetBlockAccountsByCreated(p.st, false)
+	defer accountCloseFunc()
+
+	batch := sebakstorage.NewBatch()
+	for {
+		ba, hasNext := accountIterFunc()
+		if !hasNext {
+			break
+		}
+
+		current := block.GetBlockAccountCheckpointKey(ba.Address, ba.Checkpoint)
+		prefix := block.GetBlockAccountCheckpointByAddressKeyPrefix(ba.Address)
+
+		iterFunc, closeFunc := p.st.GetIterator(prefix, false)
+		for {
+			item, hasNext := iterFunc()
+			if !hasNext {
+				break
+			}
+
+			var key string
+			if err = json.Unmarshal(item.Value, &key); err != nil {
+				closeFunc()
+				return
+			}
+			if key == current {
+				continue
+			}
+
+			var created time.Time
+			created, err = sebakcommon.GetTimeFromUUID(strings.TrimPrefix(string(item.Key), prefix))
+			if err != nil {
+				closeFunc()
+				return
+			}
+
+			if !created.Before(cutoff) {
+				continue
+			}
+
+			batch.Delete(string(item.Key))
+			batch.Delete(key)
+			n++
+
+			if batch, err = p.flush(batch, false); err != nil {
+				closeFunc()
+				return
+			}
+		}
+		closeFunc()
+	}
+
+	_, err = p.flush(batch, true)
+
+	return
+}
+
+// pruneAccountHistories removes the balance histories, which were confirmed
+// before `cutoff`; the index is ordered by the confirmed time, so the newer
+// histories are not read.
+func (p *Pruner) pruneAccountHistories(cutoff time.Time) (n int, err error) {
+	accountIterFunc, accountCloseFunc := block.GetBlockAccountsByCreated(p.st, false)
+	defer accountCloseFunc()
+
+	batch := sebakstorage.NewBatch()
+	for {
+		ba, hasNext := accountIterFunc()
+		if !hasNext {
+			break
+		}
+
+		iterFunc, closeFunc := GetBlockAccountHistory(p.st, ba.Address, "", false)
+		for {
+			h, hasNext := iterFunc()
+			if !hasNext {
+				break
+			}
+
+			// genesis balance
+			if len(h.Confirmed) < 1 {
+				continue
+			}
+
+			var confirmed time.Time
+			if confirmed, err = sebakcommon.ParseISO8601(h.Confirmed); err != nil {
+				closeFunc()
+				return
+			}
+			if !confirmed.Before(cutoff) {
+				break
+			}
+			if h.Checkpoint == ba.Checkpoint {
+				continue
+			}
+
+			batch.Delete(h.Key())
+			n++
+
+			if batch, err = p.flush(batch, false); err != nil {
+				closeFunc()
+				return
+			}
+		}
+		closeFunc()
+	}
+
+	_, err = p.flush(batch, true)
+
+	return
+}
@@ -0,0 +1,111 @@
+package sebak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func TestPrunerArchival(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	_, tx := TestMakeTransaction(networkID, 1)
+	bth := NewTransactionHistoryFromTransaction(tx, sebakcommon.MustJSONMarshal(tx))
+	require.Nil(t, bth.Save(st))
+
+	pruner := NewPruner(st, PrunePolicy{})
+	result, err := pruner.Prune(time.Now().Add(time.Hour * 24 * 365))
+	require.Nil(t, err)
+	require.Equal(t, PruneResult{}, result)
+
+	_, err = GetBlockTransactionHistory(st, tx.GetHash())
+	require.Nil(t, err)
+}
+
+func TestPrunerPrune(t *testing.T) {
+	st, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	// history
+	_, tx := TestMakeTransaction(networkID, 1)
+	bth := NewTransactionHistoryFromTransaction(tx, sebakcommon.MustJSONMarshal(tx))
+	require.Nil(t, bth.Save(st))
+
+	// transaction
+	bt := TestMakeNewBlockTransaction(networkID, 1)
+	require.Nil(t, bt.Save(st))
+
+	// account with 3 checkpoints
+	ba := block.TestMakeBlockAccount()
+	require.Nil(t, ba.Save(st))
+	for i := 0; i < 2; i++ {
+		ba.Checkpoint = sebakcommon.GetUniqueIDFromUUID()
+		require.Nil(t, ba.Save(st))
+	}
+
+	// the histories of genesis, the old checkpoint and the current one
+	require.Nil(t, SaveBlockAccountHistory(st, &block.BlockAccount{Address: ba.Address, Checkpoint: "genesis"}, nil))
+	old := &BlockTransaction{Hash: "old", Confirmed: sebakcommon.NowISO8601()}
+	require.Nil(t, SaveBlockAccountHistory(st, &block.BlockAccount{Address: ba.Address, Checkpoint: "old"}, old))
+	require.Nil(t, SaveBlockAccountHistory(st, ba, &bt))
+
+	pruner := NewPruner(st, PrunePolicy{Window: time.Hour, Interval: time.Hour})
+
+	{ // nothing is older than window
+		result, err := pruner.Prune(time.Now())
+		require.Nil(t, err)
+		require.Equal(t, PruneResult{}, result)
+	}
+
+	{
+		result, err := pruner.Prune(time.Now().Add(time.Hour * 2))
+		require.Nil(t, err)
+		require.Equal(t, PruneResult{TransactionHistories: 1, TransactionMessages: 1, AccountCheckpoints: 2, AccountHistories: 1}, result)
+
+		exists, _ := BlockTransactionHistoryModel.Has(st, bth.Hash)
+		require.False(t, exists)
+
+		fetched, err := GetBlockTransaction(st, bt.Hash)
+		require.Nil(t, err)
+		require.Equal(t, 0, len(fetched.Message))
+		require.Equal(t, bt.Operations, fetched.Operations)
+
+		// the current checkpoint is kept
+		var checkpoints []string
+		iterFunc, closeFunc := block.GetBlockAccountCheckpointByAddress(st, ba.Address, false)
+		for {
+			bac, hasNext := iterFunc()
+			if !hasNext {
+				break
+			}
+			checkpoints = append(checkpoints, bac.Checkpoint)
+		}
+		closeFunc()
+		require.Equal(t, []string{ba.Checkpoint}, checkpoints)
+
+		// the histories of genesis and the current checkpoint are kept
+		checkpoints = nil
+		historyIterFunc, historyCloseFunc := GetBlockAccountHistory(st, ba.Address, "", false)
+		for {
+			h, hasNext := historyIterFunc()
+			if !hasNext {
+				break
+			}
+			checkpoints = append(checkpoints, h.Checkpoint)
+		}
+		historyCloseFunc()
+		require.Equal(t, []string{"genesis", ba.Checkpoint}, checkpoints)
+	}
+
+	{ // pruned ones are not pruned again
+		result, err := pruner.Prune(time.Now().Add(time.Hour * 2))
+		require.Nil(t, err)
+		require.Equal(t, PruneResult{}, result)
+	}
+}
@@ -0,0 +1,318 @@
+package sebak
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+// ReindexMismatch is the difference between the existing state and the
+// rebuilt state. The mismatch of account has `Address`, and the one of
+// transaction and operation has `Hash`.
+type ReindexMismatch struct {
+	Address  string
+	Hash     string
+	Field    string // one of 'account', 'balance', 'checkpoint', 'transaction', 'operation' and 'confirmed'
+	Existing string
+	Rebuilt  string
+}
+
+type ReindexResult struct {
+	Transactions int
+	Accounts     int
+	Mismatches   []ReindexMismatch
+}
+
+func (r ReindexResult) IsMatched() bool {
+	return len(r.Mismatches) < 1
+}
+
+// Reindex rebuilds the state into the empty storage, `to` by replaying the
+// `BlockTransaction`s of `from` in confirmed order, and then compares the
+// rebuilt state with the existing one. The accounts, checkpoints and all the
+// indexes of `to` are regenerated from the genesis account and the
+// `BlockTransaction.Message`s, keeping the original `Confirmed`, so `from`
+// must keep the full history; the storage pruned by `Pruner` is refused. The
+// indexes of `from` are not used, they can be broken.
+func Reindex(from, to sebakstorage.DBBackend, networkID []byte) (result ReindexResult, err error) {
+	if from == to {
+		err = errors.New("same storage can not be reindexed")
+		return
+	}
+
+	if !isEmptyStorage(to) {
+		err = sebakerror.ErrorReindexTargetNotEmpty
+		return
+	}
+
+	var pruned bool
+	if pruned, err = from.Has(PrunerMarkerMessage); err != nil {
+		return
+	} else if pruned {
+		err = sebakerror.ErrorReindexMessageNotFound
+		return
+	}
+
+	if err = reindexGenesis(from, to, networkID); err != nil {
+		return
+	}
+
+	var hashes []string
+	if hashes, err = getReindexBlockTransactionHashes(from); err != nil {
+		return
+	}
+
+	for _, hash := range hashes {
+		var bt BlockTransaction
+		if bt, err = GetBlockTransaction(from, hash); err != nil {
+			return
+		}
+
+		if len(bt.Message) < 1 {
+			err = sebakerror.ErrorReindexMessageNotFound
+			return
+		}
+
+		var tx Transaction
+		if tx, err = NewTransactionFromJSON(bt.Message); err != nil {
+			return
+		}
+		if tx.GetHash() != bt.Hash {
+			err = sebakerror.ErrorHashDoesNotMatch
+			return
+		}
+		if err = tx.IsWellFormed(networkID); err != nil {
+			return
+		}
+
+		if err = finishTransaction(to, tx, bt.Message, bt.Confirmed); err != nil {
+			return
+		}
+		result.Transactions++
+	}
+
+	if result.Accounts, result.Mismatches, err = compareReindexedAccounts(from, to); err != nil {
+		return
+	}
+
+	var mismatches []ReindexMismatch
+	if mismatches, err = compareReindexedTransactions(from, to, hashes); err != nil {
+		return
+	}
+	result.Mismatches = append(result.Mismatches, mismatches...)
+
+	return
+}
+
+// isEmptyStorage checks whether `st` has no key.
+func isEmptyStorage(st sebakstorage.DBBackend) bool {
+	iterFunc, closeFunc := st.GetIterator("", false)
+	defer closeFunc()
+
+	_, hasNext := iterFunc()
+	return !hasNext
+}
+
+type reindexBlockTransaction struct {
+	Hash      string
+	Confirmed string
+	Created   string
+}
+
+// getReindexBlockTransactionHashes returns the hashes of all the stored
+// `BlockTransaction`s in confirmed order. The records are read instead of
+// the 'bt-confirmed-' index, so the broken index does not drop or repeat the
+// transaction.
+func getReindexBlockTransactionHashes(st sebakstorage.DBBackend) (hashes []string, err error) {
+	var bts []reindexBlockTransaction
+
+	iterFunc, closeFunc := st.GetIterator(BlockTransactionModel.Key(""), false)
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var bt reindexBlockTransaction
+		if err = json.Unmarshal(item.Value, &bt); err != nil {
+			closeFunc()
+			return
+		}
+		bts = append(bts, bt)
+	}
+	closeFunc()
+
+	sort.Slice(bts, func(i, j int) bool {
+		if bts[i].Confirmed != bts[j].Confirmed {
+			return bts[i].Confirmed < bts[j].Confirmed
+		}
+		if bts[i].Created != bts[j].Created {
+			return bts[i].Created < bts[j].Created
+		}
+		return bts[i].Hash < bts[j].Hash
+	})
+
+	for _, bt := range bts {
+		hashes = append(hashes, bt.Hash)
+	}
+
+	return
+}
+
+// reindexGenesis copies the genesis accounts, which have the checkpoint of
+// genesis block, with their initial balances.
+func reindexGenesis(from, to sebakstorage.DBBackend, networkID []byte) (err error) {
+	checkpoint := sebakcommon.MakeGenesisCheckpoint(networkID)
+
+	iterFunc, closeFunc := block.GetBlockAccountsByCreated(from, false)
+	defer closeFunc()
+
+	var found bool
+	for {
+		ba, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var bac block.BlockAccountCheckpoint
+		bac, err = block.GetBlockAccountCheckpoint(from, ba.Address, checkpoint)
+		if err == sebakerror.ErrorStorageRecordDoesNotExist {
+			err = nil
+			continue
+		} else if err != nil {
+			return
+		}
+
+		genesis := block.NewBlockAccount(ba.Address, sebakcommon.MustAmountFromString(bac.Balance), checkpoint)
+		if err = genesis.Save(to); err != nil {
+			return
+		}
+		if err = SaveBlockAccountHistory(to, genesis, nil); err != nil {
+			return
+		}
+		found = true
+	}
+
+	if !found {
+		err = sebakerror.ErrorReindexGenesisNotFound
+	}
+
+	return
+}
+
+func compareReindexedAccounts(from, to sebakstorage.DBBackend) (n int, mismatches []ReindexMismatch, err error) {
+	iterFunc, closeFunc := block.GetBlockAccountsByCreated(from, false)
+	defer closeFunc()
+
+	for {
+		existing, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		n++
+
+		var rebuilt *block.BlockAccount
+		if rebuilt, err = block.GetBlockAccount(to, existing.Address); err != nil {
+			if err != sebakerror.ErrorStorageRecordDoesNotExist {
+				return
+			}
+			err = nil
+			mismatches = append(mismatches, ReindexMismatch{Address: existing.Address, Field: "account", Existing: "found"})
+			continue
+		}
+
+		if existing.Balance != rebuilt.Balance {
+			mismatches = append(mismatches, ReindexMismatch{
+				Address:  existing.Address,
+				Field:    "balance",
+				Existing: existing.Balance,
+				Rebuilt:  rebuilt.Balance,
+			})
+		}
+		if existing.Checkpoint != rebuilt.Checkpoint {
+			mismatches = append(mismatches, ReindexMismatch{
+				Address:  existing.Address,
+				Field:    "checkpoint",
+				Existing: existing.Checkpoint,
+				Rebuilt:  rebuilt.Checkpoint,
+			})
+		}
+	}
+
+	rebuiltIterFunc, rebuiltCloseFunc := block.GetBlockAccountsByCreated(to, false)
+	defer rebuiltCloseFunc()
+
+	for {
+		rebuilt, hasNext := rebuiltIterFunc()
+		if !hasNext {
+			break
+		}
+
+		var exists bool
+		if exists, err = block.ExistBlockAccount(from, rebuilt.Address); err != nil {
+			return
+		} else if !exists {
+			mismatches = append(mismatches, ReindexMismatch{Address: rebuilt.Address, Field: "account", Rebuilt: "found"})
+		}
+	}
+
+	return
+}
+
+// compareReindexedTransactions compares the `BlockTransaction`s of `hashes`
+// and their `BlockOperation`s of `from` with the rebuilt ones.
+func compareReindexedTransactions(from, to sebakstorage.DBBackend, hashes []string) (mismatches []ReindexMismatch, err error) {
+	for _, txHash := range hashes {
+		var existing BlockTransaction
+		if existing, err = GetBlockTransaction(from, txHash); err != nil {
+			return
+		}
+
+		var rebuilt BlockTransaction
+		if rebuilt, err = GetBlockTransaction(to, existing.Hash); err != nil {
+			if err != sebakerror.ErrorStorageRecordDoesNotExist {
+				return
+			}
+			err = nil
+			mismatches = append(mismatches, ReindexMismatch{Hash: existing.Hash, Field: "transaction", Existing: "found"})
+			continue
+		}
+
+		if existing.Confirmed != rebuilt.Confirmed {
+			mismatches = append(mismatches, ReindexMismatch{
+				Hash:     existing.Hash,
+				Field:    "confirmed",
+				Existing: existing.Confirmed,
+				Rebuilt:  rebuilt.Confirmed,
+			})
+		}
+
+		for _, hash := range existing.Operations {
+			var bo BlockOperation
+			if bo, err = GetBlockOperation(to, hash); err != nil {
+				if err != sebakerror.ErrorStorageRecordDoesNotExist {
+					return
+				}
+				err = nil
+				mismatches = append(mismatches, ReindexMismatch{Hash: hash, Field: "operation", Existing: "found"})
+				continue
+			}
+
+			if bo.Confirmed != existing.Confirmed {
+				mismatches = append(mismatches, ReindexMismatch{
+					Hash:     hash,
+					Field:    "confirmed",
+					Existing: existing.Confirmed,
+					Rebuilt:  bo.Confirmed,
+				})
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,216 @@
+package sebak
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/block"
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+	"boscoin.io/sebak/lib/storage"
+)
+
+func makeReindexTestStorage(t *testing.T) (st *sebakstorage.LevelDBBackend, kpGenesis, kpTarget *keypair.Full) {
+	st, _ = sebakstorage.NewTestMemoryLevelDBBackend()
+
+	kpGenesis, _ = keypair.Random()
+	kpTarget, _ = keypair.Random()
+
+	genesis := block.NewBlockAccount(
+		kpGenesis.Address(),
+		sebakcommon.Amount(1000000),
+		sebakcommon.MakeGenesisCheckpoint(networkID),
+	)
+	require.Nil(t, genesis.Save(st))
+
+	opCreate, _ := NewOperation(
+		OperationCreateAccount,
+		NewOperationBodyCreateAccount(kpTarget.Address(), sebakcommon.Amount(5000)),
+	)
+	tx, _ := NewTransaction(kpGenesis.Address(), genesis.Checkpoint, opCreate)
+	tx.Sign(kpGenesis, networkID)
+	require.Nil(t, finishTransaction(st, tx, sebakcommon.MustJSONMarshal(tx), ""))
+
+	ba, _ := block.GetBlockAccount(st, kpGenesis.Address())
+	opPayment, _ := NewOperation(
+		OperationPayment,
+		OperationBodyPayment{Target: kpTarget.Address(), Amount: sebakcommon.Amount(300)},
+	)
+	tx, _ = NewTransaction(kpGenesis.Address(), ba.Checkpoint, opPayment)
+	tx.Sign(kpGenesis, networkID)
+	require.Nil(t, finishTransaction(st, tx, sebakcommon.MustJSONMarshal(tx), ""))
+
+	return
+}
+
+func TestReindex(t *testing.T) {
+	from, kpGenesis, kpTarget := makeReindexTestStorage(t)
+	defer from.Close()
+
+	to, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer to.Close()
+
+	result, err := Reindex(from, to, networkID)
+	require.Nil(t, err)
+	require.True(t, result.IsMatched())
+	require.Equal(t, 2, result.Transactions)
+	require.Equal(t, 2, result.Accounts)
+
+	for _, address := range []string{kpGenesis.Address(), kpTarget.Address()} {
+		existing, _ := block.GetBlockAccount(from, address)
+		rebuilt, err := block.GetBlockAccount(to, address)
+		require.Nil(t, err)
+		require.Equal(t, existing.Balance, rebuilt.Balance)
+		require.Equal(t, existing.Checkpoint, rebuilt.Checkpoint)
+	}
+
+	// indexes are regenerated
+	n, err := CountBlockTransactionsByAccount(to, kpTarget.Address())
+	require.Nil(t, err)
+	require.Equal(t, 2, n)
+
+	// the balance histories of genesis and transactions
+	for address, expected := range map[string]int{kpGenesis.Address(): 3, kpTarget.Address(): 2} {
+		var histories []BlockAccountHistory
+		historyIterFunc, historyCloseFunc := GetBlockAccountHistory(to, address, "", false)
+		for {
+			h, hasNext := historyIterFunc()
+			if !hasNext {
+				break
+			}
+			histories = append(histories, h)
+		}
+		historyCloseFunc()
+
+		require.Equal(t, expected, len(histories))
+		ba, _ := block.GetBlockAccount(to, address)
+		require.Equal(t, ba.Balance, histories[len(histories)-1].Balance)
+	}
+
+	// the confirmed time is kept
+	iterFunc, closeFunc := GetBlockTransactionsByConfirmed(from, false)
+	defer closeFunc()
+	for {
+		existing, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		rebuilt, err := GetBlockTransaction(to, existing.Hash)
+		require.Nil(t, err)
+		require.Equal(t, existing.Confirmed, rebuilt.Confirmed)
+
+		bo, err := GetBlockOperation(to, existing.Operations[0])
+		require.Nil(t, err)
+		require.Equal(t, existing.Confirmed, bo.Confirmed)
+	}
+}
+
+func TestReindexPruned(t *testing.T) {
+	from, _, _ := makeReindexTestStorage(t)
+	defer from.Close()
+
+	pruner := NewPruner(from, PrunePolicy{Window: time.Hour, Interval: time.Hour})
+	_, err := pruner.Prune(time.Now())
+	require.Nil(t, err)
+
+	to, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer to.Close()
+
+	_, err = Reindex(from, to, networkID)
+	require.Equal(t, sebakerror.ErrorReindexMessageNotFound, err)
+
+	// nothing is rebuilt
+	iterFunc, closeFunc := to.GetIterator("", false)
+	defer closeFunc()
+	_, hasNext := iterFunc()
+	require.False(t, hasNext)
+}
+
+func TestReindexCorrupted(t *testing.T) {
+	from, _, kpTarget := makeReindexTestStorage(t)
+	defer from.Close()
+
+	ba, _ := block.GetBlockAccount(from, kpTarget.Address())
+	require.Nil(t, ba.Deposit(sebakcommon.Amount(1), ba.Checkpoint))
+	require.Nil(t, ba.Save(from))
+
+	to, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer to.Close()
+
+	result, err := Reindex(from, to, networkID)
+	require.Nil(t, err)
+	require.False(t, result.IsMatched())
+	require.Equal(t, 1, len(result.Mismatches))
+	require.Equal(t, kpTarget.Address(), result.Mismatches[0].Address)
+	require.Equal(t, "balance", result.Mismatches[0].Field)
+}
+
+func TestReindexBrokenConfirmedIndex(t *testing.T) {
+	from, _, _ := makeReindexTestStorage(t)
+	defer from.Close()
+
+	// the confirmed index of the first transaction is missing, and the one of
+	// the second is duplicated
+	var keys, values []string
+	iterFunc, closeFunc := from.GetIterator(BlockTransactionModel.IndexPrefix(BlockTransactionIndexConfirmed, ""), false)
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		keys = append(keys, string(item.Key))
+
+		var hash string
+		require.Nil(t, json.Unmarshal(item.Value, &hash))
+		values = append(values, hash)
+	}
+	closeFunc()
+	require.Equal(t, 2, len(keys))
+
+	require.Nil(t, from.Remove(keys[0]))
+	require.Nil(t, from.New(keys[1]+"-duplicated", values[1]))
+
+	to, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer to.Close()
+
+	result, err := Reindex(from, to, networkID)
+	require.Nil(t, err)
+	require.True(t, result.IsMatched())
+	require.Equal(t, 2, result.Transactions)
+}
+
+func TestReindexTargetNotEmpty(t *testing.T) {
+	from, _, _ := makeReindexTestStorage(t)
+	defer from.Close()
+
+	to, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer to.Close()
+	require.Nil(t, to.New("showme", "findme"))
+
+	_, err := Reindex(from, to, networkID)
+	require.Equal(t, sebakerror.ErrorReindexTargetNotEmpty, err)
+
+	// nothing is merged
+	iterFunc, closeFunc := to.GetIterator("", false)
+	defer closeFunc()
+	item, hasNext := iterFunc()
+	require.True(t, hasNext)
+	require.Equal(t, "showme", string(item.Key))
+	_, hasNext = iterFunc()
+	require.False(t, hasNext)
+}
+
+func TestReindexWithoutGenesis(t *testing.T) {
+	from, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer from.Close()
+	to, _ := sebakstorage.NewTestMemoryLevelDBBackend()
+	defer to.Close()
+
+	_, err := Reindex(from, to, networkID)
+	require.Equal(t, sebakerror.ErrorReindexGenesisNotFound, err)
+}
@@ -0,0 +1,41 @@
+package sebakstorage
+
+// Batch collects the writes and the deletes, which will be applied at once by
+// `LevelDBBackend.Write`.
+type Batch struct {
+	items []batchItem
+}
+
+type batchItem struct {
+	key    string
+	value  []byte
+	delete bool
+}
+
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+func (b *Batch) Put(k string, v []byte) {
+	b.items = append(b.items, batchItem{key: k, value: v})
+}
+
+func (b *Batch) Delete(k string) {
+	b.items = append(b.items, batchItem{key: k, delete: true})
+}
+
+func (b *Batch) Len() int {
+	return len(b.items)
+}
+
+// Has checks the key is written by the pending items; the deleted key is not
+// found.
+func (b *Batch) Has(k string) bool {
+	for i := len(b.items) - 1; i >= 0; i-- {
+		if b.items[i].key == k {
+			return !b.items[i].delete
+		}
+	}
+
+	return false
+}
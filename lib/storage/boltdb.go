@@ -0,0 +1,494 @@
+package sebakstorage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+// BoltDBBackend is the `DBBackend` on the B+tree store, bbolt. All the records
+// are kept in the single bucket, so the keys are sorted like `LevelDBBackend`.
+//
+// bbolt allows only one writer, and the transaction of `OpenTransaction` holds
+// it until `Commit` or `Discard`. Like `LevelDBBackend`, the writes on the
+// root backend wait for the transaction, but the root write from the same
+// goroutine will never get the writer; instead of the deadlock, it fails with
+// `ErrorBoltDBWriterTimeout` after `BoltDBWriterTimeout`. The writes inside
+// transaction must be done by the transaction itself.
+type BoltDBBackend struct {
+	DB *bolt.DB
+
+	// tx is set only inside transaction
+	tx *bolt.Tx
+
+	// writer is shared with the transactions of same `bolt.DB`; it is held
+	// while the root backend writes or the transaction is opened.
+	writer chan struct{}
+
+	caches      *cacheGroup
+	invalidated []string
+}
+
+var boltDBBucketName = []byte("sebak")
+
+// boltDBIteratorPageSize is the number of items, which are read at once by
+// the iterator. The iterator does not keep the bolt transaction open between
+// the calls, so the long iteration does not block the writes.
+var boltDBIteratorPageSize int = 100
+
+// BoltDBWriterTimeout is how long the write waits the opened transaction.
+var BoltDBWriterTimeout time.Duration = 30 * time.Second
+
+var ErrorBoltDBWriterTimeout = errors.New("timeout to wait the writer; the transaction may be opened by the same goroutine")
+
+func (st *BoltDBBackend) Init(config *Config) (err error) {
+	if config.Scheme != "bolt" {
+		return fmt.Errorf("unsupported storage scheme, '%s'", config.Scheme)
+	}
+	if len(config.Path) < 1 {
+		return errors.New("empty path for bolt storage")
+	}
+
+	var db *bolt.DB
+	if db, err = bolt.Open(config.Path, 0600, &bolt.Options{Timeout: 5 * time.Second}); err != nil {
+		return
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltDBBucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return
+	}
+
+	st.DB = db
+	st.caches = newCacheGroup()
+	st.writer = make(chan struct{}, 1)
+
+	return
+}
+
+func (st *BoltDBBackend) acquireWriter() error {
+	select {
+	case st.writer <- struct{}{}:
+		return nil
+	case <-time.After(BoltDBWriterTimeout):
+		return ErrorBoltDBWriterTimeout
+	}
+}
+
+func (st *BoltDBBackend) releaseWriter() {
+	<-st.writer
+}
+
+func (st *BoltDBBackend) Close() error {
+	return st.DB.Close()
+}
+
+func (st *BoltDBBackend) OpenTransaction() (DBBackend, error) {
+	if st.tx != nil {
+		return nil, errors.New("this is already transaction")
+	}
+
+	if err := st.acquireWriter(); err != nil {
+		return nil, err
+	}
+
+	tx, err := st.DB.Begin(true)
+	if err != nil {
+		st.releaseWriter()
+		return nil, err
+	}
+
+	return &BoltDBBackend{
+		DB:     st.DB,
+		tx:     tx,
+		caches: st.caches,
+		writer: st.writer,
+	}, nil
+}
+
+func (st *BoltDBBackend) Discard() error {
+	if st.tx == nil {
+		return errors.New("this is not transaction")
+	}
+
+	err := st.tx.Rollback()
+	if err != bolt.ErrTxClosed {
+		st.releaseWriter()
+	}
+	st.invalidated = nil
+
+	return err
+}
+
+func (st *BoltDBBackend) Commit() error {
+	if st.tx == nil {
+		return errors.New("this is not transaction")
+	}
+
+	// the failed commit also closes the bolt transaction
+	if err := st.tx.Commit(); err != nil {
+		if err != bolt.ErrTxClosed {
+			st.releaseWriter()
+		}
+		return err
+	}
+	st.releaseWriter()
+
+	st.caches.remove(st.invalidated...)
+	st.invalidated = nil
+
+	return nil
+}
+
+// Cache returns the named `Cache` of storage. Inside transaction, it returns
+// nil, so the reads inside transaction do not touch the cache.
+func (st *BoltDBBackend) Cache(name string) *Cache {
+	if st.caches == nil || st.tx != nil {
+		return nil
+	}
+
+	return st.caches.get(name)
+}
+
+func (st *BoltDBBackend) invalidate(keys ...string) {
+	if st.caches == nil {
+		return
+	}
+
+	if st.tx != nil {
+		st.invalidated = append(st.invalidated, keys...)
+		return
+	}
+
+	st.caches.remove(keys...)
+}
+
+func (st *BoltDBBackend) view(f func(*bolt.Bucket) error) error {
+	if st.tx != nil {
+		return f(st.tx.Bucket(boltDBBucketName))
+	}
+
+	return st.DB.View(func(tx *bolt.Tx) error {
+		return f(tx.Bucket(boltDBBucketName))
+	})
+}
+
+func (st *BoltDBBackend) update(f func(*bolt.Bucket) error) error {
+	if st.tx != nil {
+		return f(st.tx.Bucket(boltDBBucketName))
+	}
+
+	if err := st.acquireWriter(); err != nil {
+		return err
+	}
+	defer st.releaseWriter()
+
+	return st.DB.Update(func(tx *bolt.Tx) error {
+		return f(tx.Bucket(boltDBBucketName))
+	})
+}
+
+func (st *BoltDBBackend) makeKey(key string) []byte {
+	return []byte(key)
+}
+
+func (st *BoltDBBackend) Has(k string) (exists bool, err error) {
+	err = st.view(func(b *bolt.Bucket) error {
+		exists = b.Get(st.makeKey(k)) != nil
+		return nil
+	})
+
+	return
+}
+
+func (st *BoltDBBackend) GetRaw(k string) (v []byte, err error) {
+	err = st.view(func(b *bolt.Bucket) error {
+		found := b.Get(st.makeKey(k))
+		if found == nil {
+			return sebakerror.ErrorStorageRecordDoesNotExist
+		}
+
+		// the value from bolt is valid only inside transaction
+		v = append([]byte(nil), found...)
+		return nil
+	})
+
+	return
+}
+
+func (st *BoltDBBackend) Get(k string, i interface{}) (err error) {
+	var b []byte
+	if b, err = st.GetRaw(k); err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(b, &i); err != nil {
+		return
+	}
+
+	return
+}
+
+func (st *BoltDBBackend) New(k string, v interface{}) (err error) {
+	var encoded []byte
+	if encoded, err = encodeStorageValue(v); err != nil {
+		return
+	}
+
+	err = st.update(func(b *bolt.Bucket) error {
+		if b.Get(st.makeKey(k)) != nil {
+			return fmt.Errorf("key, '%s' already exists", k)
+		}
+
+		return b.Put(st.makeKey(k), encoded)
+	})
+	st.invalidate(k)
+
+	return
+}
+
+func (st *BoltDBBackend) News(vs ...Item) (err error) {
+	if len(vs) < 1 {
+		err = errors.New("empty values")
+		return
+	}
+
+	var keys []string
+	err = st.update(func(b *bolt.Bucket) error {
+		for _, v := range vs {
+			if b.Get(st.makeKey(v.Key)) != nil {
+				return fmt.Errorf("found existing key, '%s'", v.Key)
+			}
+		}
+
+		for _, v := range vs {
+			encoded, err := sebakcommon.EncodeJSONValue(v)
+			if err != nil {
+				return err
+			}
+			if err = b.Put(st.makeKey(v.Key), encoded); err != nil {
+				return err
+			}
+			keys = append(keys, v.Key)
+		}
+
+		return nil
+	})
+	st.invalidate(keys...)
+
+	return
+}
+
+func (st *BoltDBBackend) Set(k string, v interface{}) (err error) {
+	var encoded []byte
+	if encoded, err = sebakcommon.EncodeJSONValue(v); err != nil {
+		return
+	}
+
+	err = st.update(func(b *bolt.Bucket) error {
+		if b.Get(st.makeKey(k)) == nil {
+			return fmt.Errorf("key, '%s' does not exists", k)
+		}
+
+		return b.Put(st.makeKey(k), encoded)
+	})
+	st.invalidate(k)
+
+	return
+}
+
+func (st *BoltDBBackend) Sets(vs ...Item) (err error) {
+	if len(vs) < 1 {
+		err = errors.New("empty values")
+		return
+	}
+
+	var keys []string
+	err = st.update(func(b *bolt.Bucket) error {
+		for _, v := range vs {
+			if b.Get(st.makeKey(v.Key)) == nil {
+				return fmt.Errorf("not found key, '%s'", v.Key)
+			}
+		}
+
+		for _, v := range vs {
+			encoded, err := sebakcommon.EncodeJSONValue(v)
+			if err != nil {
+				return err
+			}
+			if err = b.Put(st.makeKey(v.Key), encoded); err != nil {
+				return err
+			}
+			keys = append(keys, v.Key)
+		}
+
+		return nil
+	})
+	st.invalidate(keys...)
+
+	return
+}
+
+func (st *BoltDBBackend) Remove(k string) (err error) {
+	err = st.update(func(b *bolt.Bucket) error {
+		if b.Get(st.makeKey(k)) == nil {
+			return fmt.Errorf("key, '%s' does not exists", k)
+		}
+
+		return b.Delete(st.makeKey(k))
+	})
+	st.invalidate(k)
+
+	return
+}
+
+func (st *BoltDBBackend) Write(batch *Batch) (err error) {
+	if batch.Len() < 1 {
+		return
+	}
+
+	keys := make([]string, 0, batch.Len())
+	err = st.update(func(b *bolt.Bucket) error {
+		for _, item := range batch.items {
+			keys = append(keys, item.key)
+
+			var err error
+			if item.delete {
+				err = b.Delete(st.makeKey(item.key))
+			} else {
+				err = b.Put(st.makeKey(item.key), item.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	st.invalidate(keys...)
+
+	return
+}
+
+func (st *BoltDBBackend) GetIterator(prefix string, reverse bool) (func() (IterItem, bool), func()) {
+	var limit string
+	if len(prefix) > 0 {
+		limit = prefixLimit(prefix)
+	}
+
+	return st.newIterator(st.makeKey(prefix), st.makeKey(limit), reverse)
+}
+
+// GetIteratorRange iterates the keys from `start` to `limit`. `start` is
+// included, but `limit` is not. If `limit` is empty, there is no upper bound.
+func (st *BoltDBBackend) GetIteratorRange(start, limit string, reverse bool) (func() (IterItem, bool), func()) {
+	return st.newIterator(st.makeKey(start), st.makeKey(limit), reverse)
+}
+
+func (st *BoltDBBackend) newIterator(start, limit []byte, reverse bool) (func() (IterItem, bool), func()) {
+	var items []IterItem
+	var closed bool
+
+	// `from` is the next position to read; in reverse, it is the exclusive
+	// upper bound.
+	from := start
+	if reverse {
+		from = limit
+	}
+
+	fetch := func() {
+		items = items[:0]
+		st.view(func(b *bolt.Bucket) error {
+			c := b.Cursor()
+
+			var k, v []byte
+			if reverse {
+				if len(from) < 1 {
+					k, v = c.Last()
+				} else if k, v = c.Seek(from); k == nil {
+					k, v = c.Last()
+				} else {
+					k, v = c.Prev()
+				}
+			} else {
+				k, v = c.Seek(from)
+			}
+
+			for k != nil && len(items) < boltDBIteratorPageSize {
+				if reverse && bytes.Compare(k, start) < 0 {
+					break
+				}
+				if !reverse && len(limit) > 0 && bytes.Compare(k, limit) >= 0 {
+					break
+				}
+
+				items = append(items, IterItem{
+					Key:   append([]byte(nil), k...),
+					Value: append([]byte(nil), v...),
+				})
+
+				if reverse {
+					k, v = c.Prev()
+				} else {
+					k, v = c.Next()
+				}
+			}
+
+			return nil
+		})
+
+		if len(items) < boltDBIteratorPageSize {
+			closed = true
+			return
+		}
+
+		last := items[len(items)-1].Key
+		if reverse {
+			from = last
+		} else {
+			from = append(append([]byte(nil), last...), 0)
+		}
+	}
+
+	// follow the numbering of `LevelDBBackend`
+	var n int64
+	if reverse {
+		n = -1
+	}
+
+	var index int
+	return (func() (IterItem, bool) {
+			if index >= len(items) {
+				if closed {
+					return IterItem{}, false
+				}
+
+				fetch()
+				index = 0
+				if len(items) < 1 {
+					return IterItem{}, false
+				}
+			}
+
+			item := items[index]
+			index++
+			n++
+			item.N = n
+
+			return item, true
+		}), (func() {
+			closed = true
+			items = nil
+		})
+}
@@ -0,0 +1,219 @@
+package sebakstorage
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultCacheSize is the maximum number of the items kept by one `Cache`.
+var DefaultCacheSize int = 10000
+
+var (
+	cacheHitsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "sebak",
+			Subsystem: "storage",
+			Name:      "cache_hits_total",
+			Help:      "Number of the storage cache hits.",
+		},
+		[]string{"cache"},
+	)
+	cacheMissesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "sebak",
+			Subsystem: "storage",
+			Name:      "cache_misses_total",
+			Help:      "Number of the storage cache misses.",
+		},
+		[]string{"cache"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(cacheHitsCounter, cacheMissesCounter)
+}
+
+// Cache is the bounded LRU cache of the decoded records, which are keyed by
+// the storage key. `DBBackend` removes the key from it's caches whenever
+// the key is written, so the cached value is always same with the stored one.
+//
+// The cached value is shared by the callers, so it must not be modified; the
+// caller should copy it before modifying.
+type Cache struct {
+	sync.Mutex
+
+	name       string
+	size       int
+	items      map[string]*list.Element
+	order      *list.List
+	generation uint64
+
+	hits   uint64
+	misses uint64
+}
+
+type cacheItem struct {
+	key   string
+	value interface{}
+}
+
+func NewCache(name string, size int) *Cache {
+	return &Cache{
+		name:  name,
+		size:  size,
+		items: map[string]*list.Element{},
+		order: list.New(),
+	}
+}
+
+func (c *Cache) Name() string {
+	return c.name
+}
+
+func (c *Cache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *Cache) Hits() uint64 {
+	return atomic.LoadUint64(&c.hits)
+}
+
+func (c *Cache) Misses() uint64 {
+	return atomic.LoadUint64(&c.misses)
+}
+
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.Lock()
+	element, found := c.items[key]
+	if found {
+		c.order.MoveToFront(element)
+	}
+	c.Unlock()
+
+	if !found {
+		atomic.AddUint64(&c.misses, 1)
+		cacheMissesCounter.WithLabelValues(c.name).Inc()
+		return nil, false
+	}
+
+	atomic.AddUint64(&c.hits, 1)
+	cacheHitsCounter.WithLabelValues(c.name).Inc()
+
+	return element.Value.(*cacheItem).value, true
+}
+
+// Load returns the cached value of `key`. If it is not cached, the value is
+// loaded by `load` and cached. The nil `Cache` just calls `load`.
+func (c *Cache) Load(key string, load func() (interface{}, error)) (v interface{}, err error) {
+	if c == nil {
+		return load()
+	}
+
+	var found bool
+	if v, found = c.Get(key); found {
+		return
+	}
+
+	c.Lock()
+	generation := c.generation
+	c.Unlock()
+
+	if v, err = load(); err != nil {
+		return
+	}
+
+	c.add(generation, key, v)
+
+	return
+}
+
+// add caches the value only when nothing was removed after `generation`;
+// otherwise the loaded value can be older than the stored one.
+func (c *Cache) add(generation uint64, key string, v interface{}) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.generation != generation {
+		return
+	}
+
+	if element, found := c.items[key]; found {
+		element.Value.(*cacheItem).value = v
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&cacheItem{key: key, value: v})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheItem).key)
+	}
+}
+
+func (c *Cache) Remove(keys ...string) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	for _, key := range keys {
+		if element, found := c.items[key]; found {
+			c.order.Remove(element)
+			delete(c.items, key)
+		}
+	}
+}
+
+func (c *Cache) Purge() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	c.items = map[string]*list.Element{}
+	c.order.Init()
+}
+
+type cacheGroup struct {
+	sync.RWMutex
+
+	caches map[string]*Cache
+}
+
+func newCacheGroup() *cacheGroup {
+	return &cacheGroup{caches: map[string]*Cache{}}
+}
+
+func (g *cacheGroup) get(name string) *Cache {
+	g.RLock()
+	c, found := g.caches[name]
+	g.RUnlock()
+	if found {
+		return c
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	if c, found = g.caches[name]; !found {
+		c = NewCache(name, DefaultCacheSize)
+		g.caches[name] = c
+	}
+
+	return c
+}
+
+func (g *cacheGroup) remove(keys ...string) {
+	g.RLock()
+	defer g.RUnlock()
+
+	for _, c := range g.caches {
+		c.Remove(keys...)
+	}
+}
@@ -0,0 +1,89 @@
+package sebakstorage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheEviction(t *testing.T) {
+	c := NewCache("test", 3)
+
+	for i := 0; i < 4; i++ {
+		c.Load(fmt.Sprintf("%d", i), func() (interface{}, error) { return i, nil })
+	}
+	require.Equal(t, 3, c.Len())
+
+	// the oldest one is evicted
+	_, found := c.Get("0")
+	require.False(t, found)
+
+	v, found := c.Get("3")
+	require.True(t, found)
+	require.Equal(t, 3, v)
+
+	require.Equal(t, uint64(1), c.Hits())
+	require.Equal(t, uint64(5), c.Misses())
+}
+
+func TestCacheInvalidatedByWrite(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	load := func() (interface{}, error) {
+		var v string
+		err := st.Get("key", &v)
+		return v, err
+	}
+
+	require.Nil(t, st.New("key", "1"))
+
+	v, err := st.Cache("test").Load("key", load)
+	require.Nil(t, err)
+	require.Equal(t, "1", v)
+	require.Equal(t, 1, st.Cache("test").Len())
+
+	require.Nil(t, st.Set("key", "2"))
+	require.Equal(t, 0, st.Cache("test").Len())
+
+	v, _ = st.Cache("test").Load("key", load)
+	require.Equal(t, "2", v)
+}
+
+func TestCacheInvalidatedByCommit(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	load := func() (interface{}, error) {
+		var v string
+		err := st.Get("key", &v)
+		return v, err
+	}
+
+	require.Nil(t, st.New("key", "1"))
+	st.Cache("test").Load("key", load)
+
+	{ // discarded transaction does not touch the cache
+		ts, _ := st.OpenTransaction()
+		require.Nil(t, ts.Cache("test"))
+		require.Nil(t, ts.Set("key", "2"))
+		require.Nil(t, ts.Discard())
+
+		v, _ := st.Cache("test").Load("key", load)
+		require.Equal(t, "1", v)
+	}
+
+	{ // the written keys are invalidated after commit
+		ts, _ := st.OpenTransaction()
+		require.Nil(t, ts.Set("key", "3"))
+
+		v, _ := st.Cache("test").Load("key", load)
+		require.Equal(t, "1", v)
+
+		require.Nil(t, ts.Commit())
+
+		v, _ = st.Cache("test").Load("key", load)
+		require.Equal(t, "3", v)
+	}
+}
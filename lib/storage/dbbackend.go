@@ -1,17 +1,50 @@
 package sebakstorage
 
+import "strings"
+
+// DBBackend is the storage engine of ledger. `LevelDBBackend` and
+// `BoltDBBackend` implement it.
 type DBBackend interface {
+	Close() error
+
 	Has(string) (bool, error)
+	GetRaw(string) ([]byte, error)
 	Get(string, interface{}) error
 	New(string, interface{}) error
 	Set(string, interface{}) error
 	Remove(string) error
 
 	GetIterator(prefix string, reverse bool) (func() (IterItem, bool), func())
+	GetIteratorRange(start, limit string, reverse bool) (func() (IterItem, bool), func())
 
 	News(...Item) error
 	Sets(...Item) error
+	Write(*Batch) error
 
+	OpenTransaction() (DBBackend, error)
 	Commit() error
 	Discard() error
+
+	Cache(string) *Cache
+}
+
+// GetIteratorAfter iterates the keys of `prefix`, which come after
+// `prefix + cursor` in the order of iteration; the key of `cursor` itself is
+// not included. With empty `cursor`, it is same with `GetIterator`.
+func GetIteratorAfter(st DBBackend, prefix, cursor string, reverse bool) (func() (IterItem, bool), func()) {
+	if len(cursor) < 1 {
+		return st.GetIterator(prefix, reverse)
+	}
+
+	if reverse {
+		return st.GetIteratorRange(prefix, prefix+cursor, true)
+	}
+
+	// "\x00" is the smallest suffix, so the next key of cursor is the start
+	return st.GetIteratorRange(prefix+cursor+"\x00", prefixLimit(prefix), false)
+}
+
+// Cursor returns the cursor of `GetIteratorAfter` from the key of item.
+func Cursor(prefix string, item IterItem) string {
+	return strings.TrimPrefix(string(item.Key), prefix)
 }
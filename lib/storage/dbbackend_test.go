@@ -0,0 +1,265 @@
+package sebakstorage
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+// testDBBackends runs `f` with the every `DBBackend`.
+func testDBBackends(t *testing.T, f func(*testing.T, DBBackend)) {
+	t.Run("leveldb", func(t *testing.T) {
+		st, err := NewTestMemoryLevelDBBackend()
+		require.Nil(t, err)
+		defer st.Close()
+
+		f(t, st)
+	})
+
+	t.Run("bolt", func(t *testing.T) {
+		dbpath := fmt.Sprintf("/tmp/%s", sebakcommon.GetUniqueIDFromUUID())
+		defer os.RemoveAll(dbpath)
+
+		st, err := NewTestBoltDBBackend(dbpath)
+		require.Nil(t, err)
+		defer st.Close()
+
+		f(t, st)
+	})
+}
+
+func collectTestDBBackendKeys(iterFunc func() (IterItem, bool), closeFunc func()) (keys []string) {
+	defer closeFunc()
+
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+		keys = append(keys, string(item.Key))
+	}
+
+	return
+}
+
+func TestNewStorageBolt(t *testing.T) {
+	dbpath := fmt.Sprintf("/tmp/%s", sebakcommon.GetUniqueIDFromUUID())
+	defer os.RemoveAll(dbpath)
+
+	config, err := NewConfigFromString(fmt.Sprintf("bolt://%s", dbpath))
+	require.Nil(t, err)
+
+	st, err := NewStorage(config)
+	require.Nil(t, err)
+	defer st.Close()
+
+	_, ok := st.(*BoltDBBackend)
+	require.True(t, ok)
+}
+
+func TestBoltDBBackendWriteInsideTransaction(t *testing.T) {
+	defer func(d time.Duration) { BoltDBWriterTimeout = d }(BoltDBWriterTimeout)
+	BoltDBWriterTimeout = 100 * time.Millisecond
+
+	dbpath := fmt.Sprintf("/tmp/%s", sebakcommon.GetUniqueIDFromUUID())
+	defer os.RemoveAll(dbpath)
+
+	st, err := NewTestBoltDBBackend(dbpath)
+	require.Nil(t, err)
+	defer st.Close()
+
+	ts, err := st.OpenTransaction()
+	require.Nil(t, err)
+
+	// the root write from the same goroutine fails instead of the deadlock
+	require.Equal(t, ErrorBoltDBWriterTimeout, st.New("root", "1"))
+	require.Nil(t, ts.New("inside", "1"))
+	require.Nil(t, ts.Commit())
+
+	// after commit, the writer is released
+	require.Nil(t, st.New("root", "1"))
+	require.NotNil(t, ts.Commit())
+	require.Nil(t, st.New("root-after", "1"))
+}
+
+func TestDBBackendNewGetSetRemove(t *testing.T) {
+	testDBBackends(t, func(t *testing.T, st DBBackend) {
+		require.Nil(t, st.New("key", "1"))
+		require.NotNil(t, st.New("key", "1"))
+
+		exists, err := st.Has("key")
+		require.Nil(t, err)
+		require.True(t, exists)
+
+		var v string
+		require.Nil(t, st.Get("key", &v))
+		require.Equal(t, "1", v)
+
+		raw, err := st.GetRaw("key")
+		require.Nil(t, err)
+		require.Equal(t, []byte(`"1"`), raw)
+
+		require.Nil(t, st.Set("key", "2"))
+		require.NotNil(t, st.Set("unknown", "2"))
+		require.Nil(t, st.Get("key", &v))
+		require.Equal(t, "2", v)
+
+		require.Nil(t, st.Remove("key"))
+		require.NotNil(t, st.Remove("key"))
+
+		_, err = st.GetRaw("key")
+		require.Equal(t, sebakerror.ErrorStorageRecordDoesNotExist, err)
+	})
+}
+
+func TestDBBackendNewsSets(t *testing.T) {
+	testDBBackends(t, func(t *testing.T, st DBBackend) {
+		require.Nil(t, st.News(Item{Key: "a", Value: 1}, Item{Key: "b", Value: 2}))
+		require.NotNil(t, st.News(Item{Key: "c", Value: 3}, Item{Key: "a", Value: 1}))
+
+		exists, _ := st.Has("c")
+		require.False(t, exists)
+
+		require.Nil(t, st.Sets(Item{Key: "a", Value: 10}))
+		require.NotNil(t, st.Sets(Item{Key: "a", Value: 10}, Item{Key: "c", Value: 3}))
+	})
+}
+
+func TestDBBackendWrite(t *testing.T) {
+	testDBBackends(t, func(t *testing.T, st DBBackend) {
+		require.Nil(t, st.New("removed", "0"))
+
+		batch := NewBatch()
+		batch.Put("a", []byte(`"1"`))
+		batch.Put("b", []byte(`"2"`))
+		batch.Delete("removed")
+		require.Nil(t, st.Write(batch))
+
+		var v string
+		require.Nil(t, st.Get("b", &v))
+		require.Equal(t, "2", v)
+
+		exists, _ := st.Has("removed")
+		require.False(t, exists)
+	})
+}
+
+func TestDBBackendIterator(t *testing.T) {
+	testDBBackends(t, func(t *testing.T, st DBBackend) {
+		var expected []string
+		for i := 0; i < 250; i++ {
+			key := fmt.Sprintf("p-%03d", i)
+			require.Nil(t, st.New(key, i))
+			expected = append(expected, key)
+		}
+		require.Nil(t, st.New("o-000", 0))
+		require.Nil(t, st.New("q-000", 0))
+
+		keys := collectTestDBBackendKeys(st.GetIterator("p-", false))
+		require.Equal(t, expected, keys)
+
+		keys = collectTestDBBackendKeys(st.GetIterator("p-", true))
+		require.Equal(t, sebakcommon.ReverseStringSlice(expected), keys)
+
+		keys = collectTestDBBackendKeys(st.GetIterator("", false))
+		require.Equal(t, 252, len(keys))
+
+		keys = collectTestDBBackendKeys(st.GetIteratorRange("p-010", "p-013", false))
+		require.Equal(t, []string{"p-010", "p-011", "p-012"}, keys)
+
+		keys = collectTestDBBackendKeys(st.GetIteratorRange("p-010", "p-013", true))
+		require.Equal(t, []string{"p-012", "p-011", "p-010"}, keys)
+
+		keys = collectTestDBBackendKeys(st.GetIteratorRange("p-249", "", false))
+		require.Equal(t, []string{"p-249", "q-000"}, keys)
+
+		// closed iterator stops
+		iterFunc, closeFunc := st.GetIterator("p-", false)
+		_, hasNext := iterFunc()
+		require.True(t, hasNext)
+		closeFunc()
+	})
+}
+
+func TestDBBackendTransaction(t *testing.T) {
+	testDBBackends(t, func(t *testing.T, st DBBackend) {
+		{
+			ts, err := st.OpenTransaction()
+			require.Nil(t, err)
+
+			require.Nil(t, ts.New("key", "1"))
+
+			var v string
+			require.Nil(t, ts.Get("key", &v))
+			require.Equal(t, "1", v)
+
+			keys := collectTestDBBackendKeys(ts.GetIterator("", false))
+			require.Equal(t, []string{"key"}, keys)
+
+			require.Nil(t, ts.Discard())
+
+			exists, _ := st.Has("key")
+			require.False(t, exists)
+		}
+
+		{
+			ts, err := st.OpenTransaction()
+			require.Nil(t, err)
+
+			require.Nil(t, ts.New("key", "1"))
+			require.Nil(t, ts.Commit())
+
+			var v string
+			require.Nil(t, st.Get("key", &v))
+			require.Equal(t, "1", v)
+		}
+	})
+}
+
+func TestDBBackendCache(t *testing.T) {
+	testDBBackends(t, func(t *testing.T, st DBBackend) {
+		load := func() (interface{}, error) {
+			var v string
+			err := st.Get("key", &v)
+			return v, err
+		}
+
+		require.Nil(t, st.New("key", "1"))
+		v, _ := st.Cache("test").Load("key", load)
+		require.Equal(t, "1", v)
+
+		ts, _ := st.OpenTransaction()
+		require.Nil(t, ts.Cache("test"))
+		require.Nil(t, ts.Set("key", "2"))
+		require.Nil(t, ts.Commit())
+
+		v, _ = st.Cache("test").Load("key", load)
+		require.Equal(t, "2", v)
+	})
+}
+
+func TestDBBackendModel(t *testing.T) {
+	testDBBackends(t, func(t *testing.T, st DBBackend) {
+		for i := 0; i < 5; i++ {
+			r := &testModelRecord{ID: fmt.Sprintf("%d", i), Group: "a", Serial: fmt.Sprintf("s%d", i)}
+			require.Nil(t, testModel.Save(st, r))
+		}
+
+		n, err := testModel.Count(st, "group", "a")
+		require.Nil(t, err)
+		require.Equal(t, 5, n)
+
+		ids := collectTestModelRecords(testModel.GetIteratorRange(st, "serial", "s1", "s3", false))
+		require.Equal(t, []string{"1", "2"}, ids)
+
+		require.Nil(t, testModel.Remove(st, "0"))
+		n, _ = testModel.Count(st, "group", "a")
+		require.Equal(t, 4, n)
+	})
+}
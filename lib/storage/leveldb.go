@@ -28,6 +28,11 @@ type LevelDBBackend struct {
 	DB *leveldb.DB
 
 	Core LevelDBCore
+
+	caches *cacheGroup
+	// invalidated keeps the written keys inside transaction; the caches are
+	// invalidated by them after commit.
+	invalidated []string
 }
 
 func (st *LevelDBBackend) Init(config *Config) (err error) {
@@ -46,6 +51,7 @@ func (st *LevelDBBackend) Init(config *Config) (err error) {
 
 	st.DB = db
 	st.Core = db
+	st.caches = newCacheGroup()
 
 	return
 }
@@ -54,7 +60,7 @@ func (st *LevelDBBackend) Close() error {
 	return st.DB.Close()
 }
 
-func (st *LevelDBBackend) OpenTransaction() (*LevelDBBackend, error) {
+func (st *LevelDBBackend) OpenTransaction() (DBBackend, error) {
 	_, ok := st.Core.(*leveldb.Transaction)
 	if ok {
 		return nil, errors.New("this is already *leveldb.Transaction")
@@ -66,8 +72,9 @@ func (st *LevelDBBackend) OpenTransaction() (*LevelDBBackend, error) {
 	}
 
 	return &LevelDBBackend{
-		DB:   st.DB,
-		Core: transaction,
+		DB:     st.DB,
+		Core:   transaction,
+		caches: st.caches,
 	}, nil
 }
 
@@ -78,6 +85,8 @@ func (st *LevelDBBackend) Discard() error {
 	}
 
 	ts.Discard()
+	st.invalidated = nil
+
 	return nil
 }
 
@@ -87,7 +96,46 @@ func (st *LevelDBBackend) Commit() error {
 		return errors.New("this is not *leveldb.Transaction")
 	}
 
-	return ts.Commit()
+	if err := ts.Commit(); err != nil {
+		return err
+	}
+
+	if st.caches != nil {
+		st.caches.remove(st.invalidated...)
+	}
+	st.invalidated = nil
+
+	return nil
+}
+
+func (st *LevelDBBackend) isTransaction() bool {
+	_, ok := st.Core.(*leveldb.Transaction)
+	return ok
+}
+
+// Cache returns the named `Cache` of storage. Inside transaction, it returns
+// nil, so the reads inside transaction do not touch the cache.
+func (st *LevelDBBackend) Cache(name string) *Cache {
+	if st.caches == nil || st.isTransaction() {
+		return nil
+	}
+
+	return st.caches.get(name)
+}
+
+// invalidate removes the written keys from the caches. Inside transaction, the
+// keys are removed after commit.
+func (st *LevelDBBackend) invalidate(keys ...string) {
+	if st.caches == nil {
+		return
+	}
+
+	if st.isTransaction() {
+		st.invalidated = append(st.invalidated, keys...)
+		return
+	}
+
+	st.caches.remove(keys...)
 }
 
 func (st *LevelDBBackend) makeKey(key string) []byte {
@@ -146,6 +194,7 @@ func (st *LevelDBBackend) New(k string, v interface{}) (err error) {
 	}
 
 	err = st.Core.Put(st.makeKey(k), encoded, nil)
+	st.invalidate(k)
 
 	return
 }
@@ -167,6 +216,7 @@ func (st *LevelDBBackend) News(vs ...Item) (err error) {
 	}
 
 	batch := new(leveldb.Batch)
+	var keys []string
 	for _, v := range vs {
 		var encoded []byte
 		if encoded, err = sebakcommon.EncodeJSONValue(v); err != nil {
@@ -174,9 +224,11 @@ func (st *LevelDBBackend) News(vs ...Item) (err error) {
 		}
 
 		batch.Put(st.makeKey(v.Key), encoded)
+		keys = append(keys, v.Key)
 	}
 
 	err = st.Core.Write(batch, nil)
+	st.invalidate(keys...)
 
 	return
 }
@@ -196,6 +248,7 @@ func (st *LevelDBBackend) Set(k string, v interface{}) (err error) {
 	}
 
 	err = st.Core.Put(st.makeKey(k), encoded, nil)
+	st.invalidate(k)
 
 	return
 }
@@ -212,6 +265,7 @@ func (st *LevelDBBackend) put(k string, v interface{}) (err error) {
 		return
 	}
 	err = st.Core.Put(st.makeKey(k), encoded, nil)
+	st.invalidate(k)
 	return
 }
 
@@ -232,6 +286,7 @@ func (st *LevelDBBackend) Sets(vs ...Item) (err error) {
 	}
 
 	batch := new(leveldb.Batch)
+	var keys []string
 	for _, v := range vs {
 		var encoded []byte
 		if encoded, err = sebakcommon.EncodeJSONValue(v); err != nil {
@@ -239,9 +294,11 @@ func (st *LevelDBBackend) Sets(vs ...Item) (err error) {
 		}
 
 		batch.Put(st.makeKey(v.Key), encoded)
+		keys = append(keys, v.Key)
 	}
 
 	err = st.Core.Write(batch, nil)
+	st.invalidate(keys...)
 
 	return
 }
@@ -256,6 +313,29 @@ func (st *LevelDBBackend) Remove(k string) (err error) {
 	}
 
 	err = st.Core.Delete(st.makeKey(k), nil)
+	st.invalidate(k)
+
+	return
+}
+
+func (st *LevelDBBackend) Write(b *Batch) (err error) {
+	if b.Len() < 1 {
+		return
+	}
+
+	batch := new(leveldb.Batch)
+	keys := make([]string, 0, b.Len())
+	for _, item := range b.items {
+		keys = append(keys, item.key)
+		if item.delete {
+			batch.Delete(st.makeKey(item.key))
+			continue
+		}
+		batch.Put(st.makeKey(item.key), item.value)
+	}
+
+	err = st.Core.Write(batch, nil)
+	st.invalidate(keys...)
 
 	return
 }
@@ -266,6 +346,21 @@ func (st *LevelDBBackend) GetIterator(prefix string, reverse bool) (func() (Iter
 		dbRange = leveldbUtil.BytesPrefix(st.makeKey(prefix))
 	}
 
+	return st.newIterator(dbRange, reverse)
+}
+
+// GetIteratorRange iterates the keys from `start` to `limit`. `start` is
+// included, but `limit` is not. If `limit` is empty, there is no upper bound.
+func (st *LevelDBBackend) GetIteratorRange(start, limit string, reverse bool) (func() (IterItem, bool), func()) {
+	dbRange := &leveldbUtil.Range{Start: st.makeKey(start)}
+	if len(limit) > 0 {
+		dbRange.Limit = st.makeKey(limit)
+	}
+
+	return st.newIterator(dbRange, reverse)
+}
+
+func (st *LevelDBBackend) newIterator(dbRange *leveldbUtil.Range, reverse bool) (func() (IterItem, bool), func()) {
 	iter := st.Core.NewIterator(dbRange, nil)
 
 	var funcNext func() bool
@@ -0,0 +1,490 @@
+package sebakstorage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"boscoin.io/sebak/lib/common"
+	"boscoin.io/sebak/lib/error"
+)
+
+// Model declares how the records of one kind are stored. A record has one
+// primary key and the secondary indexes, and the keys are,
+//  * '<Prefix><Primary.Name>-<primary key>': record
+//  * '<Prefix><Index.Name>-<value>': primary key, for unique index
+//  * '<Prefix><Index.Name>-<value>-<unique id>': primary key, for index, the
+//    unique id is made by `sebakcommon.GetUniqueIDFromUUID`
+//  * '<Prefix>indexes-<primary key>': written index keys of record
+//
+// The record and it's indexes are always written in single batch.
+type Model struct {
+	Prefix  string
+	Primary Index
+	Indexes []Index
+}
+
+// Index extracts the index values from the record. If `Values` returns
+// nothing, the record is not indexed by this `Index`.
+type Index struct {
+	Name   string
+	Unique bool
+	Values func(interface{}) []string
+}
+
+const modelIndexesName string = "indexes"
+
+func NewIndex(name string, unique bool, values func(interface{}) []string) Index {
+	return Index{Name: name, Unique: unique, Values: values}
+}
+
+func NewModel(prefix string, primary Index, indexes ...Index) *Model {
+	for _, index := range append([]Index{primary}, indexes...) {
+		if index.Name == modelIndexesName {
+			panic(fmt.Errorf("index name, '%s' is reserved", modelIndexesName))
+		}
+	}
+
+	return &Model{
+		Prefix:  prefix,
+		Primary: primary,
+		Indexes: indexes,
+	}
+}
+
+func (m *Model) Key(pk string) string {
+	return fmt.Sprintf("%s%s-%s", m.Prefix, m.Primary.Name, pk)
+}
+
+func (m *Model) indexesKey(pk string) string {
+	return fmt.Sprintf("%s%s-%s", m.Prefix, modelIndexesName, pk)
+}
+
+func (m *Model) Index(name string) (Index, bool) {
+	for _, index := range m.Indexes {
+		if index.Name == name {
+			return index, true
+		}
+	}
+
+	return Index{}, false
+}
+
+// IndexPrefix returns the key prefix of index. If `value` is empty, the
+// prefix covers the whole index.
+func (m *Model) IndexPrefix(name, value string) string {
+	prefix := fmt.Sprintf("%s%s-", m.Prefix, name)
+	if len(value) < 1 {
+		return prefix
+	}
+
+	prefix += value
+	if index, _ := m.Index(name); !index.Unique {
+		prefix += "-"
+	}
+
+	return prefix
+}
+
+func (m *Model) PrimaryKey(record interface{}) string {
+	values := m.Primary.Values(record)
+	if len(values) < 1 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (m *Model) Has(st DBBackend, pk string) (bool, error) {
+	return st.Has(m.Key(pk))
+}
+
+func (m *Model) Get(st DBBackend, pk string, v interface{}) error {
+	return st.Get(m.Key(pk), v)
+}
+
+// GetByIndex loads the record by unique index.
+func (m *Model) GetByIndex(st DBBackend, name, value string, v interface{}) (err error) {
+	if index, found := m.Index(name); !found || !index.Unique {
+		return fmt.Errorf("'%s' is not unique index", name)
+	}
+
+	var pk string
+	if err = st.Get(m.IndexPrefix(name, value), &pk); err != nil {
+		return
+	}
+
+	return m.Get(st, pk, v)
+}
+
+// Save stores the record and it's indexes.
+func (m *Model) Save(st DBBackend, record interface{}) (err error) {
+	batch := NewBatch()
+	if err = m.Put(st, batch, record); err != nil {
+		return
+	}
+
+	return st.Write(batch)
+}
+
+// Put adds the record and it's indexes to `Batch`, the caller should write
+// the `Batch` by `DBBackend.Write`.
+func (m *Model) Put(st DBBackend, batch *Batch, record interface{}) (err error) {
+	pk := m.PrimaryKey(record)
+	if len(pk) < 1 {
+		return fmt.Errorf("empty primary key for '%s'", m.Prefix)
+	}
+
+	var exists bool
+	if exists, err = m.Has(st, pk); err != nil {
+		return
+	} else if exists || batch.Has(m.Key(pk)) {
+		return sebakerror.ErrorStorageRecordAlreadyExists
+	}
+
+	var encoded []byte
+	if encoded, err = encodeStorageValue(record); err != nil {
+		return
+	}
+
+	var encodedPK []byte
+	if encodedPK, err = sebakcommon.EncodeJSONValue(pk); err != nil {
+		return
+	}
+
+	var indexKeys []string
+	for _, index := range m.Indexes {
+		var keys []string
+		if keys, err = m.putIndex(st, batch, index, record, encodedPK); err != nil {
+			return
+		}
+		indexKeys = append(indexKeys, keys...)
+	}
+
+	var encodedIndexKeys []byte
+	if encodedIndexKeys, err = sebakcommon.EncodeJSONValue(indexKeys); err != nil {
+		return
+	}
+
+	batch.Put(m.Key(pk), encoded)
+	batch.Put(m.indexesKey(pk), encodedIndexKeys)
+
+	return
+}
+
+func (m *Model) putIndex(st DBBackend, batch *Batch, index Index, record interface{}, encodedPK []byte) (keys []string, err error) {
+	var values []string
+	for _, value := range index.Values(record) {
+		if len(value) < 1 {
+			continue
+		}
+		if _, found := sebakcommon.InStringArray(values, value); found {
+			continue
+		}
+		values = append(values, value)
+
+		key := m.IndexPrefix(index.Name, value)
+		if index.Unique {
+			// the unique value can be written by the other record of same
+			// `Batch`, which is not stored yet.
+			var exists bool
+			if exists, err = st.Has(key); err != nil {
+				return
+			} else if exists || batch.Has(key) {
+				err = sebakerror.ErrorStorageRecordAlreadyExists
+				return
+			}
+		} else {
+			key += sebakcommon.GetUniqueIDFromUUID()
+		}
+
+		batch.Put(key, encodedPK)
+		keys = append(keys, key)
+	}
+
+	return
+}
+
+// PutMissingIndexes adds the index keys of the stored record to `Batch`,
+// which are not written yet; the index, which was added to `Model` after the
+// record had been stored, or the record, which was stored without `Model`.
+// The index keys, which were stored with the record without `Model`, are
+// recorded, not written again. It returns the number of added index keys.
+func (m *Model) PutMissingIndexes(st DBBackend, batch *Batch, record interface{}) (n int, err error) {
+	pk := m.PrimaryKey(record)
+
+	var exists bool
+	if exists, err = m.Has(st, pk); err != nil {
+		return
+	} else if !exists {
+		err = sebakerror.ErrorStorageRecordDoesNotExist
+		return
+	}
+
+	var indexKeys []string
+	var hasIndexKeys bool
+	if err = st.Get(m.indexesKey(pk), &indexKeys); err == nil {
+		hasIndexKeys = true
+	} else if err != sebakerror.ErrorStorageRecordDoesNotExist {
+		return
+	} else {
+		err = nil
+	}
+
+	var encodedPK []byte
+	if encodedPK, err = sebakcommon.EncodeJSONValue(pk); err != nil {
+		return
+	}
+
+	for _, index := range m.Indexes {
+		prefix := m.IndexPrefix(index.Name, "")
+
+		var indexed bool
+		for _, key := range indexKeys {
+			if strings.HasPrefix(key, prefix) {
+				indexed = true
+				break
+			}
+		}
+		if indexed {
+			continue
+		}
+
+		var keys []string
+		if !hasIndexKeys {
+			// the record, which was stored without `Model`, can have the
+			// index keys without '<Prefix>indexes-<primary key>'.
+			if keys, err = m.storedIndexKeys(st, index, record, pk); err != nil {
+				return
+			} else if len(keys) > 0 {
+				indexKeys = append(indexKeys, keys...)
+				continue
+			}
+		}
+
+		if keys, err = m.putIndex(st, batch, index, record, encodedPK); err != nil {
+			return
+		}
+		indexKeys = append(indexKeys, keys...)
+		n += len(keys)
+	}
+
+	if n < 1 && hasIndexKeys {
+		return
+	}
+
+	var encodedIndexKeys []byte
+	if encodedIndexKeys, err = sebakcommon.EncodeJSONValue(indexKeys); err != nil {
+		return
+	}
+	batch.Put(m.indexesKey(pk), encodedIndexKeys)
+
+	return
+}
+
+// storedIndexKeys returns the stored keys of `index`, which point to the
+// record of `pk`.
+func (m *Model) storedIndexKeys(st DBBackend, index Index, record interface{}, pk string) (keys []string, err error) {
+	for _, value := range index.Values(record) {
+		if len(value) < 1 {
+			continue
+		}
+
+		key := m.IndexPrefix(index.Name, value)
+		if index.Unique {
+			var stored string
+			if err = st.Get(key, &stored); err == sebakerror.ErrorStorageRecordDoesNotExist {
+				err = nil
+				continue
+			} else if err != nil {
+				return
+			}
+			if stored == pk {
+				keys = append(keys, key)
+			}
+			continue
+		}
+
+		iterFunc, closeFunc := st.GetIterator(key, false)
+		for {
+			item, hasNext := iterFunc()
+			if !hasNext {
+				break
+			}
+
+			var stored string
+			if err = json.Unmarshal(item.Value, &stored); err != nil {
+				closeFunc()
+				return
+			}
+			if stored == pk {
+				keys = append(keys, string(item.Key))
+			}
+		}
+		closeFunc()
+	}
+
+	return
+}
+
+// Update adds the new record to `Batch` in place of the stored one. The
+// indexes are not changed, so the index values of record must be same.
+func (m *Model) Update(st DBBackend, batch *Batch, record interface{}) (err error) {
+	pk := m.PrimaryKey(record)
+
+	var exists bool
+	if exists, err = m.Has(st, pk); err != nil {
+		return
+	} else if !exists {
+		return sebakerror.ErrorStorageRecordDoesNotExist
+	}
+
+	var encoded []byte
+	if encoded, err = encodeStorageValue(record); err != nil {
+		return
+	}
+
+	batch.Put(m.Key(pk), encoded)
+
+	return
+}
+
+// Remove removes the record and it's indexes.
+func (m *Model) Remove(st DBBackend, pk string) (err error) {
+	batch := NewBatch()
+	if err = m.Delete(st, batch, pk); err != nil {
+		return
+	}
+
+	return st.Write(batch)
+}
+
+// Delete adds the deletion of record and it's indexes to `Batch`.
+func (m *Model) Delete(st DBBackend, batch *Batch, pk string) (err error) {
+	var exists bool
+	if exists, err = m.Has(st, pk); err != nil {
+		return
+	} else if !exists {
+		return sebakerror.ErrorStorageRecordDoesNotExist
+	}
+
+	var indexKeys []string
+	if err = st.Get(m.indexesKey(pk), &indexKeys); err != nil {
+		return
+	}
+
+	for _, key := range indexKeys {
+		batch.Delete(key)
+	}
+	batch.Delete(m.indexesKey(pk))
+	batch.Delete(m.Key(pk))
+
+	return
+}
+
+// Count returns the number of records in index. If `value` is empty, it counts
+// the whole index.
+func (m *Model) Count(st DBBackend, name, value string) (n int, err error) {
+	iterFunc, closeFunc := st.GetIterator(m.IndexPrefix(name, value), false)
+	defer closeFunc()
+
+	for {
+		if _, hasNext := iterFunc(); !hasNext {
+			break
+		}
+		n++
+	}
+
+	return
+}
+
+// GetIterator iterates the records of index by it's saved order. The
+// `IterItem.Value` is the stored record, not primary key.
+func (m *Model) GetIterator(st DBBackend, name, value string, reverse bool) (func() (IterItem, bool), func()) {
+	iterFunc, closeFunc := st.GetIterator(m.IndexPrefix(name, value), reverse)
+
+	return m.loadInsideIterator(st, iterFunc, closeFunc)
+}
+
+// GetIteratorRange iterates the records of index, which index value is
+// between `start` and `limit`. `start` is included, but `limit` is not. If
+// `limit` is empty, it iterates to the end of index.
+func (m *Model) GetIteratorRange(st DBBackend, name, start, limit string, reverse bool) (func() (IterItem, bool), func()) {
+	prefix := m.IndexPrefix(name, "")
+
+	limitKey := prefix + limit
+	if len(limit) < 1 {
+		limitKey = prefixLimit(prefix)
+	}
+
+	iterFunc, closeFunc := st.GetIteratorRange(prefix+start, limitKey, reverse)
+
+	return m.loadInsideIterator(st, iterFunc, closeFunc)
+}
+
+// GetIteratorFrom iterates the records of index, which index value has
+// `prefix`, from the index value, `start`; `start` is included.
+func (m *Model) GetIteratorFrom(st DBBackend, name, prefix, start string) (func() (IterItem, bool), func()) {
+	indexPrefix := m.IndexPrefix(name, "")
+	iterFunc, closeFunc := st.GetIteratorRange(indexPrefix+start, prefixLimit(indexPrefix+prefix), false)
+
+	return m.loadInsideIterator(st, iterFunc, closeFunc)
+}
+
+// GetIteratorAfter iterates the records of index after `cursor`; the cursor
+// of record is made by `Cursor`. With empty `cursor`, it iterates the whole
+// index.
+func (m *Model) GetIteratorAfter(st DBBackend, name, cursor string, reverse bool) (func() (IterItem, bool), func()) {
+	iterFunc, closeFunc := GetIteratorAfter(st, m.IndexPrefix(name, ""), cursor, reverse)
+
+	return m.loadInsideIterator(st, iterFunc, closeFunc)
+}
+
+// Cursor returns the cursor of item, which is iterated from the index.
+func (m *Model) Cursor(name string, item IterItem) string {
+	return Cursor(m.IndexPrefix(name, ""), item)
+}
+
+func (m *Model) loadInsideIterator(
+	st DBBackend,
+	iterFunc func() (IterItem, bool),
+	closeFunc func(),
+) (
+	func() (IterItem, bool),
+	func(),
+) {
+	return (func() (IterItem, bool) {
+			item, hasNext := iterFunc()
+			if !hasNext {
+				return IterItem{}, false
+			}
+
+			var pk string
+			if err := json.Unmarshal(item.Value, &pk); err != nil {
+				return IterItem{}, false
+			}
+
+			b, err := st.GetRaw(m.Key(pk))
+			if err != nil {
+				return IterItem{}, false
+			}
+
+			return IterItem{N: item.N, Key: item.Key, Value: b}, hasNext
+		}), (func() {
+			closeFunc()
+		})
+}
+
+// prefixLimit returns the smallest key, which is greater than all the keys
+// starting with `prefix`.
+func prefixLimit(prefix string) string {
+	limit := []byte(prefix)
+	for i := len(limit) - 1; i >= 0; i-- {
+		if limit[i] < 0xff {
+			limit[i]++
+			return string(limit[:i+1])
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,267 @@
+package sebakstorage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"boscoin.io/sebak/lib/error"
+)
+
+type testModelRecord struct {
+	ID     string
+	Group  string
+	Serial string
+	Tags   []string
+}
+
+var testModel = NewModel(
+	"tm-",
+	NewIndex("id", true, func(v interface{}) []string {
+		return []string{v.(*testModelRecord).ID}
+	}),
+	NewIndex("serial", true, func(v interface{}) []string {
+		return []string{v.(*testModelRecord).Serial}
+	}),
+	NewIndex("group", false, func(v interface{}) []string {
+		return []string{v.(*testModelRecord).Group}
+	}),
+	NewIndex("tag", false, func(v interface{}) []string {
+		return v.(*testModelRecord).Tags
+	}),
+)
+
+func collectTestModelRecords(iterFunc func() (IterItem, bool), closeFunc func()) (ids []string) {
+	defer closeFunc()
+
+	for {
+		item, hasNext := iterFunc()
+		if !hasNext {
+			break
+		}
+
+		var r testModelRecord
+		json.Unmarshal(item.Value, &r)
+		ids = append(ids, r.ID)
+	}
+
+	return
+}
+
+func TestModelSaveAndGet(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	r := &testModelRecord{ID: "1", Group: "a", Serial: "s1", Tags: []string{"x", "y", "x"}}
+	require.Nil(t, testModel.Save(st, r))
+
+	exists, err := testModel.Has(st, r.ID)
+	require.Nil(t, err)
+	require.True(t, exists)
+
+	var fetched testModelRecord
+	require.Nil(t, testModel.Get(st, r.ID, &fetched))
+	require.Equal(t, *r, fetched)
+
+	var byIndex testModelRecord
+	require.Nil(t, testModel.GetByIndex(st, "serial", r.Serial, &byIndex))
+	require.Equal(t, *r, byIndex)
+
+	// same primary key
+	err = testModel.Save(st, &testModelRecord{ID: "1", Serial: "s2"})
+	require.Equal(t, sebakerror.ErrorStorageRecordAlreadyExists, err)
+
+	// same unique index value
+	err = testModel.Save(st, &testModelRecord{ID: "2", Serial: "s1"})
+	require.Equal(t, sebakerror.ErrorStorageRecordAlreadyExists, err)
+	exists, _ = testModel.Has(st, "2")
+	require.False(t, exists)
+
+	// same unique index value in same batch
+	batch := NewBatch()
+	require.Nil(t, testModel.Put(st, batch, &testModelRecord{ID: "3", Serial: "s3"}))
+	err = testModel.Put(st, batch, &testModelRecord{ID: "4", Serial: "s3"})
+	require.Equal(t, sebakerror.ErrorStorageRecordAlreadyExists, err)
+	err = testModel.Put(st, batch, &testModelRecord{ID: "3", Serial: "s4"})
+	require.Equal(t, sebakerror.ErrorStorageRecordAlreadyExists, err)
+
+	// duplicated index values are indexed once
+	n, err := testModel.Count(st, "tag", "x")
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+}
+
+func TestModelIterator(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	var createdOrder []string
+	for i := 0; i < 10; i++ {
+		group := "even"
+		if i%2 == 1 {
+			group = "odd"
+		}
+		r := &testModelRecord{ID: fmt.Sprintf("%d", i), Group: group, Serial: fmt.Sprintf("s%d", i)}
+		require.Nil(t, testModel.Save(st, r))
+
+		if group == "even" {
+			createdOrder = append(createdOrder, r.ID)
+		}
+	}
+
+	ids := collectTestModelRecords(testModel.GetIterator(st, "group", "even", false))
+	require.Equal(t, createdOrder, ids)
+
+	ids = collectTestModelRecords(testModel.GetIterator(st, "group", "even", true))
+	require.Equal(t, len(createdOrder), len(ids))
+	for i, id := range ids {
+		require.Equal(t, createdOrder[len(createdOrder)-1-i], id)
+	}
+
+	n, err := testModel.Count(st, "group", "odd")
+	require.Nil(t, err)
+	require.Equal(t, 5, n)
+
+	n, err = testModel.Count(st, "group", "")
+	require.Nil(t, err)
+	require.Equal(t, 10, n)
+
+	// "even" < "o" <= "odd"
+	ids = collectTestModelRecords(testModel.GetIteratorRange(st, "group", "", "o", false))
+	require.Equal(t, createdOrder, ids)
+
+	ids = collectTestModelRecords(testModel.GetIteratorRange(st, "serial", "s3", "s6", false))
+	require.Equal(t, []string{"3", "4", "5"}, ids)
+
+	ids = collectTestModelRecords(testModel.GetIteratorRange(st, "serial", "s7", "", false))
+	require.Equal(t, []string{"7", "8", "9"}, ids)
+
+	// the index values, which have "e", from "ev"; "odd" is not included
+	ids = collectTestModelRecords(testModel.GetIteratorFrom(st, "group", "e", "ev"))
+	require.Equal(t, createdOrder, ids)
+
+	ids = collectTestModelRecords(testModel.GetIteratorFrom(st, "group", "e", "f"))
+	require.Equal(t, 0, len(ids))
+
+	{ // cursor
+		iterFunc, closeFunc := testModel.GetIteratorAfter(st, "serial", "", false)
+		var cursor string
+		for i := 0; i < 4; i++ {
+			item, _ := iterFunc()
+			cursor = testModel.Cursor("serial", item)
+		}
+		closeFunc()
+		require.Equal(t, "s3", cursor)
+
+		ids = collectTestModelRecords(testModel.GetIteratorAfter(st, "serial", cursor, false))
+		require.Equal(t, []string{"4", "5", "6", "7", "8", "9"}, ids)
+
+		ids = collectTestModelRecords(testModel.GetIteratorAfter(st, "serial", cursor, true))
+		require.Equal(t, []string{"2", "1", "0"}, ids)
+	}
+}
+
+func TestModelRemove(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	r0 := &testModelRecord{ID: "0", Group: "a", Serial: "s0", Tags: []string{"x"}}
+	r1 := &testModelRecord{ID: "1", Group: "a", Serial: "s1", Tags: []string{"x"}}
+	require.Nil(t, testModel.Save(st, r0))
+	require.Nil(t, testModel.Save(st, r1))
+
+	require.Nil(t, testModel.Remove(st, r0.ID))
+	require.Equal(t, sebakerror.ErrorStorageRecordDoesNotExist, testModel.Remove(st, r0.ID))
+
+	exists, _ := testModel.Has(st, r0.ID)
+	require.False(t, exists)
+
+	ids := collectTestModelRecords(testModel.GetIterator(st, "tag", "x", false))
+	require.Equal(t, []string{r1.ID}, ids)
+
+	// the unique index value can be used again
+	require.Nil(t, testModel.Save(st, &testModelRecord{ID: "2", Serial: r0.Serial}))
+
+	// nothing is left after all the records are removed
+	require.Nil(t, testModel.Remove(st, r1.ID))
+	require.Nil(t, testModel.Remove(st, "2"))
+
+	iterFunc, closeFunc := st.GetIterator(testModel.Prefix, false)
+	defer closeFunc()
+	_, hasNext := iterFunc()
+	require.False(t, hasNext)
+}
+
+func TestModelPutMissingIndexes(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	// the record, which was stored without `Model`
+	r0 := &testModelRecord{ID: "0", Group: "a", Serial: "s0", Tags: []string{"x"}}
+	require.Nil(t, st.New(testModel.Key(r0.ID), r0))
+
+	batch := NewBatch()
+	n, err := testModel.PutMissingIndexes(st, batch, r0)
+	require.Nil(t, err)
+	require.Equal(t, 3, n)
+	require.Nil(t, st.Write(batch))
+
+	ids := collectTestModelRecords(testModel.GetIterator(st, "tag", "x", false))
+	require.Equal(t, []string{r0.ID}, ids)
+
+	// the indexed record is not indexed again
+	batch = NewBatch()
+	n, err = testModel.PutMissingIndexes(st, batch, r0)
+	require.Nil(t, err)
+	require.Equal(t, 0, n)
+	require.Equal(t, 0, batch.Len())
+
+	// the backfilled record can be removed with it's indexes
+	require.Nil(t, testModel.Remove(st, r0.ID))
+
+	iterFunc, closeFunc := st.GetIterator(testModel.Prefix, false)
+	defer closeFunc()
+	_, hasNext := iterFunc()
+	require.False(t, hasNext)
+}
+
+func TestModelPutMissingIndexesStoredKeys(t *testing.T) {
+	st, _ := NewTestMemoryLevelDBBackend()
+	defer st.Close()
+
+	// the record and it's index keys, which were stored without `Model`
+	r0 := &testModelRecord{ID: "0", Group: "a", Serial: "s0", Tags: []string{"x"}}
+	require.Nil(t, st.New(testModel.Key(r0.ID), r0))
+	require.Nil(t, st.New(testModel.IndexPrefix("serial", r0.Serial), r0.ID))
+	require.Nil(t, st.New(testModel.IndexPrefix("group", r0.Group)+"u0", r0.ID))
+
+	// the other record of same group
+	r1 := &testModelRecord{ID: "1", Group: "a", Serial: "s1"}
+	require.Nil(t, testModel.Save(st, r1))
+
+	batch := NewBatch()
+	n, err := testModel.PutMissingIndexes(st, batch, r0)
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+	require.Nil(t, st.Write(batch))
+
+	count, err := testModel.Count(st, "group", r0.Group)
+	require.Nil(t, err)
+	require.Equal(t, 2, count)
+
+	ids := collectTestModelRecords(testModel.GetIterator(st, "tag", "x", false))
+	require.Equal(t, []string{r0.ID}, ids)
+
+	// the stored index keys are removed with the record
+	require.Nil(t, testModel.Remove(st, r0.ID))
+
+	count, err = testModel.Count(st, "group", r0.Group)
+	require.Nil(t, err)
+	require.Equal(t, 1, count)
+
+	exists, err := st.Has(testModel.IndexPrefix("serial", r0.Serial))
+	require.Nil(t, err)
+	require.False(t, exists)
+}
@@ -1,6 +1,7 @@
 package sebakstorage
 
 import (
+	"errors"
 	"sort"
 
 	"boscoin.io/sebak/lib/common"
@@ -10,11 +11,11 @@ import (
 )
 
 type StateDB struct {
-	levelDB     *LevelDBBackend
+	levelDB     DBBackend
 	changedkeys map[string]struct{}
 }
 
-func NewStateDB(st *LevelDBBackend) *StateDB {
+func NewStateDB(st DBBackend) *StateDB {
 	db := &StateDB{
 		levelDB: st,
 		// If we need thread safety, we should use sync.Map insteads map
@@ -23,10 +24,18 @@ func NewStateDB(st *LevelDBBackend) *StateDB {
 	return db
 }
 
+func (s *StateDB) Close() error {
+	return s.levelDB.Close()
+}
+
 func (s *StateDB) Has(k string) (bool, error) {
 	return s.levelDB.Has(k)
 }
 
+func (s *StateDB) GetRaw(k string) ([]byte, error) {
+	return s.levelDB.GetRaw(k)
+}
+
 func (s *StateDB) Get(k string, i interface{}) error {
 	return s.levelDB.Get(k, i)
 }
@@ -50,6 +59,10 @@ func (s *StateDB) GetIterator(prefix string, reverse bool) (func() (IterItem, bo
 	return s.levelDB.GetIterator(prefix, reverse)
 }
 
+func (s *StateDB) GetIteratorRange(start, limit string, reverse bool) (func() (IterItem, bool), func()) {
+	return s.levelDB.GetIteratorRange(start, limit, reverse)
+}
+
 func (s *StateDB) News(vs ...Item) error {
 	for _, v := range vs {
 		s.changedkeys[v.Key] = struct{}{}
@@ -66,6 +79,18 @@ func (s *StateDB) Sets(vs ...Item) error {
 	return s.levelDB.Sets(vs...)
 }
 
+func (s *StateDB) Write(b *Batch) error {
+	for _, item := range b.items {
+		s.changedkeys[item.key] = struct{}{}
+	}
+
+	return s.levelDB.Write(b)
+}
+
+func (s *StateDB) OpenTransaction() (DBBackend, error) {
+	return nil, errors.New("`StateDB` does not support nested transaction")
+}
+
 func (s *StateDB) Commit() error {
 	return s.levelDB.Commit()
 }
@@ -74,6 +99,10 @@ func (s *StateDB) Discard() error {
 	return s.levelDB.Discard()
 }
 
+func (s *StateDB) Cache(name string) *Cache {
+	return s.levelDB.Cache(name)
+}
+
 func (s *StateDB) MakeHash() ([]byte, error) {
 	ks := make([]string, 0, len(s.changedkeys))
 
@@ -4,7 +4,7 @@ import (
 	"testing"
 )
 
-func newTestStateDB(t *testing.T) (*LevelDBBackend, *LevelDBBackend, *StateDB) {
+func newTestStateDB(t *testing.T) (*LevelDBBackend, DBBackend, *StateDB) {
 	st, err := NewTestMemoryLevelDBBackend()
 	if err != nil {
 		t.Fatal(err)
@@ -10,6 +10,7 @@ import (
 var SupportedStorageType []string = []string{
 	"memory",
 	"file",
+	"bolt",
 }
 
 type IterItem struct {
@@ -23,13 +24,22 @@ type Item struct {
 	Value interface{}
 }
 
-type Model struct {
-}
-
-func NewStorage(config *Config) (st *LevelDBBackend, err error) {
-	st = &LevelDBBackend{}
-	if err = st.Init(config); err != nil {
-		return
+// NewStorage opens the storage by the scheme of `Config`; 'memory' and 'file'
+// are `LevelDBBackend` and 'bolt' is `BoltDBBackend`.
+func NewStorage(config *Config) (st DBBackend, err error) {
+	switch config.Scheme {
+	case "bolt":
+		backend := &BoltDBBackend{}
+		if err = backend.Init(config); err != nil {
+			return
+		}
+		st = backend
+	default:
+		backend := &LevelDBBackend{}
+		if err = backend.Init(config); err != nil {
+			return
+		}
+		st = backend
 	}
 
 	return
@@ -94,3 +104,11 @@ func ParseConfig(s string) (u *Config, err error) {
 
 	return
 }
+
+func encodeStorageValue(v interface{}) ([]byte, error) {
+	if serializable, ok := v.(sebakcommon.Serializable); ok {
+		return serializable.Serialize()
+	}
+
+	return sebakcommon.EncodeJSONValue(v)
+}
@@ -34,3 +34,13 @@ func NewTestFileLevelDBBackend(f string) (st *LevelDBBackend, err error) {
 
 	return
 }
+
+func NewTestBoltDBBackend(f string) (st *BoltDBBackend, err error) {
+	st = &BoltDBBackend{}
+	config, _ := NewConfigFromString(fmt.Sprintf("bolt://%s", f))
+	if err = st.Init(config); err != nil {
+		return
+	}
+
+	return
+}
@@ -109,7 +109,7 @@ func (tx Transaction) IsWellFormed(networkID []byte) (err error) {
 	return
 }
 
-func (tx Transaction) Validate(st *sebakstorage.LevelDBBackend) (err error) {
+func (tx Transaction) Validate(st sebakstorage.DBBackend) (err error) {
 	// TODO check whether `Checkpoint` is in `Block Transaction` and is latest
 	// `Checkpoint`
 	// TODO check whether `Source` is in `Block Account`
@@ -243,19 +243,27 @@ func (tb TransactionBody) MakeHashString() string {
 /// Returns:
 ///   err = If the `Transaction` could not be externalized
 ///
-func FinishTransaction(st *sebakstorage.LevelDBBackend, ballot Ballot, tx Transaction) (err error) {
+func FinishTransaction(st sebakstorage.DBBackend, ballot Ballot, tx Transaction) (err error) {
 	var raw []byte
 	raw, err = ballot.Data().Serialize()
 	if err != nil {
 		return
 	}
 
-	var ts *sebakstorage.LevelDBBackend
+	return finishTransaction(st, tx, raw, "")
+}
+
+// finishTransaction externalizes `tx` with it's raw message, `raw`. The
+// `BlockTransaction` and it's `BlockOperation`s are confirmed at `confirmed`;
+// if empty, they are confirmed now.
+func finishTransaction(st sebakstorage.DBBackend, tx Transaction, raw []byte, confirmed string) (err error) {
+	var ts sebakstorage.DBBackend
 	if ts, err = st.OpenTransaction(); err != nil {
 		return
 	}
 
 	bt := NewBlockTransactionFromTransaction(tx, raw)
+	bt.Confirmed = confirmed
 	if err = bt.Save(ts); err != nil {
 		ts.Discard()
 		return
@@ -284,9 +292,37 @@ func FinishTransaction(st *sebakstorage.LevelDBBackend, ballot Ballot, tx Transa
 		return
 	}
 
+	if err = saveBlockAccountHistories(ts, tx, bt); err != nil {
+		ts.Discard()
+		return
+	}
+
 	if err = ts.Commit(); err != nil {
 		ts.Discard()
 	}
 
 	return
 }
+
+// saveBlockAccountHistories indexes the balances of the source and targets of
+// `tx` after it is finished.
+func saveBlockAccountHistories(st sebakstorage.DBBackend, tx Transaction, bt BlockTransaction) (err error) {
+	addresses := []string{tx.B.Source}
+	for _, op := range tx.B.Operations {
+		if _, found := sebakcommon.InStringArray(addresses, op.B.TargetAddress()); !found {
+			addresses = append(addresses, op.B.TargetAddress())
+		}
+	}
+
+	for _, address := range addresses {
+		var ba *block.BlockAccount
+		if ba, err = block.GetBlockAccount(st, address); err != nil {
+			return
+		}
+		if err = SaveBlockAccountHistory(st, ba, &bt); err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -371,7 +371,7 @@ func (vt *ISAACVotingThresholdPolicy) Connected() int {
 }
 
 func (vt *ISAACVotingThresholdPolicy) SetConnected(n int) error {
-	if n < 1 {
+	if n < 0 {
 		return sebakerror.ErrorVotingThresholdInvalidValidators
 	}
 